@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// appConfig identifies a GitHub App installation to authenticate as, in place of a personal access token.
+type appConfig struct {
+	// ID is the App's ID, shown on its settings page.
+	ID int64 `yaml:"id"`
+	// Key is the path to the App's PEM-encoded private key, or a credential source for the key itself.
+	Key string `yaml:"key"`
+	// Installation is the ID of the installation to act as. It may be omitted if the App is installed
+	// just once.
+	Installation int64 `yaml:"installation"`
+}
+
+// app is the GitHub App in effect for this invocation, from --app-id and friends, the environment, or the
+// config file.
+var app appConfig
+
+// configured returns true if an App to authenticate as was given.
+func (a *appConfig) configured() bool {
+	return a.ID != 0
+}
+
+// check validates the App settings.
+func (a *appConfig) check() error {
+	if a.ID == 0 && (a.Key != "" || a.Installation != 0) {
+		return errors.New("--app-key and --app-installation require --app-id")
+	} else if a.ID != 0 && a.Key == "" {
+		return errors.New("--app-id requires --app-key, the App's private key")
+	}
+	return nil
+}
+
+// applyAppDefaults fills in the App settings not given by flag from $GHMM_APP_ID, $GHMM_APP_KEY, and
+// $GHMM_APP_INSTALLATION, or the config file.
+func applyAppDefaults(changed func(string) bool) error {
+	envInt := func(name string, v *int64) error {
+		if env := os.Getenv(name); env != "" {
+			n, err := strconv.ParseInt(env, 10, 64)
+			if err != nil {
+				return errors.Errorf("malformed $%s %q; expected a number", name, env)
+			}
+			*v = n
+		}
+		return nil
+	}
+	if !changed("app-id") {
+		app.ID = cfg.App.ID
+		if err := envInt("GHMM_APP_ID", &app.ID); err != nil {
+			return err
+		}
+	}
+	if !changed("app-key") {
+		app.Key = cfg.App.Key
+		if env := os.Getenv("GHMM_APP_KEY"); env != "" {
+			app.Key = env
+		}
+	}
+	if !changed("app-installation") {
+		app.Installation = cfg.App.Installation
+		if err := envInt("GHMM_APP_INSTALLATION", &app.Installation); err != nil {
+			return err
+		}
+	}
+	return app.check()
+}
+
+// appKey loads the App's private key: from the file named by Key, or, if Key is a credential source, from
+// wherever that says.
+func (a *appConfig) appKey() (*rsa.PrivateKey, error) {
+	var b []byte
+	var err error
+	switch kind := strings.SplitN(a.Key, ":", 2)[0]; kind {
+	case "env", "file", "cmd", "enc":
+		var s string
+		if s, err = resolveCredential(a.Key); err != nil {
+			return nil, errors.Wrap(err, "resolving the GitHub App private key")
+		}
+		b = []byte(s)
+	default:
+		if b, err = ioutil.ReadFile(a.Key); err != nil {
+			return nil, errors.Wrapf(err, "reading GitHub App private key %s", a.Key)
+		}
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("the GitHub App private key is not PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the GitHub App private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT signs the short-lived JSON Web Token with which an App authenticates as itself, in order to obtain
+// installation tokens.
+func appJWT(id int64, key *rsa.PrivateKey) (string, error) {
+	// Backdate the token a little, in case our clock runs ahead of GitHub's; ten minutes is the most allowed.
+	now := time.Now()
+	header := `{"alg":"RS256","typ":"JWT"}`
+	claims := fmt.Sprintf(`{"iat":%d,"exp":%d,"iss":"%d"}`,
+		now.Add(-time.Minute).Unix(), now.Add(9*time.Minute).Unix(), id)
+	enc := base64.RawURLEncoding
+	signing := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(claims))
+	sum := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", errors.Wrap(err, "signing GitHub App token")
+	}
+	return signing + "." + enc.EncodeToString(sig), nil
+}
+
+// appTokenSource mints installation access tokens, which last an hour, as they are needed.
+type appTokenSource struct {
+	api  string // the REST API URL, ending in a slash.
+	base http.RoundTripper
+	id   int64
+	key  *rsa.PrivateKey
+
+	installation int64 // looked up on first use, if not given.
+}
+
+// appRequest makes a request authenticated as the App itself.
+func (s *appTokenSource) appRequest(method, path string, out interface{}) error {
+	jwt, err := appJWT(s.id, s.key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, s.api+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+	resp, err := (&http.Client{Transport: s.base}).Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "requesting %s as GitHub App %d", path, s.id)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("requesting %s as GitHub App %d: %s: %s",
+			path, s.id, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(out), "decoding response to %s", path)
+}
+
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	if s.installation == 0 {
+		var installs []struct {
+			ID      int64 `json:"id"`
+			Account struct {
+				Login string `json:"login"`
+			} `json:"account"`
+		}
+		if err := s.appRequest("GET", "app/installations", &installs); err != nil {
+			return nil, err
+		}
+		if len(installs) != 1 {
+			var accounts []string
+			for _, in := range installs {
+				accounts = append(accounts, fmt.Sprintf("%s (%d)", in.Account.Login, in.ID))
+			}
+			return nil, errors.Errorf("GitHub App %d has %d installations (%s); pass --app-installation to "+
+				"choose one", s.id, len(installs), strings.Join(accounts, ", "))
+		}
+		s.installation = installs[0].ID
+	}
+
+	var res struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("app/installations/%d/access_tokens", s.installation)
+	if err := s.appRequest("POST", path, &res); err != nil {
+		return nil, err
+	}
+	registerSecret(res.Token)
+	return &oauth2.Token{AccessToken: res.Token, Expiry: res.ExpiresAt}, nil
+}
+
+// appLogin returns the login of the configured App's bot user, as which its installations make changes.
+func appLogin(api string) (string, error) {
+	key, err := app.appKey()
+	if err != nil {
+		return "", err
+	}
+	src := &appTokenSource{api: api, base: http.DefaultTransport, id: app.ID, key: key}
+	var res struct {
+		Slug string `json:"slug"`
+	}
+	if err = src.appRequest("GET", "app", &res); err != nil {
+		return "", err
+	}
+	return res.Slug + "[bot]", nil
+}
+
+// appTransport returns a transport that authenticates requests as the configured App installation, minting
+// a fresh installation token whenever the last one is about to expire.
+func appTransport(base http.RoundTripper, api string) (http.RoundTripper, error) {
+	key, err := app.appKey()
+	if err != nil {
+		return nil, err
+	}
+	src := &appTokenSource{api: api, base: base, id: app.ID, key: key, installation: app.Installation}
+	return &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, src), Base: base}, nil
+}
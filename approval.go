@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// postPlan, if set, names the issue (owner/repo#number) on which to post a dry-run plan for approval.
+var postPlan string
+
+// planCommentMarker introduces the machine-readable copy of a plan embedded in an approval comment.
+const planCommentMarker = "<!-- ghmm-plan"
+
+// planDiffFence opens the block in an approval comment that shows approvers the plan's changes.
+const planDiffFence = "```diff\n"
+
+// planDocument is the serialized form of a plan, as embedded in approval comments and saved with --out.
+type planDocument struct {
+	Version int       `json:"version"`
+	Changes []*change `json:"changes"`
+}
+
+// parseIssueRef parses an owner/repo#number issue reference.
+func parseIssueRef(s string) (repo, int, error) {
+	ix := strings.LastIndex(s, "#")
+	if ix < 0 {
+		return "", 0, errors.Errorf("malformed issue %q; expected owner/repo#number", s)
+	}
+	r, err := ghmm.ParseRepo(s[:ix])
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := strconv.Atoi(s[ix+1:])
+	if err != nil || n <= 0 {
+		return "", 0, errors.Errorf("malformed issue number in %q", s)
+	}
+	return r, n, nil
+}
+
+// postPlanComment posts a plan as a comment on the issue named by --post-plan, for a teammate to approve.
+// The comment lists the changes for people to read, and embeds the plan itself for apply-plan to execute.
+func postPlanComment(gh *github.Client, p *plan) error {
+	r, number, err := parseIssueRef(postPlan)
+	if err != nil {
+		return err
+	}
+
+	text, err := planCommentBody(p)
+	if err != nil {
+		return err
+	}
+	comment, _, err := gh.Issues.CreateComment(context.Background(), r.Owner(), r.Name(), number,
+		&github.IssueComment{Body: &text})
+	if err != nil {
+		return errors.Wrapf(err, "posting plan to %s", postPlan)
+	}
+	fmt.Fprintf(stdout, "posted plan for approval at %s\n", comment.GetHTMLURL())
+	return nil
+}
+
+// planCommentBody renders the body of an approval comment for a plan.
+func planCommentBody(p *plan) (string, error) {
+	doc, err := json.MarshalIndent(planDocument{Version: 1, Changes: p.Changes}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "### ghmm plan: %d changes across %d repos\n\n", len(p.Changes), len(p.Repos()))
+	fmt.Fprintf(&body, "%s%s```\n", planDiffFence, planDiff(p, nil))
+	fmt.Fprintf(&body, "\nTo approve, react with :+1:. Once approved, run "+
+		"`ghmm apply-plan --from-comment <link to this comment> --yes` to make these changes.\n\n")
+	// JSON escapes '>', so the plan can never terminate the HTML comment early.
+	fmt.Fprintf(&body, "%s\n%s\n-->\n", planCommentMarker, doc)
+	return body.String(), nil
+}
+
+// commentURLPattern matches the path of an issue or pull request, within a comment's URL.
+var commentURLPattern = regexp.MustCompile(`^/([^/]+)/([^/]+)/(?:issues|pull)/\d+$`)
+
+// parseCommentURL extracts the repo and comment ID from a comment URL, such as
+// https://github.com/acme/ops/issues/12#issuecomment-345.
+func parseCommentURL(s string) (repo, int64, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "parsing comment URL %q", s)
+	}
+	m := commentURLPattern.FindStringSubmatch(u.Path)
+	if m == nil || !strings.HasPrefix(u.Fragment, "issuecomment-") {
+		return "", 0, errors.Errorf("malformed comment URL %q; expected a link to an issue comment", s)
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(u.Fragment, "issuecomment-"), 10, 64)
+	if err != nil {
+		return "", 0, errors.Errorf("malformed comment ID in %q", s)
+	}
+	return repo(m[1] + "/" + m[2]), id, nil
+}
+
+// extractPlan recovers the plan embedded in an approval comment. Approvers only ever see the comment's diff,
+// so the plan must render to exactly that diff; otherwise, what was approved need not be what would be applied.
+func extractPlan(body string) (*plan, error) {
+	start := strings.Index(body, planCommentMarker)
+	if start < 0 {
+		return nil, errors.New("the comment does not contain a ghmm plan")
+	}
+	rest := body[start+len(planCommentMarker):]
+	end := strings.Index(rest, "-->")
+	if end < 0 {
+		return nil, errors.New("the comment's plan is truncated")
+	}
+	p, err := parsePlanDocument([]byte(rest[:end]), "the comment's plan")
+	if err != nil {
+		return nil, err
+	}
+
+	fence := strings.Index(body, planDiffFence)
+	if fence < 0 || fence > start ||
+		!strings.HasPrefix(body[fence+len(planDiffFence):], planDiff(p, nil)+"```\n") {
+		return nil, errors.New("the comment's plan does not match the changes it shows approvers (or was " +
+			"posted with a different --timezone); post a new plan")
+	}
+	return p, nil
+}
+
+// parsePlanDocument parses a serialized plan, described by what in any error.
+func parsePlanDocument(b []byte, what string) (*plan, error) {
+	var doc planDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", what)
+	}
+	if doc.Version != 1 {
+		return nil, errors.Errorf("unsupported plan version %d; upgrade ghmm", doc.Version)
+	}
+	return &plan{Changes: doc.Changes}, nil
+}
+
+// planApprovers returns the users, other than the plan's author, who approved it with a +1 reaction and
+// have write access to the repo it was posted in.
+func planApprovers(gh *github.Client, r repo, comment *github.IssueComment) ([]string, error) {
+	ctx := context.Background()
+	author := comment.GetUser().GetLogin()
+
+	var approvers []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reactions, resp, err := gh.Reactions.ListIssueCommentReactions(ctx, r.Owner(), r.Name(), comment.GetID(), opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing reactions to the plan")
+		}
+		for _, re := range reactions {
+			login := re.GetUser().GetLogin()
+			if re.GetContent() != "+1" || login == author {
+				continue
+			}
+			level, _, err := gh.Repositories.GetPermissionLevel(ctx, r.Owner(), r.Name(), login)
+			if err != nil {
+				return nil, errors.Wrapf(err, "checking %s's permissions in repo %s", login, r)
+			}
+			if p := level.GetPermission(); p == "admin" || p == "write" {
+				approvers = append(approvers, login)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return approvers, nil
+}
+
+// checkPlanCurrent verifies that every milestone a plan edits or deletes is still as it was when the plan was made,
+// so that an approved plan cannot silently clobber changes made since. Any error says what has happened since,
+// and what to do about it.
+func checkPlanCurrent(gh *github.Client, p *plan, since, remedy string) error {
+	return parallel(len(p.Changes), func(i int) error {
+		c := p.Changes[i]
+		if c.Kind == createChange {
+			return nil
+		}
+		m, _, err := gh.Issues.GetMilestone(context.Background(), c.Repo.Owner(), c.Repo.Name(), c.Number)
+		if err != nil {
+			return errors.Wrapf(err, "fetching milestone %s (#%d) in repo %s", c.Title, c.Number, c.Repo)
+		}
+		return checkChangeCurrent(c, m, since, remedy)
+	})
+}
+
+// checkChangeCurrent verifies that a milestone is still as it was when a change to it was planned. A milestone
+// that a change deletes without unassigning its issues must still have none, since deleting it would silently
+// drop any assigned since from it.
+func checkChangeCurrent(c *change, m *github.Milestone, since, remedy string) error {
+	if !fieldsOf(m).Equal(c.Old) {
+		return errors.Errorf("milestone %s (#%d) in repo %s has changed since %s; %s",
+			c.Title, c.Number, c.Repo, since, remedy)
+	}
+	if n := m.GetOpenIssues() + m.GetClosedIssues(); c.Kind == deleteChange && !c.Unassign && n > 0 {
+		return errors.Errorf("milestone %s (#%d) in repo %s has had %d issues assigned to it since %s; "+
+			"remove them from it, or delete it with `ghmm delete --unassign`", c.Title, c.Number, c.Repo, n, since)
+	}
+	return nil
+}
+
+// checkPlanCreates verifies that no milestone has taken the title of one that a plan creates since the plan
+// was made.
+func checkPlanCreates(gh *github.Client, p *plan) error {
+	for _, c := range p.Changes {
+		if c.Kind != createChange {
+			continue
+		}
+		m, err := findMilestone(gh, c.Repo, c.Title)
+		if err != nil {
+			return err
+		} else if m != nil {
+			return errors.Errorf("cannot create milestone %s in repo %s, since a milestone (#%d) with that title "+
+				"exists there now", c.Title, c.Repo, m.GetNumber())
+		}
+	}
+	return nil
+}
+
+func doApplyPlan(commentURL string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	r, id, err := parseCommentURL(commentURL)
+	if err != nil {
+		return err
+	}
+	comment, _, err := gh.Issues.GetComment(context.Background(), r.Owner(), r.Name(), id)
+	if err != nil {
+		return errors.Wrapf(err, "fetching comment %s", commentURL)
+	}
+	// An edit could change the plan after it was approved, so only ever apply pristine comments.
+	if !comment.GetUpdatedAt().Equal(comment.GetCreatedAt()) {
+		return errors.New("the plan's comment has been edited since it was posted; post a new plan")
+	}
+	p, err := extractPlan(comment.GetBody())
+	if err != nil {
+		return err
+	}
+
+	approvers, err := planApprovers(gh, r, comment)
+	if err != nil {
+		return err
+	} else if len(approvers) == 0 {
+		return errors.Errorf("the plan has not been approved; it needs a +1 reaction from someone other than "+
+			"%s with write access to repo %s", comment.GetUser().GetLogin(), r)
+	}
+	fmt.Fprintf(stdout, "plan approved by %s\n", strings.Join(approvers, ", "))
+
+	if err = checkPlanCurrent(gh, p, "the plan was made", "post a new plan"); err != nil {
+		return err
+	}
+	if err = checkPlanCreates(gh, p); err != nil {
+		return err
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("applied %d planned changes", c)
+		} else {
+			fmt.Fprintf(stdout, "would apply %d planned changes; re-run with --yes to apply them\n", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractPlan(t *testing.T) {
+	var p plan
+	p.Create("acme/a", "M1", milestoneFields{State: "open", DueOn: dueOnDate(2019, 7, 1)})
+	p.Delete("acme/b", testMilestone(2, "M0", "closed", dueOnDate(2019, 6, 1), 0), false)
+	body, err := planCommentBody(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := extractPlan(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlan(t, "posted", got, describePlan(&p))
+
+	// A plan whose hidden changes differ from the diff approvers see must never be applied.
+	tampered := strings.Replace(body, `"title": "M1"`, `"title": "M2"`, 1)
+	if tampered == body {
+		t.Fatal("found no title to tamper with")
+	}
+	if _, err = extractPlan(tampered); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("tampered plan: got error %v", err)
+	}
+	hidden := strings.Replace(body, planDiffFence, "```\n", 1)
+	if _, err = extractPlan(hidden); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("plan without a diff: got error %v", err)
+	}
+}
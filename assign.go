@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// maxSearchResults is the most results the search API will return for any one query.
+const maxSearchResults = 1000
+
+// searchScope returns the search qualifiers limiting a query to the given orgs and repos. (The user:
+// qualifier matches the repos owned by an org as well as those owned by a user.)
+func searchScope(orgOrRepo string) string {
+	var quals []string
+	for _, t := range splitTargets(orgOrRepo) {
+		if strings.Contains(t, "/") {
+			quals = append(quals, "repo:"+t)
+		} else {
+			quals = append(quals, "user:"+t)
+		}
+	}
+	return strings.Join(quals, " ")
+}
+
+// searchIssues returns every issue and PR matching the search query, along with the repo each is in.
+func searchIssues(gh *github.Client, query string) ([]*github.Issue, []repo, error) {
+	var issues []*github.Issue
+	var repos []repo
+	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		res, resp, err := gh.Search.Issues(context.Background(), query, opts)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "searching for issues matching %q", query)
+		}
+		if opts.Page == 0 {
+			ghmmClient(gh).WarnPartialResults(resp, fmt.Sprintf("the search for %q", query))
+			if res.GetIncompleteResults() {
+				warnf("the search for %q timed out, so some matching issues may be missing", query)
+			}
+			if res.GetTotal() > maxSearchResults {
+				warnf("%d issues match %q, but only the first %d can be searched; narrow the query and re-run",
+					res.GetTotal(), query, maxSearchResults)
+			}
+		}
+		for i := range res.Issues {
+			iss := &res.Issues[i]
+			// Search results don't include the repo itself, just its API URL, which ends with owner/name.
+			parts := strings.Split(iss.GetRepositoryURL(), "/")
+			if len(parts) < 2 {
+				continue
+			}
+			issues = append(issues, iss)
+			repos = append(repos, repo(parts[len(parts)-2]+"/"+parts[len(parts)-1]))
+		}
+		if resp.NextPage == 0 {
+			return issues, repos, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// doAssignIssues finds the issues and PRs across an org's repos that match a search query, and assigns
+// each to the given milestone in its repo. Issues already in the milestone, and those in repos that are
+// excluded from the target, are left alone.
+func doAssignIssues(orgOrRepo, milestone, query string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+	repos := make(map[repo]*repoSnapshot)
+	for _, rs := range snap.Repos {
+		repos[repo(strings.ToLower(string(rs.Repo)))] = rs
+	}
+
+	issues, issueRepos, err := searchIssues(gh, query+" "+searchScope(orgOrRepo))
+	if err != nil {
+		return err
+	}
+
+	type assignment struct {
+		repo  repo
+		issue *github.Issue
+		to    *github.Milestone
+	}
+	var assigns []assignment
+	var targets []repo
+	seen := make(map[repo]bool)
+	var outside int
+	for i, iss := range issues {
+		rs := repos[repo(strings.ToLower(string(issueRepos[i])))]
+		if rs == nil {
+			outside++
+			continue
+		}
+		if iss.GetMilestone().GetTitle() == milestone {
+			continue
+		}
+		m := rs.Milestone(milestone)
+		if m == nil {
+			warnRepo(rs.Repo, fmt.Sprintf("issue #%d in repo %s matches, but the repo has no open %s milestone",
+				iss.GetNumber(), rs.Repo, milestone),
+				"issues match in repos with no open %s milestone: %s", milestone)
+			continue
+		}
+		if !seen[rs.Repo] {
+			seen[rs.Repo] = true
+			targets = append(targets, rs.Repo)
+		}
+		assigns = append(assigns, assignment{repo: rs.Repo, issue: iss, to: m})
+	}
+	if outside > 0 {
+		infof("skipping %d matching issues in repos excluded from %s", outside, orgOrRepo)
+	}
+	if err = checkWriteAccess(gh, targets); err != nil {
+		return err
+	}
+
+	for _, a := range assigns {
+		r, n := a.repo, a.issue.GetNumber()
+		if !applying() {
+			fmt.Fprintf(stdout, "would assign issue #%d in repo %s to milestone %s\n", n, r, milestone)
+			continue
+		}
+		number := a.to.GetNumber()
+		req := &github.IssueRequest{Milestone: &number}
+		if _, _, err = gh.Issues.Edit(context.Background(), r.Owner(), r.Name(), n, req); err != nil {
+			return errors.Wrapf(err, "assigning issue #%d in repo %s to milestone %s", n, r, milestone)
+		}
+		fmt.Fprintf(stdout, "assigned issue #%d in repo %s to milestone %s\n", n, r, milestone)
+	}
+
+	switch c := len(assigns); {
+	case c == 0:
+		infof("no matching issues to assign to milestone %s", milestone)
+	case applying():
+		successf("assigned %d issues to milestone %s", c, milestone)
+	default:
+		fmt.Fprintf(stdout, "would assign %d issues to milestone %s; re-run with --yes to assign them\n", c, milestone)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// auditLog is where to append a record of every plan of milestone changes, and of the results of applying
+// it: the path of a file, or "syslog" to send the records to the system log. It is empty, and nothing is
+// recorded, unless the audit log is enabled.
+var auditLog string
+
+// auditRecord is a single entry in the audit log, written as one line of JSON.
+type auditRecord struct {
+	Time       time.Time      `json:"time"`
+	User       string         `json:"user"`                 // the local user who ran ghmm.
+	GitHubUser string         `json:"githubUser,omitempty"` // who the changes were made as on GitHub.
+	Command    []string       `json:"command"`              // the command line, with secrets redacted.
+	Applied    bool           `json:"applied"`              // false for dry-runs and plans posted for approval.
+	Changes    []*auditChange `json:"changes"`
+}
+
+// auditChange is a planned change, along with its outcome: "planned" in a dry-run, and otherwise "applied"
+// or "failed", with the error.
+type auditChange struct {
+	*change
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// auditChanges records a plan's changes in the audit log, along with which of them failed if it was applied.
+// A record that can't be written is warned about, rather than failing the command, since by the time it is
+// written the changes have already been made.
+func auditChanges(gh *github.Client, changes []*change, applied bool, failed []*failedChange) {
+	if auditLog == "" || len(changes) == 0 {
+		return
+	}
+
+	rec := auditRecord{Time: time.Now(), GitHubUser: auditGitHubUser(gh), Applied: applied}
+	if u, err := user.Current(); err == nil {
+		rec.User = u.Username
+	}
+	for _, arg := range os.Args {
+		rec.Command = append(rec.Command, redact(arg))
+	}
+	errs := make(map[*change]string)
+	for _, f := range failed {
+		errs[f.Change] = f.Error
+	}
+	for _, c := range changes {
+		ac := &auditChange{change: c, Result: "planned"}
+		if applied {
+			if e, bad := errs[c]; bad {
+				ac.Result, ac.Error = "failed", e
+			} else {
+				ac.Result = "applied"
+			}
+		}
+		rec.Changes = append(rec.Changes, ac)
+	}
+
+	b, err := json.Marshal(rec)
+	if err == nil {
+		err = writeAudit(b)
+	}
+	if err != nil {
+		warnf("could not record the changes in the audit log: %v", err)
+	}
+}
+
+// auditGitHubUser returns the login that changes are made as, or a description of the GitHub App
+// installation, if authenticating as one.
+func auditGitHubUser(gh *github.Client) string {
+	if app.configured() {
+		return fmt.Sprintf("app %d", app.ID)
+	}
+	u, _, err := gh.Users.Get(context.Background(), "")
+	if err != nil {
+		debugf("could not look up the authenticated user for the audit log: %v", err)
+		return ""
+	}
+	return u.GetLogin()
+}
+
+// writeAudit appends a single record to the audit log.
+func writeAudit(b []byte) error {
+	if auditLog == "syslog" {
+		// Shell out rather than use log/syslog, which isn't available on every platform.
+		out, err := exec.Command("logger", "-t", "ghmm", "-p", "auth.notice", string(b)).CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "running logger: %s", strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	f, err := os.OpenFile(auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "opening audit log %s", auditLog)
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "writing audit log %s", auditLog)
+	}
+	return errors.Wrapf(f.Close(), "writing audit log %s", auditLog)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// resolveCredential turns a credential source from the config file into a token. Sources take one of
+// the following forms:
+//
+//	env:NAME       read the token from the environment variable NAME
+//	file:PATH      read the token from the file at PATH (a leading ~ expands to the home directory)
+//	cmd:COMMAND    run COMMAND with the shell and use its output as the token
+//	enc:PATH       decrypt the token from the encrypted token file at PATH
+func resolveCredential(source string) (string, error) {
+	ix := strings.Index(source, ":")
+	if ix == -1 {
+		return "", errors.Errorf("malformed credential source %q; expected env:, file:, cmd:, or enc: prefix", source)
+	}
+	kind, val := source[:ix], source[ix+1:]
+
+	var tok string
+	switch kind {
+	case "env":
+		tok = os.Getenv(val)
+		if tok == "" {
+			return "", errors.Errorf("environment variable %s is empty or unset", val)
+		}
+	case "file", "enc":
+		if strings.HasPrefix(val, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", errors.Wrap(err, "expanding ~")
+			}
+			val = filepath.Join(home, val[2:])
+		}
+		if kind == "enc" {
+			tok, err := loadEncryptedToken(val)
+			registerSecret(tok)
+			return tok, err
+		}
+		b, err := ioutil.ReadFile(val)
+		if err != nil {
+			return "", errors.Wrapf(err, "reading token file %s", val)
+		}
+		tok = string(b)
+	case "cmd":
+		out, err := exec.Command("sh", "-c", val).Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "running token command %q", val)
+		}
+		tok = string(out)
+	default:
+		return "", errors.Errorf("unrecognized credential source kind %q; expected env, file, cmd, or enc", kind)
+	}
+
+	tok = strings.TrimSpace(tok)
+	registerSecret(tok)
+	return tok, nil
+}
+
+// tokenEnvVars are the environment variables consulted for a token when --token isn't given, in order.
+var tokenEnvVars = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+// tokenOrigin describes where resolveToken found the default token, for `ghmm auth status`.
+var tokenOrigin string
+
+// resolveToken determines the default token: --token if it was given, otherwise the first of the token
+// environment variables that is set, then the config file's token source, then the contents of the
+// encrypted token file, if there is one, then the token stored in the OS keychain by `ghmm auth login`, and
+// otherwise the token the gh CLI is logged in with, if any.
+func resolveToken() (string, error) {
+	if token != "" {
+		registerSecret(token)
+		tokenOrigin = "--token"
+		return token, nil
+	}
+	for _, env := range tokenEnvVars {
+		if tok := os.Getenv(env); tok != "" {
+			registerSecret(tok)
+			tokenOrigin = "$" + env
+			return tok, nil
+		}
+	}
+	if cfg.Token != "" {
+		tok, err := resolveCredential(cfg.Token)
+		tokenOrigin = "the config file (" + cfg.Token + ")"
+		return tok, errors.Wrap(err, "resolving the token configured in the config file")
+	}
+
+	path := tokenFilePath
+	if path == "" {
+		if path = defaultTokenFilePath(); path != "" {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				path = ""
+			}
+		}
+	}
+	if path != "" {
+		tok, err := loadEncryptedToken(path)
+		registerSecret(tok)
+		tokenOrigin = "the encrypted token file " + path
+		return tok, err
+	}
+
+	host := credentialHost()
+	if tok, err := keychainGet(host); err != nil || tok != "" {
+		tokenOrigin = "the OS keychain"
+		return tok, err
+	}
+
+	// Finally, reuse the gh CLI's login, if there is one.
+	tok := ghCLIToken(host)
+	registerSecret(tok)
+	if tok != "" {
+		tokenOrigin = "the gh CLI's login"
+	}
+	return tok, nil
+}
+
+// credentialTransport authenticates each request with the token mapped to the owner it targets in the
+// config file, falling back to the default token (if any) for everything else. Mapped credentials are
+// resolved lazily, the first time a request for that owner is made.
+type credentialTransport struct {
+	base    http.RoundTripper
+	def     http.RoundTripper
+	sources map[string]string // owner (lowercased) to credential source.
+
+	mu     sync.Mutex
+	owners map[string]http.RoundTripper // owner (lowercased) to its authenticating transport.
+}
+
+func newCredentialTransport(base http.RoundTripper, defToken string, sources map[string]string) *credentialTransport {
+	t := &credentialTransport{
+		base:    base,
+		def:     base,
+		sources: make(map[string]string),
+		owners:  make(map[string]http.RoundTripper),
+	}
+	if defToken != "" {
+		t.def = tokenTransport(base, defToken)
+	}
+	for owner, src := range sources {
+		t.sources[strings.ToLower(owner)] = src
+	}
+	return t
+}
+
+// tokenTransport returns a transport that authenticates requests with the given static token.
+func tokenTransport(base http.RoundTripper, tok string) http.RoundTripper {
+	return &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tok}),
+		Base:   base,
+	}
+}
+
+// requestOwner extracts the user or organization that an API request targets, if any.
+func requestOwner(req *http.Request) string {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	// GitHub Enterprise serves the API under an /api/v3 prefix.
+	if len(parts) > 2 && parts[0] == "api" && parts[1] == "v3" {
+		parts = parts[2:]
+	}
+	if len(parts) > 1 {
+		switch parts[0] {
+		case "repos", "orgs", "users":
+			return strings.ToLower(parts[1])
+		}
+	}
+	return ""
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt, err := t.transportFor(requestOwner(req))
+	if err != nil {
+		return nil, err
+	}
+	return rt.RoundTrip(req)
+}
+
+// transportFor returns the transport that authenticates requests for the given owner.
+func (t *credentialTransport) transportFor(owner string) (http.RoundTripper, error) {
+	src, ok := t.sources[owner]
+	if !ok {
+		return t.def, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rt, ok := t.owners[owner]; ok {
+		return rt, nil
+	}
+	tok, err := resolveCredential(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving token for %s", owner)
+	}
+	rt := tokenTransport(t.base, tok)
+	t.owners[owner] = rt
+	return rt, nil
+}
+
+var (
+	ssoMu     sync.Mutex
+	ssoWarned = make(map[string]bool) // owners whose SSO failure has already been explained.
+)
+
+// ssoAuthorizationURL returns the URL at which the token may be authorized for SAML SSO, if err is the
+// result of GitHub rejecting a request because the token has not yet been authorized.
+func ssoAuthorizationURL(err error) (string, bool) {
+	ghErr, ok := errors.Cause(err).(*github.ErrorResponse)
+	if !ok || ghErr.Response == nil || ghErr.Response.StatusCode != http.StatusForbidden {
+		return "", false
+	}
+	h := ghErr.Response.Header.Get(ghmm.SSOHeader)
+	if !strings.HasPrefix(h, "required") {
+		return "", false
+	}
+	for _, part := range strings.Split(h, ";") {
+		if part = strings.TrimSpace(part); strings.HasPrefix(part, "url=") {
+			return strings.TrimPrefix(part, "url="), true
+		}
+	}
+	return "", true
+}
+
+// skipUnauthorized returns true if err means the token is not authorized for r's organization via SAML SSO,
+// in which case the repo should be skipped. The first time this happens for an org, it explains how to
+// authorize the token; afterwards it just notes each skipped repo.
+func skipUnauthorized(err error, r repo) bool {
+	url, ok := ssoAuthorizationURL(err)
+	if !ok {
+		return false
+	}
+
+	ssoMu.Lock()
+	defer ssoMu.Unlock()
+	if owner := strings.ToLower(r.Owner()); !ssoWarned[owner] {
+		ssoWarned[owner] = true
+		if url != "" {
+			warnf("the token is not authorized for SAML single sign-on in org %s; skipping its repos\n"+
+				"  to authorize it, visit: %s", r.Owner(), url)
+		} else {
+			warnf("the token is not authorized for SAML single sign-on in org %s; skipping its repos", r.Owner())
+		}
+	}
+	warnRepo(r, fmt.Sprintf("skipped repo %s (SAML SSO authorization required)", r),
+		"SAML SSO authorization required; skipped %s")
+	return true
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// sparkTicks are the bars of a sparkline, from lowest to highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a row of bars scaled between zero and the largest of them.
+func sparkline(values []int) string {
+	var max int
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 {
+			i = v * (len(sparkTicks) - 1) / max
+		}
+		b.WriteRune(sparkTicks[i])
+	}
+	return b.String()
+}
+
+// burndownPoint is the state of a milestone's work at the end of one sample period.
+type burndownPoint struct {
+	At     time.Time
+	Open   int
+	Closed int
+}
+
+// burndown samples how many of the given issues were open and closed at each interval, from start up to end.
+// Issues count from when they were created, since GitHub doesn't record when they were added to a milestone.
+func burndown(issues []*github.Issue, start, end time.Time, every time.Duration) []burndownPoint {
+	var points []burndownPoint
+	for at := start; ; at = at.Add(every) {
+		if at.After(end) {
+			at = end
+		}
+		pt := burndownPoint{At: at}
+		for _, iss := range issues {
+			if iss.GetCreatedAt().After(at) {
+				continue
+			}
+			if closed := iss.GetClosedAt(); !closed.IsZero() && !closed.After(at) {
+				pt.Closed++
+			} else {
+				pt.Open++
+			}
+		}
+		points = append(points, pt)
+		if !at.Before(end) {
+			return points
+		}
+	}
+}
+
+// doBurndown reports how a milestone's open and closed issue and PR counts, summed across every repo that
+// has it, have changed over time.
+func doBurndown(orgOrRepo, milestone, interval string) error {
+	every, err := parseCadence(interval)
+	if err != nil {
+		return err
+	}
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	var ms []*github.Milestone
+	var repos []repo
+	for _, rs := range snap.Repos {
+		if m := rs.Milestone(milestone); m != nil {
+			ms = append(ms, m)
+			repos = append(repos, rs.Repo)
+		}
+	}
+	if len(ms) == 0 {
+		return errors.Errorf("milestone %s does not exist in %s", milestone, orgOrRepo)
+	}
+
+	found := make([][]*github.Issue, len(ms))
+	err = parallel(len(ms), func(i int) error {
+		var err error
+		found[i], err = ghmmClient(gh).ListMilestoneIssues(context.Background(), repos[i], ms[i].GetNumber(), "all")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The burndown runs from when the milestone was first created until now, or until it was last closed.
+	var issues []*github.Issue
+	var start, end time.Time
+	closed := true
+	for i, m := range ms {
+		issues = append(issues, found[i]...)
+		if c := m.GetCreatedAt(); start.IsZero() || c.Before(start) {
+			start = c
+		}
+		if m.GetState() == "open" {
+			closed = false
+		} else if c := m.GetClosedAt(); c.After(end) {
+			end = c
+		}
+	}
+	if !closed || end.IsZero() {
+		end = time.Now()
+	}
+	points := burndown(issues, start, end, every)
+
+	tab := newTable(
+		column{Name: "DATE"},
+		column{Name: "OPEN"},
+		column{Name: "CLOSED"},
+		column{Name: "COMPLETE"},
+	)
+	var opens []int
+	for _, pt := range points {
+		tab.AddRow(
+			cell{Text: inDueZone(pt.At).Format("Mon Jan _2 2006")},
+			cell{Text: strconv.Itoa(pt.Open)},
+			cell{Text: strconv.Itoa(pt.Closed)},
+			cell{Text: completion(pt.Open, pt.Closed)},
+		)
+		opens = append(opens, pt.Open)
+	}
+	tab.Print()
+
+	fmt.Fprintf(stdout, "open issues and PRs in milestone %s across %d repos: %s\n",
+		milestone, len(ms), sparkline(opens))
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// icsEscaper escapes text values in iCalendar content lines (RFC 5545, section 3.3.11).
+var icsEscaper = strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\n", "\\n")
+
+// icsLine folds a content line so that no line exceeds 75 octets, as RFC 5545 requires, without splitting
+// a UTF-8 sequence.
+func icsLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		n := 75
+		for n > 0 && line[n]&0xC0 == 0x80 {
+			n--
+		}
+		b.WriteString(line[:n] + "\r\n ")
+		line = line[n:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// doCalendar writes an iCalendar file with an all-day event on the due date of each open milestone, so that
+// release schedules can be imported into team calendars.
+func doCalendar(orgOrRepo string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+	var milestones []*milestone
+	for _, ms := range snap.Aggregate() {
+		if !ms.DueOn.IsZero() {
+			milestones = append(milestones, ms)
+		}
+	}
+	sortMilestones(milestones, "due")
+
+	var b strings.Builder
+	icsLine(&b, "BEGIN:VCALENDAR")
+	icsLine(&b, "VERSION:2.0")
+	icsLine(&b, "PRODID:-//ghmm//GitHub Milestone Manager//EN")
+	icsLine(&b, "CALSCALE:GREGORIAN")
+	icsLine(&b, "X-WR-CALNAME:"+icsEscaper.Replace(orgOrRepo+" milestones"))
+	stamp := snap.Taken.UTC().Format("20060102T150405Z")
+	for _, ms := range milestones {
+		day := inDueZone(ms.DueOn)
+		icsLine(&b, "BEGIN:VEVENT")
+		// The UID stays the same from one export to the next, so that re-importing updates events in place.
+		icsLine(&b, "UID:"+url.PathEscape(orgOrRepo+"/"+ms.Title)+"@ghmm")
+		icsLine(&b, "DTSTAMP:"+stamp)
+		icsLine(&b, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+		icsLine(&b, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		icsLine(&b, "SUMMARY:"+icsEscaper.Replace(ms.Title+" due"))
+		desc := fmt.Sprintf("Milestone %s is due in %d repos, with %d open and %d closed issues and PRs.",
+			ms.Title, len(ms.Repos), ms.OpenIssues, ms.ClosedIssues)
+		if ms.Description != "" {
+			desc += "\n\n" + ms.Description
+		}
+		icsLine(&b, "DESCRIPTION:"+icsEscaper.Replace(desc))
+		icsLine(&b, "TRANSP:TRANSPARENT")
+		icsLine(&b, "END:VEVENT")
+	}
+	icsLine(&b, "END:VCALENDAR")
+
+	_, err = fmt.Fprint(stdout, b.String())
+	return err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+)
+
+// checkResult is the machine-readable summary printed by check.
+type checkResult struct {
+	Target     string         `json:"target"`
+	OK         bool           `json:"ok"`
+	Repos      int            `json:"repos"`
+	Milestones int            `json:"milestones"`
+	Skipped    []repo         `json:"skipped,omitempty"` // repos that could not be read, and so weren't checked.
+	Problems   []checkProblem `json:"problems"`
+}
+
+// checkProblem is a single inconsistency found by check.
+type checkProblem struct {
+	Kind      ghmm.DriftKind `json:"kind"` // missing, due, state, duplicate, or unexpected.
+	Milestone string         `json:"milestone"`
+	Repo      repo           `json:"repo"`
+	Has       string         `json:"has,omitempty"`    // for due date and state drift, the repo's value...
+	Expect    string         `json:"expect,omitempty"` // ...and the value the other repos have.
+}
+
+// doCheckMilestones verifies that every open milestone exists in every repo (or, with a release train, in
+// exactly the train's repos), with the same due date everywhere. It prints a JSON summary of any problems
+// to stdout; the problems are also warned about as drift, so that the command exits with exitWarnings.
+func doCheckMilestones(orgOrRepo string, train bool, specFile string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+	if train || specFile != "" {
+		if err = useSpecTrain(gh, snap, specFile); err != nil {
+			return err
+		}
+	}
+
+	checkingDrift = true
+	res := &checkResult{
+		Target:     orgOrRepo,
+		Repos:      len(snap.Repos),
+		Milestones: len(snap.Aggregate()),
+		Skipped:    snap.Skipped,
+		Problems:   []checkProblem{},
+	}
+	repos := make([]*ghmm.RepoMilestones, len(snap.Repos))
+	for i, rs := range snap.Repos {
+		repos[i] = &ghmm.RepoMilestones{Repo: rs.Repo, Milestones: rs.Milestones}
+	}
+	for _, d := range ghmm.DetectDrift(repos, snap.InTrain) {
+		p := checkProblem{Kind: d.Kind, Milestone: d.Title, Repo: d.Repo}
+		switch d.Kind {
+		case ghmm.StateDrift:
+			p.Has, p.Expect = d.State, d.ExpectState
+		case ghmm.DueDrift:
+			p.Has, p.Expect = checkDate(d.DueOn), checkDate(d.ExpectDueOn)
+		}
+		res.Problems = append(res.Problems, p)
+	}
+	res.OK = len(res.Problems) == 0 && len(res.Skipped) == 0
+	return printJSON(res)
+}
+
+// checkDate formats a due date for check's summary, as RFC 3339 like list's JSON, or "none" if there is none.
+func checkDate(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// doCloneMilestones copies the source repo's open milestones, with their due dates and descriptions, into
+// the target repos, so that a new repo starts out in step with an existing one. Milestones the target already
+// has, by title, are left alone.
+func doCloneMilestones(source, target string) error {
+	src, err := ghmm.ParseRepo(source)
+	if err != nil {
+		return err
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	// Read the source directly, rather than through a snapshot, so that --include and --exclude, which
+	// choose the target repos, can't filter it out.
+	milestones, err := ghmmClient(gh).ListMilestones(context.Background(), src, "open")
+	if err != nil {
+		return errors.Wrapf(err, "listing the milestones of source repo %s", src)
+	}
+	if len(milestones) == 0 {
+		fmt.Fprintf(stdout, "repo %s has no open milestones to clone\n", src)
+		return nil
+	}
+
+	// Look at every target milestone, closed ones included, since titles must be unique within a repo.
+	snap, err := takeSnapshot(gh, target, "all")
+	if err != nil {
+		return err
+	}
+
+	var p plan
+	var existing int
+	for _, rs := range snap.Repos {
+		if rs.Repo == src {
+			continue
+		}
+		for _, m := range milestones {
+			if len(rs.MilestonesTitled(m.GetTitle())) > 0 {
+				existing++
+				continue
+			}
+			f := fieldsOf(m)
+			f.State = "open"
+			p.Create(rs.Repo, m.GetTitle(), f)
+		}
+	}
+	if existing > 0 {
+		infof("skipping %d milestones that already exist in the target repos", existing)
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("cloned %d milestones from %s", c, src)
+		} else {
+			fmt.Fprintf(stdout, "would clone %d milestones from %s; re-run with --yes to create them\n", c, src)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// theme maps each kind of highlighted output to the color used to render it. A color is either one of
+// the names in colorCodes, a raw ANSI SGR parameter string such as "1;35", or "none" to disable it.
+type theme struct {
+	Overdue string `yaml:"overdue"` // due dates that have already passed.
+	Warning string `yaml:"warning"` // warnings about drift and other problems.
+	Success string `yaml:"success"` // summaries of successfully applied changes.
+	Repo    string `yaml:"repo"`    // repository names.
+	Removed string `yaml:"removed"` // old values in the diffs of dry-runs.
+	Added   string `yaml:"added"`   // new values in the diffs of dry-runs.
+}
+
+// defaultTheme is used for any theme entry that the configuration leaves unset.
+var defaultTheme = theme{
+	Overdue: "red",
+	Warning: "yellow",
+	Success: "green",
+	Repo:    "cyan",
+	Removed: "red",
+	Added:   "green",
+}
+
+// colorCodes maps friendly color names to their ANSI SGR parameters.
+var colorCodes = map[string]string{
+	"black":          "30",
+	"red":            "31",
+	"green":          "32",
+	"yellow":         "33",
+	"blue":           "34",
+	"magenta":        "35",
+	"cyan":           "36",
+	"white":          "37",
+	"bright-black":   "90",
+	"bright-red":     "91",
+	"bright-green":   "92",
+	"bright-yellow":  "93",
+	"bright-blue":    "94",
+	"bright-magenta": "95",
+	"bright-cyan":    "96",
+	"bright-white":   "97",
+	"bold":           "1",
+}
+
+// currentTheme returns the configured theme, with defaults filled in for any unset entries.
+func currentTheme() theme {
+	t := cfg.Colors
+	if t.Overdue == "" {
+		t.Overdue = defaultTheme.Overdue
+	}
+	if t.Warning == "" {
+		t.Warning = defaultTheme.Warning
+	}
+	if t.Success == "" {
+		t.Success = defaultTheme.Success
+	}
+	if t.Repo == "" {
+		t.Repo = defaultTheme.Repo
+	}
+	if t.Removed == "" {
+		t.Removed = defaultTheme.Removed
+	}
+	if t.Added == "" {
+		t.Added = defaultTheme.Added
+	}
+	return t
+}
+
+// sgr turns a theme color into its ANSI SGR parameters, returning "" if the color is disabled or unknown.
+func sgr(color string) string {
+	if color == "" || color == "none" {
+		return ""
+	}
+	if code, ok := colorCodes[color]; ok {
+		return code
+	}
+	if strings.Trim(color, "0123456789;") == "" {
+		return color
+	}
+	return ""
+}
+
+// colorMode controls colored output: "always", "never", or "auto" to color only when writing to a terminal.
+var colorMode = "auto"
+
+// checkColorMode validates the --color flag.
+func checkColorMode() error {
+	switch colorMode {
+	case "always", "never", "auto":
+		return nil
+	default:
+		return errors.Errorf("unrecognized --color value %q; expected always, never, or auto", colorMode)
+	}
+}
+
+// colorEnabled returns true if colored output should be written to the given file. Unless colors were
+// forced on or off with --color, they are used only for terminals, and never when NO_COLOR is set
+// (see https://no-color.org) or the terminal is dumb.
+func colorEnabled(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the escape sequences for the given theme color, if colors are enabled for f.
+func paint(f *os.File, s string, color string) string {
+	code := sgr(color)
+	if code == "" || s == "" || !colorEnabled(f) {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// warnf prints a warning to stderr, unless --quiet was passed.
+func warnf(format string, args ...interface{}) {
+	countWarning()
+	if level < normalLevel {
+		return
+	}
+	fmt.Fprintf(stderr, "%s %s\n",
+		paint(os.Stderr, "warning:", currentTheme().Warning), fmt.Sprintf(format, args...))
+}
+
+// successf prints a summary of successfully applied changes to stdout.
+func successf(format string, args ...interface{}) {
+	fmt.Fprintln(stdout, paint(os.Stdout, fmt.Sprintf(format, args...), currentTheme().Success))
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Shell completion is driven by a hidden __complete command, which is given the words typed so far and
+// prints one candidate per line for the last of them. The scripts for each shell just call it, so that
+// completion behaves the same everywhere, and can suggest things only GitHub knows, such as milestone titles.
+
+// completeAnnotation is the command annotation listing what each positional argument is, for completion:
+// "org" for an org or repo, and "milestone" for a milestone title in it.
+const completeAnnotation = "ghmm_complete_args"
+
+// completeArgs records what a command's positional arguments are, so that they can be completed.
+func completeArgs(cmd *cobra.Command, kinds ...string) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[completeAnnotation] = strings.Join(kinds, ",")
+}
+
+// completionTTL is how long org and milestone candidates are cached, so that repeated presses of tab don't
+// each wait on GitHub.
+const completionTTL = 5 * time.Minute
+
+// doComplete prints the completion candidates for the last of the given words, which follow the program name.
+func doComplete(root *cobra.Command, words []string) error {
+	// Warnings would only garble the shell's display.
+	stderr = ioutil.Discard
+
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	partial, before := words[len(words)-1], words[:len(words)-1]
+	cmd, rest, err := root.Find(before)
+	if err != nil {
+		return nil
+	}
+
+	// Separate the positional arguments from the flags, skipping flags' values.
+	var args []string
+	var flagValue bool
+	for _, w := range rest {
+		switch {
+		case flagValue:
+			flagValue = false
+		case strings.HasPrefix(w, "-"):
+			if !strings.Contains(w, "=") {
+				flagValue = takesValue(cmd, w)
+			}
+		default:
+			args = append(args, w)
+		}
+	}
+	if flagValue {
+		// The word is a flag's value, which we know nothing about; let the shell fall back to files.
+		return nil
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(partial, "-"):
+		add := func(f *pflag.Flag) {
+			if !f.Hidden {
+				candidates = append(candidates, "--"+f.Name)
+			}
+		}
+		cmd.NonInheritedFlags().VisitAll(add)
+		cmd.InheritedFlags().VisitAll(add)
+	case cmd.HasAvailableSubCommands() && len(args) == 0:
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() {
+				candidates = append(candidates, sub.Name())
+			}
+		}
+	default:
+		candidates = completeArg(cmd, args)
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, partial) {
+			fmt.Fprintln(stdout, c)
+		}
+	}
+	return nil
+}
+
+// takesValue returns true if the named flag (as typed, with its dashes) needs a value.
+func takesValue(cmd *cobra.Command, w string) bool {
+	var f *pflag.Flag
+	if strings.HasPrefix(w, "--") {
+		f = cmd.Flags().Lookup(w[2:])
+	} else if len(w) == 2 {
+		f = cmd.Flags().ShorthandLookup(w[1:])
+	}
+	return f != nil && f.NoOptDefVal == ""
+}
+
+// completeArg returns the candidates for a command's next positional argument, given those before it.
+func completeArg(cmd *cobra.Command, args []string) []string {
+	kinds := strings.Split(cmd.Annotations[completeAnnotation], ",")
+	// With a default target, the org may be left off, so the first argument may be either.
+	if def := defaultTarget(); def != "" && kinds[0] == "org" && len(args) == 0 && len(kinds) > 1 {
+		return append(completeOrgs(), completeMilestones(def)...)
+	}
+	if len(args) >= len(kinds) {
+		return nil
+	}
+	switch kinds[len(args)] {
+	case "org":
+		return completeOrgs()
+	case "milestone":
+		target := args[0]
+		if defaultTarget() != "" && len(args) < len(kinds)-1 {
+			target = defaultTarget()
+		}
+		return completeMilestones(target)
+	}
+	return nil
+}
+
+// completeOrgs returns the orgs the user belongs to, along with their own login.
+func completeOrgs() []string {
+	return cachedCandidates("orgs", func(gh *github.Client) ([]string, error) {
+		ctx := context.Background()
+		var names []string
+		if user, _, err := gh.Users.Get(ctx, ""); err == nil {
+			names = append(names, user.GetLogin())
+		}
+		orgs, _, err := gh.Organizations.List(ctx, "", &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orgs {
+			names = append(names, o.GetLogin())
+		}
+		return names, nil
+	})
+}
+
+// completeMilestones returns the titles of the open milestones in an org or repo.
+func completeMilestones(target string) []string {
+	return cachedCandidates("milestones/"+strings.ToLower(target), func(gh *github.Client) ([]string, error) {
+		snap, err := takeSnapshot(gh, target, "open")
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		var titles []string
+		for _, rs := range snap.Repos {
+			for _, m := range rs.Milestones {
+				if t := m.GetTitle(); !seen[t] {
+					seen[t] = true
+					titles = append(titles, t)
+				}
+			}
+		}
+		return titles, nil
+	})
+}
+
+// cachedCandidates returns completion candidates from the cache if they are fresh enough, and otherwise
+// fetches and caches them. Any failure simply means no candidates.
+func cachedCandidates(name string, fetch func(*github.Client) ([]string, error)) []string {
+	type cached struct {
+		Fetched    time.Time `json:"fetched"`
+		Candidates []string  `json:"candidates"`
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return nil
+	}
+	// ghClient sets the scope, so that different credentials never see each other's candidates.
+	key := fmt.Sprintf("completion/%s/%s.json", repoCacheScope, name)
+	st, stErr := cacheStore()
+	if stErr == nil {
+		if b, err := st.Get(key); err == nil {
+			var c cached
+			if json.Unmarshal(b, &c) == nil && time.Since(c.Fetched) < completionTTL {
+				return c.Candidates
+			}
+		}
+	}
+
+	candidates, err := fetch(gh)
+	if err != nil {
+		return nil
+	}
+	sort.Strings(candidates)
+	if stErr == nil {
+		if b, err := json.Marshal(&cached{Fetched: time.Now(), Candidates: candidates}); err == nil {
+			_ = st.Put(key, b)
+		}
+	}
+	return candidates
+}
+
+// completionScripts are the scripts that hook each shell's completion up to __complete.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for ghmm; load with: source <(ghmm completion bash)
+_ghmm() {
+    local IFS=$'\n'
+    COMPREPLY=($(ghmm __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -o filenames -F _ghmm ghmm
+`,
+	"zsh": `#compdef ghmm
+# zsh completion for ghmm; load with: source <(ghmm completion zsh)
+_ghmm() {
+    local -a candidates
+    candidates=("${(@f)$(ghmm __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -Q -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef _ghmm ghmm
+`,
+	"fish": `# fish completion for ghmm; load with: ghmm completion fish | source
+complete -c ghmm -f -a '(ghmm __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`,
+}
+
+// doCompletion prints the completion script for the given shell.
+func doCompletion(shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return errors.Errorf("unsupported shell %q; expected bash, zsh, or fish", shell)
+	}
+	fmt.Fprint(stdout, script)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// config holds the user's settings, loaded from the ghmm configuration file if one exists.
+type config struct {
+	// Token is the credential source (env:, file:, cmd:, or enc:) of the token to use when neither --token
+	// nor $GITHUB_TOKEN or $GH_TOKEN is given.
+	Token string `yaml:"token"`
+	// Org is the org (or repo) that commands operate on when none is given; $GHMM_ORG overrides it.
+	Org string `yaml:"org"`
+	// Exclude lists glob patterns of repos to always skip, in addition to any given with --exclude.
+	Exclude []string `yaml:"exclude"`
+	// DateFormat is the layout, in Go's reference time notation, of due dates given on the command line.
+	DateFormat string `yaml:"dateFormat"`
+	// Concurrency is the default for --concurrency.
+	Concurrency int `yaml:"concurrency"`
+	// Slack is the URL of a Slack incoming webhook, or a credential source for it, used when --notify-slack
+	// isn't given.
+	Slack string `yaml:"slack"`
+	// API is the default for --api.
+	API string `yaml:"api"`
+	// App is a GitHub App installation to authenticate as, rather than with a token.
+	App appConfig `yaml:"app"`
+	// Timezone is the default for --timezone.
+	Timezone string `yaml:"timezone"`
+	// BaseURL is the API URL of a GitHub Enterprise Server instance to use instead of github.com.
+	BaseURL string `yaml:"baseURL"`
+	// Colors overrides the default color theme used for terminal output.
+	Colors theme `yaml:"colors"`
+	// Tokens maps owners (orgs or users) to the credential source used for requests that target them.
+	Tokens map[string]string `yaml:"tokens"`
+	// Next controls how the milestone that follows another is named and scheduled.
+	Next nextConfig `yaml:"next"`
+	// Rules automatically assign new issues to milestones.
+	Rules []*assignRule `yaml:"rules"`
+	// Webhook configures the webhook server.
+	Webhook webhookConfig `yaml:"webhook"`
+	// AuditLog is the default for --audit-log.
+	AuditLog string `yaml:"auditLog"`
+	// Store selects where cached state, such as due date history, is kept.
+	Store storeConfig `yaml:"store"`
+}
+
+// cfg is the configuration in effect for this invocation.
+var cfg config
+
+// configPath returns the location of the configuration file, honoring $XDG_CONFIG_HOME if it is set.
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ghmm", "config.yaml")
+}
+
+// loadConfig reads the configuration file into cfg. A missing file is not an error; it simply leaves
+// every setting at its default.
+func loadConfig() error {
+	path := configPath()
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "reading config file %s", path)
+	}
+	if err = yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return errors.Wrapf(err, "parsing config file %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// prune, when converging on a milestone spec, closes open milestones that the spec doesn't list.
+var prune bool
+
+// isSpecFile returns true if the file given to `ghmm apply` is a milestone spec, rather than a saved plan.
+func isSpecFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// planSpec plans the changes that converge the snapshot's repos on the spec. Each milestone the spec lists
+// is created, in every repo it selects, if it is open and missing, and is otherwise edited to match the
+// spec's title, state, and whichever of the due date and description the spec gives. Repos that the spec
+// excludes from a milestone are left alone. With --prune, open milestones that the spec doesn't list are
+// closed.
+func planSpec(snap *orgSnapshot, s *spec) *plan {
+	listed := make(map[string]bool)
+	for _, ms := range s.Milestones {
+		listed[ms.Title] = true
+	}
+
+	p := &plan{}
+	for _, rs := range snap.Repos {
+		if !snap.InTrain(rs.Repo) {
+			continue
+		}
+		for _, ms := range s.Milestones {
+			if !ms.Repos.Matches(rs.Repo) {
+				continue
+			}
+			existing := rs.MilestonesTitled(ms.Title)
+			if len(existing) == 0 {
+				if ms.State == "open" {
+					p.Create(rs.Repo, ms.Title, milestoneFields{
+						State:       ms.State,
+						DueOn:       ms.dueOn,
+						Description: ms.Description,
+					})
+				}
+				continue
+			}
+			for _, m := range existing {
+				fields := fieldsOf(m)
+				fields.State = ms.State
+				if !ms.dueOn.IsZero() {
+					fields.DueOn = ms.dueOn
+				}
+				if ms.Description != "" {
+					fields.Description = ms.Description
+				}
+				p.Edit(rs.Repo, m, fields)
+			}
+		}
+
+		if prune {
+			for _, m := range rs.Milestones {
+				if m.GetState() == "open" && !listed[m.GetTitle()] {
+					fields := fieldsOf(m)
+					fields.State = "closed"
+					p.Edit(rs.Repo, m, fields)
+				}
+			}
+		}
+	}
+	return p
+}
+
+// doApplySpec converges the milestones of an org's repos on a milestone spec, creating, editing, and
+// closing milestones as needed. If the spec lists a release train, only the train's repos are touched.
+func doApplySpec(orgOrRepo, file string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	s, err := loadSpec(gh, "", file)
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	if len(s.Train) > 0 {
+		snap.UseTrain(s.Train)
+	}
+
+	p := planSpec(snap, s)
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	creates, edits := p.Count(createChange), p.Count(editChange)
+	if creates+edits == 0 {
+		fmt.Fprintf(stdout, "%s already matches milestone spec %s\n", orgOrRepo, file)
+	} else if applying() {
+		successf("converged %s on milestone spec %s: created %d and edited %d milestones across %d repos",
+			orgOrRepo, file, creates, edits, len(p.Repos()))
+	} else {
+		fmt.Fprintf(stdout, "would create %d and edit %d milestones across %d repos; re-run with --yes to "+
+			"apply them\n", creates, edits, len(p.Repos()))
+	}
+	return nil
+}
+
+// doApply applies either a milestone spec, given the org or repo to converge on it, or a saved plan.
+func doApply(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing plan or milestone spec file to apply")
+	}
+	if len(args) == 1 && !isSpecFile(args[0]) {
+		return doApplyPlanFile(args[0])
+	}
+	args = withDefaultTarget(args, 2)
+	if len(args) < 2 {
+		return errors.New("missing org or repo to converge on the milestone spec")
+	} else if !isSpecFile(args[1]) {
+		return errors.Errorf("%s is not a milestone spec; expected a .yaml or .yml file", args[1])
+	}
+	return doApplySpec(args[0], args[1])
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"gopkg.in/yaml.v2"
+)
+
+// testSpecSnapshot is an org whose repos mostly agree on their milestones, but not entirely.
+func testSpecSnapshot() *orgSnapshot {
+	jul, aug := dueOnDate(2019, 7, 1), dueOnDate(2019, 8, 1)
+	m2 := testMilestone(3, "M2", "open", aug, 0)
+	m2.Description = github.String("The second")
+	return testSnapshot(
+		&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{
+			testMilestone(1, "M0", "closed", time.Time{}, 0),
+			testMilestone(2, "M1", "open", jul, 0),
+			m2,
+		}},
+		&repoSnapshot{Repo: "acme/b", Milestones: []*github.Milestone{
+			testMilestone(1, "M1", "open", aug, 0),
+			testMilestone(2, "Backlog", "open", time.Time{}, 0),
+		}},
+		&repoSnapshot{Repo: "acme/infra", Milestones: []*github.Milestone{}},
+	)
+}
+
+func TestPlanSpec(t *testing.T) {
+	defer func() { prune = false }()
+
+	tests := []struct {
+		name  string
+		spec  string
+		prune bool
+		want  []string
+	}{
+		{
+			name: "create and edit",
+			spec: `
+milestones:
+- title: M1
+  due: 2019-07-01
+- title: M2
+  description: Second
+- title: M0
+  state: closed
+`,
+			want: []string{
+				`edit acme/a#3 M2 open 2019-08-01 "Second"`,
+				`edit acme/b#1 M1 open 2019-07-01 ""`,
+				`create acme/b#0 M2 open none "Second"`,
+				`create acme/infra#0 M1 open 2019-07-01 ""`,
+				`create acme/infra#0 M2 open none "Second"`,
+			},
+		},
+		{
+			name: "close",
+			spec: `
+milestones:
+- title: M1
+  state: closed
+  repos:
+    exclude: [acme/b]
+`,
+			want: []string{`edit acme/a#2 M1 closed 2019-07-01 ""`},
+		},
+		{
+			name: "include",
+			spec: `
+milestones:
+- title: M3
+  due: 9/1/2019
+  repos:
+    include: [acme/i*]
+`,
+			want: []string{`create acme/infra#0 M3 open 2019-09-01 ""`},
+		},
+		{
+			name: "train",
+			spec: `
+train: [acme/a, acme/b]
+milestones:
+- title: M2
+`,
+			want: []string{`create acme/b#0 M2 open none ""`},
+		},
+		{
+			name: "prune",
+			spec: `
+milestones:
+- title: M1
+  due: 2019-07-01
+  repos:
+    include: [acme/a, acme/b]
+`,
+			prune: true,
+			want: []string{
+				`edit acme/a#3 M2 closed 2019-08-01 "The second"`,
+				`edit acme/b#1 M1 open 2019-07-01 ""`,
+				`edit acme/b#2 Backlog closed none ""`,
+			},
+		},
+	}
+	for _, test := range tests {
+		s, err := parseSpec([]byte(test.spec), test.name)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		snap := testSpecSnapshot()
+		if len(s.Train) > 0 {
+			snap.UseTrain(s.Train)
+		}
+		prune = test.prune
+		checkPlan(t, test.name, planSpec(snap, s), test.want)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, bad := range []string{
+		"milestones:\n- due: 2019-07-01\n",
+		"milestones:\n- title: M1\n- title: M1\n",
+		"milestones:\n- title: M1\n  state: done\n",
+		"milestones:\n- title: M1\n  due: someday\n",
+		"milestones:\n- title: M1\n  repos:\n    include: ['acme/[']\n",
+		"train: ['[']\n",
+		"milestones:\n- title: M1\n  owner: joe\n",
+	} {
+		if _, err := parseSpec([]byte(bad), "test"); err == nil {
+			t.Errorf("parsed bad spec:\n%s", bad)
+		}
+	}
+}
+
+func TestExportSpecRoundTrip(t *testing.T) {
+	defer takeWarnings()
+
+	// Exporting an org's milestones and converging the org on the result changes only what had drifted.
+	snap := testSpecSnapshot()
+	b, err := yaml.Marshal(exportSpec(snap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := parseSpec(b, "export")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, b)
+	}
+	checkPlan(t, "export", planSpec(snap, s), []string{`edit acme/b#1 M1 open 2019-07-01 ""`})
+
+	// And once converged, the org matches the export exactly.
+	for _, rs := range snap.Repos {
+		if rs.Repo == "acme/b" {
+			jul := dueOnDate(2019, 7, 1)
+			rs.Milestones[0].DueOn = &jul
+		}
+	}
+	if b2, err := yaml.Marshal(exportSpec(snap)); err != nil {
+		t.Fatal(err)
+	} else if string(b2) != string(b) {
+		t.Errorf("converging changed the export:\n%s\nwant\n%s", b2, b)
+	}
+	checkPlan(t, "converged", planSpec(snap, s), nil)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// workCounts splits a milestone's open and closed work into issues and pull requests, since "5 open" means
+// something very different when they're all unreviewed PRs rather than untriaged bugs.
+type workCounts struct {
+	OpenIssues   int
+	ClosedIssues int
+	OpenPRs      int
+	ClosedPRs    int
+}
+
+func (w *workCounts) add(o *workCounts) {
+	w.OpenIssues += o.OpenIssues
+	w.ClosedIssues += o.ClosedIssues
+	w.OpenPRs += o.OpenPRs
+	w.ClosedPRs += o.ClosedPRs
+}
+
+// workCountsBatch is how many repos are queried in a single GraphQL request.
+const workCountsBatch = 25
+
+// workCountsFields is the GraphQL selection used to count a milestone's issues and PRs.
+const workCountsFields = `pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        openIssues: issues(states: OPEN) { totalCount }
+        closedIssues: issues(states: CLOSED) { totalCount }
+        openPRs: pullRequests(states: OPEN) { totalCount }
+        closedPRs: pullRequests(states: [CLOSED, MERGED]) { totalCount }
+      }`
+
+type totalCount struct {
+	TotalCount int `json:"totalCount"`
+}
+
+// FetchWorkCounts queries the split issue and PR counts for every milestone in the snapshot, batching many
+// repos into each GraphQL request. The REST API only reports combined counts. It does nothing if the
+// snapshot already has them, as it does when taken with --api graphql.
+func (snap *orgSnapshot) FetchWorkCounts(gh *github.Client) error {
+	if snap.Counts != nil {
+		return nil
+	}
+
+	states := "[OPEN, CLOSED]"
+	switch snap.State {
+	case "open":
+		states = "[OPEN]"
+	case "closed":
+		states = "[CLOSED]"
+	}
+
+	snap.Counts = make(map[string]*workCounts)
+	for start := 0; start < len(snap.Repos); start += workCountsBatch {
+		end := start + workCountsBatch
+		if end > len(snap.Repos) {
+			end = len(snap.Repos)
+		}
+		if err := snap.fetchWorkCountsBatch(gh, snap.Repos[start:end], states); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchWorkCountsBatch queries the split counts for a batch of repos' milestones. Repos with more than a
+// page of milestones are queried again, on their own, for the pages after, until none are left.
+func (snap *orgSnapshot) fetchWorkCountsBatch(gh *github.Client, batch []*repoSnapshot, states string) error {
+	cursors := make([]*string, len(batch))
+	pending := make([]int, len(batch))
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 {
+		var params []string
+		var query bytes.Buffer
+		vars := make(map[string]interface{})
+		for _, i := range pending {
+			params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!, $a%d: String", i, i, i))
+			vars[fmt.Sprintf("o%d", i)] = batch[i].Repo.Owner()
+			vars[fmt.Sprintf("n%d", i)] = batch[i].Repo.Name()
+			vars[fmt.Sprintf("a%d", i)] = cursors[i]
+			fmt.Fprintf(&query, "  r%d: repository(owner: $o%d, name: $n%d) {\n"+
+				"    milestones(first: 100, after: $a%d, states: %s) {\n      %s\n    }\n  }\n",
+				i, i, i, i, states, workCountsFields)
+		}
+		q := fmt.Sprintf("query(%s) {\n%s}", strings.Join(params, ", "), query.String())
+
+		var res map[string]*struct {
+			Milestones struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Number       int        `json:"number"`
+					OpenIssues   totalCount `json:"openIssues"`
+					ClosedIssues totalCount `json:"closedIssues"`
+					OpenPRs      totalCount `json:"openPRs"`
+					ClosedPRs    totalCount `json:"closedPRs"`
+				} `json:"nodes"`
+			} `json:"milestones"`
+		}
+		if err := graphQL(context.Background(), gh, q, vars, &res); err != nil {
+			return errors.Wrap(err, "counting milestone issues and pull requests")
+		}
+
+		var next []int
+		for _, i := range pending {
+			r := res[fmt.Sprintf("r%d", i)]
+			if r == nil {
+				continue
+			}
+			for _, n := range r.Milestones.Nodes {
+				snap.Counts[slipKey(batch[i].Repo, n.Number)] = &workCounts{
+					OpenIssues:   n.OpenIssues.TotalCount,
+					ClosedIssues: n.ClosedIssues.TotalCount,
+					OpenPRs:      n.OpenPRs.TotalCount,
+					ClosedPRs:    n.ClosedPRs.TotalCount,
+				}
+			}
+			if r.Milestones.PageInfo.HasNextPage {
+				cursors[i] = github.String(r.Milestones.PageInfo.EndCursor)
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v19/github"
+)
+
+func TestFetchWorkCountsPages(t *testing.T) {
+	// acme/a has two pages of milestones and acme/b just one, so only acme/a is asked for a second page.
+	var queries int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]*string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		queries++
+
+		page := func(number int, next string) map[string]interface{} {
+			node := map[string]interface{}{
+				"number":       number,
+				"openIssues":   map[string]int{"totalCount": number},
+				"closedIssues": map[string]int{"totalCount": 0},
+				"openPRs":      map[string]int{"totalCount": 1},
+				"closedPRs":    map[string]int{"totalCount": 0},
+			}
+			return map[string]interface{}{"milestones": map[string]interface{}{
+				"pageInfo": map[string]interface{}{"hasNextPage": next != "", "endCursor": next},
+				"nodes":    []interface{}{node},
+			}}
+		}
+		data := make(map[string]interface{})
+		if a := body.Variables["a0"]; a == nil {
+			data["r0"] = page(1, "c1")
+		} else if *a == "c1" {
+			data["r0"] = page(2, "")
+		}
+		if _, ok := body.Variables["n1"]; ok {
+			data["r1"] = page(3, "")
+		}
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": data}); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer srv.Close()
+
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(srv.URL + "/")
+	snap := &orgSnapshot{Target: "acme", State: "all", Repos: []*repoSnapshot{{Repo: "acme/a"}, {Repo: "acme/b"}}}
+	if err := snap.FetchWorkCounts(gh); err != nil {
+		t.Fatal(err)
+	}
+
+	if queries != 2 {
+		t.Errorf("got %d queries, want 2", queries)
+	}
+	for _, k := range []struct {
+		repo   repo
+		number int
+	}{{"acme/a", 1}, {"acme/a", 2}, {"acme/b", 3}} {
+		c := snap.Counts[slipKey(k.repo, k.number)]
+		if c == nil || c.OpenIssues != k.number || c.OpenPRs != 1 {
+			t.Errorf("%s#%d: got counts %+v", k.repo, k.number, c)
+		}
+	}
+	if len(snap.Counts) != 3 {
+		t.Errorf("got %d counts, want 3", len(snap.Counts))
+	}
+}
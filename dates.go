@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// timezone names the time zone whose calendar days due dates fall on.
+	timezone string
+	// dueLocation is the loaded time zone, or nil to use GitHub's conventional 7am UTC.
+	dueLocation *time.Location
+)
+
+// dueOnDate returns the moment a milestone due on the given calendar day is due: the start of that day in
+// the configured time zone, or, by default, 7am UTC (the start of the day in US Pacific time, as GitHub's
+// own UI sets it).
+func dueOnDate(y int, m time.Month, d int) time.Time {
+	if dueLocation != nil {
+		return time.Date(y, m, d, 0, 0, 0, 0, dueLocation)
+	}
+	return time.Date(y, m, d, 7, 0, 0, 0, time.UTC)
+}
+
+// inDueZone converts a due date into the configured time zone for display, so that it shows the calendar
+// day it was meant to fall on.
+func inDueZone(t time.Time) time.Time {
+	if dueLocation != nil && !t.IsZero() {
+		return t.In(dueLocation)
+	}
+	return t
+}
+
+// isoDateFormat is the ISO 8601 calendar date layout, accepted wherever a due date is.
+const isoDateFormat = "2006-01-02"
+
+// parseMilestoneDueOn parses an absolute due date, trying the configured date format (1/2/2006 by default),
+// then ISO 8601 (2006-01-02), and then RFC 3339. Dates given in RFC 3339 are used exactly as given; the
+// others are due at the time of day GitHub milestones are.
+func parseMilestoneDueOn(d string) (time.Time, error) {
+	for _, layout := range []string{dateFormat, isoDateFormat} {
+		if t, err := time.Parse(layout, d); err == nil {
+			return dueOnDate(t.Date()), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, d); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.Errorf("malformed date %q; please use %s, %s, or RFC 3339 format",
+		d, dateFormat, isoDateFormat)
+}
+
+// todayDueOn returns the due date of a milestone due today.
+func todayDueOn() time.Time {
+	now := time.Now()
+	if dueLocation != nil {
+		now = now.In(dueLocation)
+	}
+	return dueOnDate(now.Date())
+}
+
+// dueDate is a due date as given on the command line: either an absolute date, or an offset such as +2w
+// from some other date. The zero value is no due date at all.
+type dueDate struct {
+	at     time.Time // the absolute date, if the due date isn't relative.
+	rel    bool      // true if the due date is relative.
+	days   int       // the relative offset in days...
+	months int       // ...and months.
+}
+
+// parseDueDate parses a due date given either absolutely, or relative to some other date as a number of
+// days, weeks, or months, like +30d, +2w, or +1m (or, to pull a date in, -1w).
+func parseDueDate(s string) (dueDate, error) {
+	if len(s) > 2 && (s[0] == '+' || s[0] == '-') {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err == nil && n > 0 {
+			if s[0] == '-' {
+				n = -n
+			}
+			switch s[len(s)-1] {
+			case 'd':
+				return dueDate{rel: true, days: n}, nil
+			case 'w':
+				return dueDate{rel: true, days: 7 * n}, nil
+			case 'm':
+				return dueDate{rel: true, months: n}, nil
+			}
+		}
+		return dueDate{}, errors.Errorf("malformed relative date %q; expected a number of days, weeks, or months, "+
+			"like +30d, +2w, or +1m", s)
+	}
+	t, err := parseMilestoneDueOn(s)
+	if err != nil {
+		return dueDate{}, err
+	}
+	return dueDate{at: t}, nil
+}
+
+// From resolves the due date, taking a relative one from the given base date, or from today if the base
+// date is zero.
+func (d dueDate) From(base time.Time) time.Time {
+	if !d.rel {
+		return d.at
+	}
+	if base.IsZero() {
+		base = todayDueOn()
+	}
+	return base.AddDate(0, d.months, d.days)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDueDate(t *testing.T) {
+	base := dueOnDate(2019, 7, 1)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "8/1/2019", want: dueOnDate(2019, 8, 1)},
+		{in: "2019-08-01", want: dueOnDate(2019, 8, 1)},
+		{in: "2019-08-01T00:00:00-07:00", want: time.Date(2019, 8, 1, 7, 0, 0, 0, time.UTC)},
+		{in: "+30d", want: dueOnDate(2019, 7, 31)},
+		{in: "+2w", want: dueOnDate(2019, 7, 15)},
+		{in: "-1w", want: dueOnDate(2019, 6, 24)},
+		{in: "+1m", want: dueOnDate(2019, 8, 1)},
+	}
+	for _, test := range tests {
+		d, err := parseDueDate(test.in)
+		if err != nil {
+			t.Errorf("parseDueDate(%q): %v", test.in, err)
+			continue
+		}
+		if got := d.From(base); !got.Equal(test.want) {
+			t.Errorf("parseDueDate(%q).From(%v) = %v, want %v", test.in, base, got, test.want)
+		}
+	}
+
+	for _, in := range []string{"", "tomorrow", "13/45/2019", "+d", "+0w", "+2y", "2019-8-1"} {
+		if _, err := parseDueDate(in); err == nil {
+			t.Errorf("parseDueDate(%q) succeeded", in)
+		}
+	}
+
+	// Relative dates from no date at all are taken from today, and the zero due date is no date at all.
+	if got, want := (dueDate{rel: true, days: 1}).From(time.Time{}), todayDueOn().AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("+1d from no date = %v, want %v", got, want)
+	}
+	if got := (dueDate{}).From(base); !got.IsZero() {
+		t.Errorf("no due date = %v", got)
+	}
+}
+
+func TestDueZone(t *testing.T) {
+	defer func() { dueLocation = nil }()
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	dueLocation = loc
+	d := dueOnDate(2019, 7, 1)
+	if !d.Equal(time.Date(2019, 6, 30, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("due on 7/1 in Tokyo is %v", d.UTC())
+	}
+	if got := inDueZone(d.UTC()).Format(isoDateFormat); got != "2019-07-01" {
+		t.Errorf("displayed as %s", got)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Settings that may be given by flag, environment variable, or config file are resolved with that
+// precedence: a flag passed on the command line wins, then the environment, then the config file, and
+// finally the built-in default.
+
+// defaultDateFormat is the layout, in Go's reference time notation, of due dates given on the command line.
+const defaultDateFormat = "1/2/2006"
+
+// dateFormat is the layout in effect for due dates given on the command line.
+var dateFormat = defaultDateFormat
+
+// applyDefaults fills in the settings not given by flag from the environment or the config file. It runs
+// after the config file is loaded, and before any command.
+func applyDefaults(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+
+	if !flags.Changed("concurrency") {
+		if env := os.Getenv("GHMM_CONCURRENCY"); env != "" {
+			n, err := strconv.Atoi(env)
+			if err != nil || n < 1 {
+				return errors.Errorf("malformed $GHMM_CONCURRENCY %q; expected a positive number", env)
+			}
+			concurrency = n
+		} else if cfg.Concurrency > 0 {
+			concurrency = cfg.Concurrency
+		}
+	}
+
+	if err := applyAppDefaults(flags.Changed); err != nil {
+		return err
+	}
+
+	if !flags.Changed("api") {
+		if env := os.Getenv("GHMM_API"); env != "" {
+			apiBackend = env
+		} else if cfg.API != "" {
+			apiBackend = cfg.API
+		}
+	}
+	if err := checkAPIBackend(apiBackend); err != nil {
+		return err
+	}
+
+	if !flags.Changed("timezone") {
+		if env := os.Getenv("GHMM_TIMEZONE"); env != "" {
+			timezone = env
+		} else {
+			timezone = cfg.Timezone
+		}
+	}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return errors.Wrapf(err, "unrecognized time zone %q", timezone)
+		}
+		dueLocation = loc
+	}
+
+	if !flags.Changed("notify-slack") && cfg.Slack != "" {
+		if strings.HasPrefix(cfg.Slack, "https://") {
+			slackWebhook = cfg.Slack
+		} else {
+			url, err := resolveCredential(cfg.Slack)
+			if err != nil {
+				return errors.Wrap(err, "resolving the Slack webhook configured in the config file")
+			}
+			slackWebhook = url
+		}
+	}
+	// Incoming webhook URLs embed the credential to post with.
+	registerSecret(slackWebhook)
+
+	if !flags.Changed("audit-log") {
+		if env := os.Getenv("GHMM_AUDIT_LOG"); env != "" {
+			auditLog = env
+		} else {
+			auditLog = cfg.AuditLog
+		}
+	}
+
+	if env := os.Getenv("GHMM_DATE_FORMAT"); env != "" {
+		dateFormat = env
+	} else if cfg.DateFormat != "" {
+		dateFormat = cfg.DateFormat
+	}
+
+	if reposFile != "" {
+		t, err := loadReposFile(reposFile)
+		if err != nil {
+			return err
+		}
+		reposFileTarget = t
+	}
+
+	// Exclusions accumulate rather than override, since they only ever make commands safer.
+	if env := os.Getenv("GHMM_EXCLUDE"); env != "" {
+		selector.Exclude = append(selector.Exclude, strings.Split(env, ",")...)
+	}
+	selector.Exclude = append(selector.Exclude, cfg.Exclude...)
+	return nil
+}
+
+// defaultTarget returns the org (or repo) that commands operate on when none is given.
+func defaultTarget() string {
+	if reposFileTarget != "" {
+		return reposFileTarget
+	}
+	if env := os.Getenv("GHMM_ORG"); env != "" {
+		return env
+	}
+	return cfg.Org
+}
+
+// withDefaultTarget supplies the default target for a command that takes the target followed by
+// want-1 further arguments, if the arguments given are exactly one short.
+func withDefaultTarget(args []string, want int) []string {
+	if reposFile != "" && len(args) >= want {
+		warnf("ignoring --repos-file %s, since an org or repo was given", reposFile)
+		return args
+	}
+	if t := defaultTarget(); t != "" && len(args) == want-1 {
+		return append([]string{t}, args...)
+	}
+	return args
+}
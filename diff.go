@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// planDiff renders a plan as a unified diff of each repo's milestones, with a hunk for every milestone the
+// plan creates, edits, or deletes. If f is not nil, the diff is colored for writing to it.
+func planDiff(p *plan, f *os.File) string {
+	th := currentTheme()
+	color := func(s, c string) string {
+		if f == nil {
+			return s
+		}
+		return paint(f, s, c)
+	}
+
+	var b strings.Builder
+	for _, r := range p.Repos() {
+		fmt.Fprintln(&b, color("--- "+string(r), "bold"))
+		fmt.Fprintln(&b, color("+++ "+string(r), "bold"))
+		for _, c := range p.Changes {
+			if c.Repo != r {
+				continue
+			}
+
+			var was, now milestoneFields
+			var header string
+			switch c.Kind {
+			case createChange:
+				now = c.New
+				header = fmt.Sprintf("%s, new", c.Title)
+			case editChange:
+				was, now = c.Old, c.New
+				header = fmt.Sprintf("%s (#%d)", c.Title, c.Number)
+			case deleteChange:
+				was = c.Old
+				header = fmt.Sprintf("%s (#%d), deleted", c.Title, c.Number)
+				if c.Unassign && c.Issues > 0 {
+					header += fmt.Sprintf(" after removing it from %d issues", c.Issues)
+				}
+			}
+			fmt.Fprintln(&b, color("@@ "+header+" @@", th.Repo))
+
+			oldLines, newLines := diffFields(was), diffFields(now)
+			for i := range oldLines {
+				switch {
+				case c.Kind == createChange:
+					fmt.Fprintln(&b, color("+"+newLines[i], th.Added))
+				case c.Kind == deleteChange:
+					fmt.Fprintln(&b, color("-"+oldLines[i], th.Removed))
+				case oldLines[i] == newLines[i]:
+					fmt.Fprintln(&b, " "+oldLines[i])
+				default:
+					fmt.Fprintln(&b, color("-"+oldLines[i], th.Removed))
+					fmt.Fprintln(&b, color("+"+newLines[i], th.Added))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// diffFields renders a milestone's fields as the lines of a diff, in a fixed order.
+func diffFields(f milestoneFields) []string {
+	due := "none"
+	if !f.DueOn.IsZero() {
+		due = inDueZone(f.DueOn).Format("Mon Jan _2 2006")
+	}
+	return []string{
+		"title: " + f.Title,
+		"state: " + f.State,
+		"due: " + due,
+		"description: " + fmt.Sprintf("%q", f.Description),
+	}
+}
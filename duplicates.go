@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/spf13/cobra"
+)
+
+// duplicateOptions control which milestone a mutation acts on when several in one repo share a title.
+type duplicateOptions struct {
+	PreferOpen bool // choose the one open milestone among them.
+	Number     int  // choose the milestone with this number.
+}
+
+var dupOpts duplicateOptions
+
+// addDuplicateFlags registers the flags for choosing between like-titled milestones.
+func addDuplicateFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(
+		&dupOpts.PreferOpen, "prefer-open", false,
+		"Where several milestones in a repo share the title, act on the open one")
+	cmd.PersistentFlags().IntVar(
+		&dupOpts.Number, "number", 0, "Where several milestones in a repo share the title, act on the one with this number")
+}
+
+// chooseDuplicate picks one of several milestones in a repo that share a title: by --number, then by
+// --prefer-open, then by asking, if there is a terminal to ask on. If none of those settle it, the repo is
+// skipped with a warning, rather than editing an arbitrary one of them.
+func chooseDuplicate(r repo, title string, matches []*github.Milestone) *github.Milestone {
+	if dupOpts.Number != 0 {
+		for _, m := range matches {
+			if m.GetNumber() == dupOpts.Number {
+				return m
+			}
+		}
+	}
+	if dupOpts.PreferOpen {
+		var open []*github.Milestone
+		for _, m := range matches {
+			if m.GetState() == "open" {
+				open = append(open, m)
+			}
+		}
+		if len(open) == 1 {
+			return open[0]
+		}
+	}
+	if !rpcMode && isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		if m := promptDuplicate(r, title, matches); m != nil {
+			return m
+		}
+	}
+
+	warnRepo(r, fmt.Sprintf("repo %s has %d milestones titled %s (%s); skipping it "+
+		"(pass --prefer-open or --number to choose one)", r, len(matches), title, describeDuplicates(matches)),
+		"several milestones are titled %s in %s; pass --prefer-open or --number to choose one", title)
+	return nil
+}
+
+// describeDuplicates lists like-titled milestones by number and state.
+func describeDuplicates(matches []*github.Milestone) string {
+	var descs []string
+	for _, m := range matches {
+		descs = append(descs, fmt.Sprintf("#%d %s", m.GetNumber(), m.GetState()))
+	}
+	return strings.Join(descs, ", ")
+}
+
+// promptDuplicate asks the user which of several like-titled milestones to act on, returning nil if they
+// choose none of them.
+func promptDuplicate(r repo, title string, matches []*github.Milestone) *github.Milestone {
+	fmt.Fprintf(stderr, "repo %s has %d milestones titled %s:\n", r, len(matches), title)
+	for i, m := range matches {
+		fmt.Fprintf(stderr, "  %d) #%d, %s, due %v\n", i+1, m.GetNumber(), m.GetState(), m.GetDueOn())
+	}
+	fmt.Fprintf(stderr, "which one? (1-%d, or enter to skip this repo) ", len(matches))
+	notifyInput(fmt.Sprintf("which milestone titled %s to use in repo %s", title, r))
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || i < 1 || i > len(matches) {
+		return nil
+	}
+	return matches[i-1]
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// editOptions are the fields edit changes; those left empty (or nil) are left alone.
+type editOptions struct {
+	Due         string  // the new due date: 1/2/2006, or relative to each milestone's current one, like +1w.
+	Title       string  // the new title.
+	Description *string // the new description, which may be empty to clear it.
+	State       string  // the new state: open or closed.
+}
+
+// check validates the options, returning an error if they change nothing or are malformed.
+func (opts *editOptions) check() error {
+	if opts.Due == "" && opts.Title == "" && opts.Description == nil && opts.State == "" {
+		return errors.New("nothing to edit; pass at least one of --due, --title, --description, or --state")
+	}
+	if opts.State != "" && opts.State != "open" && opts.State != "closed" {
+		return errors.Errorf("unrecognized state %q; expected open or closed", opts.State)
+	}
+	if opts.Due != "" {
+		if _, err := parseDueDate(opts.Due); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func doEditMilestone(orgOrRepo, milestone string, opts editOptions) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	p, err := planEditMilestone(snap, milestone, opts)
+	if err != nil {
+		return err
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("edited %d milestones", c)
+		} else {
+			fmt.Fprintf(stdout, "would edit %d milestones; re-run with --yes to edit them\n", c)
+		}
+	}
+
+	return nil
+}
+
+// planEditMilestone plans to change every matching milestone's fields as the options say, all in a single
+// edit per repo. As with rename, a repo where the new title is already taken is skipped entirely, rather than
+// having just its other fields changed.
+func planEditMilestone(snap *orgSnapshot, milestone string, opts editOptions) (*plan, error) {
+	var due dueDate
+	if opts.Due != "" {
+		var err error
+		if due, err = parseDueDate(opts.Due); err != nil {
+			return nil, err
+		}
+	}
+
+	var p plan
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(milestone)
+		if m == nil {
+			continue
+		}
+		f := fieldsOf(m)
+		if opts.Title != "" && opts.Title != milestone {
+			if others := rs.MilestonesTitled(opts.Title); len(others) > 0 {
+				warnRepo(rs.Repo, fmt.Sprintf("repo %s already has a milestone %s (#%d); not editing %s (#%d)",
+					rs.Repo, opts.Title, others[0].GetNumber(), milestone, m.GetNumber()),
+					"not editing milestone %s, since %s already exists in %s", milestone, opts.Title)
+				continue
+			}
+			f.Title = opts.Title
+		}
+		if opts.Due != "" {
+			f.DueOn = due.From(m.GetDueOn())
+		}
+		if opts.Description != nil {
+			f.Description = *opts.Description
+		}
+		if opts.State != "" {
+			f.State = opts.State
+		}
+		p.Edit(rs.Repo, m, f)
+	}
+	return &p, nil
+}
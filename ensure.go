@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// planEnsureMilestone plans to create the milestone in every repo that has no milestone by that title, in
+// any state. Existing milestones are left untouched, unless reconcileDue is set, in which case their due
+// dates are changed to the requested one (but their states are not).
+func planEnsureMilestone(snap *orgSnapshot, milestone string, dueOn time.Time, reconcileDue bool) (*plan, int) {
+	var p plan
+	var existing int
+	for _, rs := range snap.Repos {
+		if len(rs.MilestonesTitled(milestone)) == 0 {
+			p.Create(rs.Repo, milestone, milestoneFields{State: "open", DueOn: dueOn})
+			continue
+		}
+		existing++
+		if reconcileDue {
+			if m := rs.Milestone(milestone); m != nil {
+				f := fieldsOf(m)
+				f.DueOn = dueOn
+				p.Edit(rs.Repo, m, f)
+			}
+		}
+	}
+	return &p, existing
+}
+
+// doEnsureMilestone makes sure that every repo has the milestone, creating it just where it is missing. It
+// is idempotent, so that automation can run it repeatedly.
+func doEnsureMilestone(orgOrRepo, milestone string, dueOn time.Time, reconcileDue bool) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	p, existing := planEnsureMilestone(snap, milestone, dueOn, reconcileDue)
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	open, edit := p.Count(createChange), p.Count(editChange)
+	switch {
+	case open == 0 && edit == 0:
+		fmt.Fprintf(stdout, "all %d repos already have milestone %s\n", existing, milestone)
+	case applying():
+		successf("opened %d and rescheduled %d milestones; %d repos already had milestone %s",
+			open, edit, existing, milestone)
+	default:
+		fmt.Fprintf(stdout, "would open %d and reschedule %d milestones; %d repos already have milestone %s; "+
+			"re-run with --yes to do so\n", open, edit, existing, milestone)
+	}
+	return nil
+}
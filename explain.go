@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// explain prints a summary of the API calls made once a command finishes.
+var explain bool
+
+// rateUsage tracks consumption of a single rate-limit resource (core, search, graphql, ...).
+type rateUsage struct {
+	first     int // the remaining budget reported by the first response, plus the call that consumed it.
+	remaining int // the remaining budget reported by the latest response.
+	limit     int
+}
+
+// apiStats accumulates statistics about every API call made during this invocation.
+type apiStats struct {
+	mu        sync.Mutex
+	rest      int
+	graphql   int
+	search    int
+	cacheHits int
+	elapsed   time.Duration
+	rates     map[string]*rateUsage
+}
+
+var stats = &apiStats{rates: make(map[string]*rateUsage)}
+
+// record tallies a single completed API call.
+func (s *apiStats) record(req *http.Request, resp *http.Response, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.elapsed += elapsed
+	switch path := req.URL.Path; {
+	case strings.HasSuffix(path, "/graphql"):
+		s.graphql++
+	case strings.Contains(path, "/search/"):
+		s.search++
+	default:
+		s.rest++
+	}
+	if resp == nil {
+		return
+	}
+	if resp.StatusCode == http.StatusNotModified || resp.Header.Get("X-From-Cache") != "" {
+		s.cacheHits++
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	u, ok := s.rates[resource]
+	if !ok {
+		u = &rateUsage{first: remaining + 1}
+		s.rates[resource] = u
+	}
+	u.remaining, u.limit = remaining, limit
+}
+
+// print writes the summary of API usage to stderr.
+func (s *apiStats) print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.rest + s.graphql + s.search
+	fmt.Fprintf(stderr, "API calls: %d (REST %d, GraphQL %d, Search %d)\n", total, s.rest, s.graphql, s.search)
+	var hitRate float64
+	if total > 0 {
+		hitRate = float64(s.cacheHits) / float64(total) * 100
+	}
+	fmt.Fprintf(stderr, "Cache hits: %d (%.0f%%)\n", s.cacheHits, hitRate)
+	fmt.Fprintf(stderr, "Total API time: %v\n", s.elapsed.Round(time.Millisecond))
+
+	var resources []string
+	for r := range s.rates {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	for _, r := range resources {
+		u := s.rates[r]
+		fmt.Fprintf(stderr, "Rate limit (%s): consumed %d, %d of %d remaining\n",
+			r, u.first-u.remaining, u.remaining, u.limit)
+	}
+}
+
+// statsTransport records statistics about every request that passes through it.
+type statsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+	stats.record(req, resp, elapsed)
+	logRequest(req, resp, err, elapsed)
+	return resp, err
+}
+
+// logRequest logs a completed API call with --verbose, along with the rate limit remaining afterwards.
+func logRequest(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	if level < verboseLevel {
+		return
+	}
+	elapsed = elapsed.Round(time.Millisecond)
+	if err != nil {
+		debugf("%s %s: %v (%v)", req.Method, req.URL, err, elapsed)
+		return
+	}
+	var rate string
+	if rem := resp.Header.Get("X-RateLimit-Remaining"); rem != "" {
+		resource := resp.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = "core"
+		}
+		rate = fmt.Sprintf("; %s of %s %s remaining", rem, resp.Header.Get("X-RateLimit-Limit"), resource)
+	}
+	debugf("%s %s: %s (%v%s)", req.Method, req.URL, resp.Status, elapsed, rate)
+}
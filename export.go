@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// exportSchemaVersion is bumped whenever the shape of exported records changes incompatibly.
+const exportSchemaVersion = 1
+
+// milestoneRecord is one row of exported milestone data. See the README for the documented schema.
+type milestoneRecord struct {
+	Kind          string     `json:"kind"` // always "milestone".
+	SchemaVersion int        `json:"schema_version"`
+	SnapshotAt    time.Time  `json:"snapshot_at"`
+	Repo          string     `json:"repo"`
+	Number        int        `json:"number"`
+	Title         string     `json:"title"`
+	State         string     `json:"state"`
+	Description   string     `json:"description"`
+	DueOn         *time.Time `json:"due_on"`
+	CreatedAt     *time.Time `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	OpenIssues    int        `json:"open_issues"`
+	ClosedIssues  int        `json:"closed_issues"`
+	URL           string     `json:"url"`
+}
+
+// issueRecord is one row of exported issue data, emitted only when issues are requested.
+type issueRecord struct {
+	Kind            string     `json:"kind"` // always "issue".
+	SchemaVersion   int        `json:"schema_version"`
+	SnapshotAt      time.Time  `json:"snapshot_at"`
+	Repo            string     `json:"repo"`
+	Number          int        `json:"number"`
+	MilestoneNumber int        `json:"milestone_number"`
+	MilestoneTitle  string     `json:"milestone_title"`
+	Title           string     `json:"title"`
+	State           string     `json:"state"`
+	IsPullRequest   bool       `json:"is_pull_request"`
+	Labels          []string   `json:"labels"`
+	CreatedAt       *time.Time `json:"created_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	URL             string     `json:"url"`
+}
+
+func doExport(orgOrRepo, state string, issues bool) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, state)
+	if err != nil {
+		return err
+	}
+
+	// Write newline-delimited JSON, one record per line, as warehouse loaders like BigQuery and Athena expect.
+	enc := json.NewEncoder(stdout)
+	for _, rs := range snap.Repos {
+		for _, m := range rs.Milestones {
+			rec := milestoneRecord{
+				Kind:          "milestone",
+				SchemaVersion: exportSchemaVersion,
+				SnapshotAt:    snap.Taken.UTC(),
+				Repo:          string(rs.Repo),
+				Number:        m.GetNumber(),
+				Title:         m.GetTitle(),
+				State:         m.GetState(),
+				Description:   m.GetDescription(),
+				DueOn:         m.DueOn,
+				CreatedAt:     m.CreatedAt,
+				UpdatedAt:     m.UpdatedAt,
+				ClosedAt:      m.ClosedAt,
+				OpenIssues:    m.GetOpenIssues(),
+				ClosedIssues:  m.GetClosedIssues(),
+				URL:           m.GetHTMLURL(),
+			}
+			if err = enc.Encode(&rec); err != nil {
+				return err
+			}
+
+			if issues {
+				if err = exportIssues(gh, enc, snap, rs.Repo, m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// exportIssues writes a record for every issue and PR in the given milestone.
+func exportIssues(gh *github.Client, enc *json.Encoder, snap *orgSnapshot, r repo, m *github.Milestone) error {
+	is, err := ghmmClient(gh).ListMilestoneIssues(context.Background(), r, m.GetNumber(), "all")
+	if err != nil {
+		return err
+	}
+	for _, iss := range is {
+		labels := []string{}
+		for _, l := range iss.Labels {
+			labels = append(labels, l.GetName())
+		}
+		rec := issueRecord{
+			Kind:            "issue",
+			SchemaVersion:   exportSchemaVersion,
+			SnapshotAt:      snap.Taken.UTC(),
+			Repo:            string(r),
+			Number:          iss.GetNumber(),
+			MilestoneNumber: m.GetNumber(),
+			MilestoneTitle:  m.GetTitle(),
+			Title:           iss.GetTitle(),
+			State:           iss.GetState(),
+			IsPullRequest:   iss.IsPullRequest(),
+			Labels:          labels,
+			CreatedAt:       iss.CreatedAt,
+			ClosedAt:        iss.ClosedAt,
+			URL:             iss.GetHTMLURL(),
+		}
+		if err = enc.Encode(&rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
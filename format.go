@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// milestoneTemplate is what a --format template is executed against for each milestone. Its fields are kept
+// stable, so that scripts relying on them keep working.
+type milestoneTemplate struct {
+	Title        string
+	State        string
+	DueOn        time.Time // in the configured time zone; the zero time if the milestone has no due date.
+	Description  string
+	OpenIssues   int                 // open issues and PRs, across every repo.
+	ClosedIssues int                 // closed issues and PRs, across every repo.
+	Work         *workCountsJSON     // the split between issues and PRs.
+	Repos        []repoMilestoneJSON // the repos that have the milestone.
+	Missing      []string            // the repos that don't.
+}
+
+// templateFuncs are the functions available to --format templates, beyond Go's built-in ones.
+var templateFuncs = template.FuncMap{
+	// date formats a due date as 2006-01-02, or the given layout, or as "" if there is none.
+	"date": func(t time.Time, layout ...string) string {
+		if t.IsZero() {
+			return ""
+		}
+		if len(layout) > 0 {
+			return t.Format(layout[0])
+		}
+		return t.Format(isoDateFormat)
+	},
+	// join joins strings with a separator.
+	"join": func(sep string, s []string) string {
+		return strings.Join(s, sep)
+	},
+	// repoNames returns the names of repos.
+	"repoNames": func(repos []repoMilestoneJSON) []string {
+		var names []string
+		for _, r := range repos {
+			names = append(names, r.Repo)
+		}
+		return names
+	},
+}
+
+// parseFormat parses a --format template. Each milestone's output is followed by a newline, unless the
+// template ends with one already.
+func parseFormat(format string) (*template.Template, error) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing --format template")
+	}
+	return tmpl, nil
+}
+
+// milestoneTemplateOf returns the template data for an aggregated milestone.
+func milestoneTemplateOf(snap *orgSnapshot, ms *milestone) *milestoneTemplate {
+	mt := &milestoneTemplate{
+		Title:        ms.Title,
+		State:        ms.State,
+		DueOn:        inDueZone(ms.DueOn),
+		Description:  ms.Description,
+		OpenIssues:   ms.OpenIssues,
+		ClosedIssues: ms.ClosedIssues,
+		Missing:      []string{},
+	}
+	if w := ms.Work; w != nil {
+		mt.Work = &workCountsJSON{
+			OpenIssues:   w.OpenIssues,
+			ClosedIssues: w.ClosedIssues,
+			OpenPRs:      w.OpenPRs,
+			ClosedPRs:    w.ClosedPRs,
+		}
+	}
+	for _, rs := range snap.Repos {
+		if ms.Repos[rs.Repo] {
+			mt.Repos = append(mt.Repos, repoMilestoneJSON{
+				Repo: string(rs.Repo), Present: true, Number: ms.Numbers[rs.Repo], URL: ms.URLs[rs.Repo],
+			})
+		} else {
+			mt.Missing = append(mt.Missing, string(rs.Repo))
+		}
+	}
+	return mt
+}
+
+// printMilestonesFormat prints each milestone through a --format template.
+func printMilestonesFormat(tmpl *template.Template, snap *orgSnapshot, milestones []*milestone) error {
+	for _, ms := range milestones {
+		if err := tmpl.Execute(stdout, milestoneTemplateOf(snap, ms)); err != nil {
+			return errors.Wrapf(err, "formatting milestone %s", ms.Title)
+		}
+	}
+	return nil
+}
+
+// checkFormat validates that --format isn't combined with an --output other than text, and parses it.
+func checkFormat(format, output string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	} else if output != "text" {
+		return nil, errors.Errorf("--format and --output %s are mutually exclusive", output)
+	}
+	return parseFormat(format)
+}
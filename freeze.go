@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// flagFrozen leaves issues added to a frozen milestone in place, but labels them for triage.
+	flagFrozen = "flag"
+	// removeFrozen takes issues added to a frozen milestone back out of it.
+	removeFrozen = "remove"
+	// defaultFreezeLabel is the label applied to issues flagged by the flag policy.
+	defaultFreezeLabel = "scope-freeze"
+)
+
+// freezeSpec locks a milestone's scope, so that issues added to it from some date on are flagged or removed.
+type freezeSpec struct {
+	From   string `yaml:"from,omitempty"`   // the date the freeze starts; the milestone is frozen now if empty.
+	Policy string `yaml:"policy,omitempty"` // "flag" (the default) or "remove".
+	Label  string `yaml:"label,omitempty"`  // the label flagged issues get.
+
+	from time.Time // the parsed start date.
+}
+
+// check validates the freeze and fills in its defaults.
+func (fs *freezeSpec) check() error {
+	switch fs.Policy {
+	case "":
+		fs.Policy = flagFrozen
+	case flagFrozen, removeFrozen:
+	default:
+		return errors.Errorf("unrecognized freeze policy %q; expected %s or %s", fs.Policy, flagFrozen, removeFrozen)
+	}
+	if fs.Label == "" {
+		fs.Label = defaultFreezeLabel
+	}
+	if fs.From != "" {
+		d, err := parseMilestoneDueOn(fs.From)
+		if err != nil {
+			return errors.Wrap(err, "freeze date")
+		}
+		fs.from = d
+	}
+	return nil
+}
+
+// Frozen returns true if the freeze is in effect at the given time.
+func (fs *freezeSpec) Frozen(now time.Time) bool {
+	return fs.from.IsZero() || !now.Before(fs.from)
+}
+
+// freezeSpecFile, if set, is read for milestone freezes instead of the org's spec.
+var freezeSpecFile string
+
+// frozenMilestone returns the spec of the milestone with the given title in the given repo if its scope
+// is currently frozen, and nil otherwise.
+func frozenMilestone(gh *github.Client, r repo, title string) (*milestoneSpec, error) {
+	s, err := loadSpec(gh, r.Owner(), freezeSpecFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, ms := range s.Milestones {
+		if ms.Title == title && ms.Freeze != nil && ms.Repos.Matches(r) && ms.Freeze.Frozen(time.Now()) {
+			return ms, nil
+		}
+	}
+	return nil, nil
+}
+
+// freezeWebhookHandler enforces milestone freezes, flagging or removing issues as they are added to a
+// frozen milestone, and notifying the configured channel that it did so.
+func freezeWebhookHandler(gh *github.Client, event interface{}, opts *webhookOptions) error {
+	ev, ok := event.(*github.IssuesEvent)
+	if !ok {
+		return nil
+	}
+	iss := ev.GetIssue()
+	switch ev.GetAction() {
+	case "milestoned":
+	case "opened":
+		if iss.Milestone == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	r := repo(ev.GetRepo().GetFullName())
+	title, n := iss.GetMilestone().GetTitle(), iss.GetNumber()
+	ms, err := frozenMilestone(gh, r, title)
+	if err != nil || ms == nil {
+		return err
+	}
+
+	var verb, done, what, outcome string
+	switch ms.Freeze.Policy {
+	case removeFrozen:
+		verb, done, what = "remove", "removed", fmt.Sprintf("issue #%d in repo %s from frozen milestone %s", n, r, title)
+		outcome = "removed it"
+		if opts.Apply {
+			if err = ghmmClient(gh).ClearIssueMilestone(context.Background(), r, n); err != nil {
+				return err
+			}
+		}
+	case flagFrozen:
+		verb, done, what = "label", "labeled", fmt.Sprintf("issue #%d in repo %s %s for being added to frozen milestone %s",
+			n, r, ms.Freeze.Label, title)
+		outcome = "labeled it " + ms.Freeze.Label
+		if opts.Apply {
+			_, _, err = gh.Issues.AddLabelsToIssue(context.Background(), r.Owner(), r.Name(), n,
+				[]string{ms.Freeze.Label})
+			if err != nil {
+				return errors.Wrapf(err, "labeling issue #%d in repo %s", n, r)
+			}
+		}
+	}
+
+	if !opts.Apply {
+		fmt.Fprintf(stdout, "would %s %s\n", verb, what)
+		return nil
+	}
+	successf("%s %s", done, what)
+	return notifyChannel(fmt.Sprintf("%s added %s to frozen milestone %s; ghmm %s",
+		ev.GetSender().GetLogin(), iss.GetHTMLURL(), title, outcome))
+}
+
+// notifyChannel posts a message to the chat channel configured for the webhook server, if there is one.
+func notifyChannel(text string) error {
+	if cfg.Webhook.Notify == "" {
+		return nil
+	}
+	url, err := resolveCredential(cfg.Webhook.Notify)
+	if err != nil {
+		return errors.Wrap(err, "resolving notification webhook")
+	}
+	return postSlack(url, text)
+}
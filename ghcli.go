@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ghCLIConfigDir returns the directory in which the gh CLI keeps its configuration, following the same rules
+// gh itself does.
+func ghCLIConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if dir := os.Getenv("AppData"); dir != "" {
+		return filepath.Join(dir, "GitHub CLI")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh")
+}
+
+// credentialHost returns the host whose stored credentials to use: github.com, or that of the GitHub
+// Enterprise Server instance in use.
+func credentialHost() string {
+	base := resolveBaseURL()
+	if base == "" {
+		return "github.com"
+	}
+	if u, err := url.Parse(base); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "github.com"
+}
+
+// ghCLIToken returns the token the gh CLI is logged in with for the given host, or "" if it isn't. Older
+// versions of gh keep the token in hosts.yml; newer ones keep it in the system keyring, which only gh itself
+// can be relied upon to read, so it is asked with `gh auth token`.
+func ghCLIToken(host string) string {
+	if dir := ghCLIConfigDir(); dir != "" {
+		if b, err := ioutil.ReadFile(filepath.Join(dir, "hosts.yml")); err == nil {
+			var hosts map[string]struct {
+				OAuthToken string `yaml:"oauth_token"`
+			}
+			if yaml.Unmarshal(b, &hosts) == nil {
+				if tok := hosts[host].OAuthToken; tok != "" {
+					return tok
+				}
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -6,5 +6,8 @@ require (
 	github.com/google/go-github/v19 v19.1.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v0.0.5
+	github.com/spf13/pflag v1.0.3
+	golang.org/x/crypto v0.0.0-20200117160349-530e935923ad
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// graphQLError is a single error reported by the GraphQL API.
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// graphQLEndpoint returns the URL of the GraphQL API. On github.com it lives alongside the REST API, but GitHub
+// Enterprise Server serves it from /api/graphql rather than under the /api/v3/ REST prefix.
+func graphQLEndpoint(gh *github.Client) string {
+	u := *gh.BaseURL
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path += "graphql"
+	}
+	return u.String()
+}
+
+// graphQL runs a GraphQL query or mutation against GitHub, decoding the response's data into out.
+// Requests go through the same client (and hence the same authentication and instrumentation) as the
+// REST calls do.
+func graphQL(ctx context.Context, gh *github.Client, query string, vars map[string]interface{},
+	out interface{}) error {
+	body := map[string]interface{}{"query": query, "variables": vars}
+	req, err := gh.NewRequest("POST", graphQLEndpoint(gh), body)
+	if err != nil {
+		return errors.Wrap(err, "creating GraphQL request")
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if _, err = gh.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		var msgs []string
+		for _, e := range resp.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return errors.Errorf("GraphQL error: %s", strings.Join(msgs, "; "))
+	}
+	if out != nil {
+		if err = json.Unmarshal(resp.Data, out); err != nil {
+			return errors.Wrap(err, "decoding GraphQL response")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// responseCacheMaxAge is how long a cached response may go unused before it is evicted.
+	responseCacheMaxAge = 30 * 24 * time.Hour
+	// responseCacheMaxBytes bounds the size of the response cache; the least recently used responses are
+	// evicted to stay under it.
+	responseCacheMaxBytes = 64 << 20
+)
+
+// cachedResponse is a response body saved along with the ETag it was served with, so that later runs can
+// make the same request conditionally.
+type cachedResponse struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cachedHeaders are the response headers replayed from the cache when GitHub answers 304 Not Modified. The
+// rest, including the rate limit headers, come from the 304 response itself.
+var cachedHeaders = []string{"Content-Type", "Link"}
+
+// etagTransport sends GET requests with If-None-Match whenever an earlier run saved a response to the same
+// request, and, when GitHub replies 304 Not Modified, serves that saved response instead. Conditional
+// requests answered with 304 don't count against the rate limit, and return much sooner, so repeating a
+// command against an org whose milestones haven't changed is cheap.
+//
+// Responses may hold private repo data, so they are only ever kept in the local cache directory, never in a
+// shared store, and are cached by the credentials they were fetched with as well as by URL: the transport
+// sits beneath the one that authenticates requests, so it sees their Authorization headers. Unused
+// responses are evicted once they are a month old, or when the cache grows past its size limit.
+type etagTransport struct {
+	base http.RoundTripper
+
+	openOnce sync.Once
+	cache    *fileStore
+	cacheErr error
+	warnOnce sync.Once
+}
+
+// etagCacheKey is the key under which the response to a request is cached.
+func etagCacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization") + "\n" + req.URL.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// store returns the response cache, opening it on first use and evicting whatever has aged out.
+func (t *etagTransport) store() (*fileStore, error) {
+	t.openOnce.Do(func() {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			t.cacheErr = errors.Wrap(err, "locating cache directory")
+			return
+		}
+		t.cache = &fileStore{dir: filepath.Join(dir, "ghmm", "http")}
+		if err = evictResponses(t.cache.dir, time.Now()); err != nil {
+			t.warn(err)
+		}
+	})
+	return t.cache, t.cacheErr
+}
+
+// evictResponses removes the cached responses in dir that were last used longer ago than
+// responseCacheMaxAge, and then the least recently used ones until the rest fit in responseCacheMaxBytes.
+func evictResponses(dir string, now time.Time) error {
+	type entry struct {
+		path string
+		fi   os.FileInfo
+	}
+	var entries []entry
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !fi.IsDir() {
+			entries = append(entries, entry{path: p, fi: fi})
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "listing cached responses")
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].fi.ModTime().After(entries[j].fi.ModTime()) })
+	var size int64
+	for _, e := range entries {
+		size += e.fi.Size()
+		if now.Sub(e.fi.ModTime()) > responseCacheMaxAge || size > responseCacheMaxBytes {
+			if err = os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "evicting cached response")
+			}
+		}
+	}
+	return nil
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// Problems with the cache only ever cost us the savings, so they are worth a warning but no more.
+	st, err := t.store()
+	if err != nil {
+		t.warn(err)
+		return t.base.RoundTrip(req)
+	}
+	key := etagCacheKey(req)
+	var cached *cachedResponse
+	if b, err := st.Get(key); err == nil {
+		if err = json.Unmarshal(b, &cached); err != nil {
+			cached = nil
+		}
+	} else if err != errNotFound {
+		t.warn(err)
+	}
+
+	r := req
+	if cached != nil {
+		r = req.Clone(req.Context())
+		r.Header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// Mark the response as used, so that eviction keeps it.
+		now := time.Now()
+		_ = os.Chtimes(st.path(key), now, now)
+
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		for _, h := range cachedHeaders {
+			if v, ok := cached.Header[h]; ok {
+				resp.Header[h] = v
+			} else {
+				resp.Header.Del(h)
+			}
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		return resp, nil
+	}
+
+	if etag := resp.Header.Get("ETag"); resp.StatusCode == http.StatusOK && etag != "" {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		saved := &cachedResponse{ETag: etag, Header: make(http.Header), Body: body}
+		for _, h := range cachedHeaders {
+			if v, ok := resp.Header[h]; ok {
+				saved.Header[h] = v
+			}
+		}
+		b, err := json.Marshal(saved)
+		if err == nil {
+			err = st.Put(key, b)
+		}
+		if err != nil {
+			t.warn(err)
+		}
+	}
+	return resp, nil
+}
+
+// warn reports a problem with the response cache, just once per run.
+func (t *etagTransport) warn(err error) {
+	t.warnOnce.Do(func() {
+		warnf("could not use the response cache; requests will not be conditional: %v", err)
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETagTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghmm-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	var full, conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title":"M1"}`))
+	}))
+	defer srv.Close()
+
+	get := func(tok string) string {
+		t.Helper()
+		c := &http.Client{Transport: tokenTransport(&etagTransport{base: http.DefaultTransport}, tok)}
+		resp, err := c.Get(srv.URL + "/repos/acme/a/milestones")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", resp.Status, resp.Header.Get("Content-Type"))
+		}
+		return string(b)
+	}
+
+	// The second run asks conditionally and is served from the cache, but other tokens don't share it.
+	for _, tok := range []string{"alice", "alice", "bob"} {
+		if body := get(tok); body != `{"title":"M1"}` {
+			t.Errorf("got body %q", body)
+		}
+	}
+	if full != 2 || conditional != 1 {
+		t.Errorf("got %d full and %d conditional requests, want 2 and 1", full, conditional)
+	}
+
+	// Responses are kept in the local cache directory, once for each token.
+	if fis, err := ioutil.ReadDir(filepath.Join(dir, "ghmm", "http")); err != nil || len(fis) != 2 {
+		t.Errorf("got cached responses %v (%v)", fis, err)
+	}
+}
+
+func TestEvictResponses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghmm-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(strings.Repeat("x", size)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/recent", 10, time.Hour)
+	write("a/stale", 10, responseCacheMaxAge+time.Hour)
+	write("b/big", responseCacheMaxBytes-5, 2*time.Hour)
+	write("b/oldest", 10, 3*time.Hour)
+
+	if err := evictResponses(dir, now); err != nil {
+		t.Fatal(err)
+	}
+	fs := &fileStore{dir: dir}
+	keys, err := fs.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(keys, " "); got != "a/recent" {
+		t.Errorf("kept %s, want a/recent", got)
+	}
+
+	if err := evictResponses(filepath.Join(dir, "missing"), now); err != nil {
+		t.Errorf("evicting from a missing cache: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// journalPrefix is the store key prefix under which the journal of applied changes is kept, one entry per
+// run, so that a run's changes can be undone.
+const journalPrefix = "journal/"
+
+// maxJournalEntries is how many runs the journal remembers; older entries are pruned as new ones are added.
+const maxJournalEntries = 100
+
+// undoing is the ID of the journal entry being undone, if any, so that the undo's own entry can say so.
+var undoing int
+
+// journalEntry records the changes a single run applied successfully, along with the values they replaced.
+type journalEntry struct {
+	ID      int       `json:"id"`
+	Command []string  `json:"command"` // the command line, with secrets redacted.
+	Time    time.Time `json:"time"`
+	Changes []*change `json:"changes"`
+	Undoes  int       `json:"undoes,omitempty"` // the ID of the entry this run undid, if it was an undo.
+}
+
+// journalKey returns the store key of the journal entry with the given ID. IDs are zero-padded so that the
+// store lists entries in order.
+func journalKey(id int) string {
+	return fmt.Sprintf("%s%08d.json", journalPrefix, id)
+}
+
+// journalIDs returns the IDs of every entry in the journal, oldest first.
+func journalIDs(st store) ([]int, error) {
+	keys, err := st.List(journalPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the journal")
+	}
+	var ids []int
+	for _, k := range keys {
+		id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(k, journalPrefix), ".json"))
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// recordJournal adds the changes a run applied to the journal, pruning the oldest entries beyond
+// maxJournalEntries. As with the run summary, failing to record them is only a warning.
+func recordJournal(changes []*change) {
+	if len(changes) == 0 {
+		return
+	}
+	st, err := cacheStore()
+	if err != nil {
+		warnf("could not record the changes in the undo journal: %v", err)
+		return
+	}
+	ids, err := journalIDs(st)
+	if err != nil {
+		warnf("could not record the changes in the undo journal: %v", err)
+		return
+	}
+
+	e := journalEntry{ID: 1, Time: time.Now(), Changes: changes, Undoes: undoing}
+	if len(ids) > 0 {
+		e.ID = ids[len(ids)-1] + 1
+	}
+	for _, arg := range os.Args {
+		e.Command = append(e.Command, redact(arg))
+	}
+	b, err := json.MarshalIndent(e, "", "    ")
+	if err != nil {
+		warnf("could not record the changes in the undo journal: %v", err)
+		return
+	}
+	if err = st.Put(journalKey(e.ID), b); err != nil {
+		warnf("could not record the changes in the undo journal: %v", err)
+		return
+	}
+	debugf("recorded %d changes in the undo journal as entry %d", len(changes), e.ID)
+
+	for len(ids) >= maxJournalEntries {
+		if err = st.Delete(journalKey(ids[0])); err != nil {
+			warnf("could not prune the undo journal: %v", err)
+			return
+		}
+		ids = ids[1:]
+	}
+}
+
+// loadJournalEntry reads the journal entry with the given ID, or the latest entry if id is 0.
+func loadJournalEntry(st store, id int) (*journalEntry, error) {
+	if id == 0 {
+		ids, err := journalIDs(st)
+		if err != nil {
+			return nil, err
+		} else if len(ids) == 0 {
+			return nil, errors.New("the undo journal is empty; no changes have been recorded")
+		}
+		id = ids[len(ids)-1]
+	}
+	b, err := st.Get(journalKey(id))
+	if err == errNotFound {
+		return nil, errors.Errorf("there is no entry %d in the undo journal; run `ghmm undo --list` to see them", id)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "reading undo journal entry %d", id)
+	}
+	var e journalEntry
+	if err = json.Unmarshal(b, &e); err != nil {
+		return nil, errors.Wrapf(err, "parsing undo journal entry %d", id)
+	}
+	return &e, nil
+}
+
+// Inverse plans the changes that undo the entry's, in the reverse order that they were made: created
+// milestones are deleted, edits are reverted, and deleted milestones are recreated. (A recreated milestone
+// gets a new number, and the issues that were in it are not restored.)
+func (e *journalEntry) Inverse() *plan {
+	p := &plan{}
+	for i := len(e.Changes) - 1; i >= 0; i-- {
+		c := e.Changes[i]
+		switch c.Kind {
+		case createChange:
+			p.Changes = append(p.Changes, &change{
+				Kind:   deleteChange,
+				Repo:   c.Repo,
+				Number: c.Number,
+				Title:  c.New.Title,
+				Old:    c.New,
+			})
+		case editChange:
+			p.Changes = append(p.Changes, &change{
+				Kind:   editChange,
+				Repo:   c.Repo,
+				Number: c.Number,
+				Title:  c.New.Title,
+				Old:    c.New,
+				New:    c.Old,
+			})
+		case deleteChange:
+			p.Create(c.Repo, c.Old.Title, c.Old)
+		}
+	}
+	return p
+}
+
+// doUndo reverses the changes recorded in a journal entry: the one with the given ID, or the latest if id
+// is 0. Every milestone involved must still be as the entry left it, so that an undo never clobbers changes
+// made since.
+func doUndo(id int) error {
+	st, err := cacheStore()
+	if err != nil {
+		return err
+	}
+	e, err := loadJournalEntry(st, id)
+	if err != nil {
+		return err
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	p := e.Inverse()
+	infof("undoing %d changes across %d repos from journal entry %d (%s, at %v)",
+		len(p.Changes), len(p.Repos()), e.ID, strings.Join(e.Command, " "), e.Time.Format(time.RFC1123))
+	if err = checkPlanCurrent(gh, p, "journal entry "+strconv.Itoa(e.ID)+" was recorded",
+		"undo the later changes first"); err != nil {
+		return err
+	}
+	if err = checkPlanCreates(gh, p); err != nil {
+		return err
+	}
+	undoing = e.ID
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); applying() {
+		successf("undid %d changes from journal entry %d", c, e.ID)
+	} else {
+		fmt.Fprintf(stdout, "would undo %d changes; re-run with --yes to undo them\n", c)
+	}
+	return nil
+}
+
+// doListJournal prints the entries in the undo journal, newest first.
+func doListJournal() error {
+	st, err := cacheStore()
+	if err != nil {
+		return err
+	}
+	ids, err := journalIDs(st)
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(
+		column{Name: "ID"},
+		column{Name: "TIME"},
+		column{Name: "CHANGES"},
+		column{Name: "REPOS"},
+		column{Name: "COMMAND"},
+	)
+	for i := len(ids) - 1; i >= 0; i-- {
+		e, err := loadJournalEntry(st, ids[i])
+		if err != nil {
+			return err
+		}
+		p := &plan{Changes: e.Changes}
+		cmd := strings.Join(e.Command, " ")
+		if e.Undoes != 0 {
+			cmd += fmt.Sprintf(" (undid %d)", e.Undoes)
+		}
+		tab.AddRow(
+			cell{Text: strconv.Itoa(e.ID)},
+			cell{Text: e.Time.Format(time.RFC1123)},
+			cell{Text: strconv.Itoa(len(e.Changes))},
+			cell{Text: strconv.Itoa(len(p.Repos()))},
+			cell{Text: cmd},
+		)
+	}
+	tab.Print()
+	return nil
+}
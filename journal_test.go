@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJournalInverse(t *testing.T) {
+	jul, aug := dueOnDate(2019, 7, 1), dueOnDate(2019, 8, 1)
+	e := &journalEntry{ID: 1, Changes: []*change{
+		{Kind: createChange, Repo: "acme/a", Number: 5, Title: "M2",
+			New: milestoneFields{Title: "M2", State: "open", DueOn: aug}},
+		{Kind: editChange, Repo: "acme/b", Number: 1, Title: "M1",
+			Old: milestoneFields{Title: "M1", State: "open", DueOn: jul},
+			New: milestoneFields{Title: "Spring", State: "closed", DueOn: aug}},
+		{Kind: deleteChange, Repo: "acme/c", Number: 2, Title: "M0",
+			Old: milestoneFields{Title: "M0", State: "closed", Description: "old"}},
+	}}
+
+	p := e.Inverse()
+	// The changes are undone in reverse, and each undo starts from what the change left behind.
+	checkPlan(t, "inverse", p, []string{
+		`create acme/c#0 M0 closed none "old"`,
+		`edit acme/b#1 M1 open 2019-07-01 ""`,
+		`delete acme/a#5 M2 open 2019-08-01 ""`,
+	})
+	if edit := p.Changes[1]; edit.Title != "Spring" || !edit.Old.Equal(e.Changes[1].New) {
+		t.Errorf("the reverted edit doesn't expect the milestone as the edit left it: %+v", edit)
+	}
+
+	// Undoing the undo makes the original changes again, though recreated milestones get new numbers.
+	again := (&journalEntry{Changes: p.Changes}).Inverse()
+	checkPlan(t, "inverse of the inverse", again, []string{
+		`create acme/a#0 M2 open 2019-08-01 ""`,
+		`edit acme/b#1 Spring closed 2019-08-01 ""`,
+		`delete acme/c#0 M0 closed none "old"`,
+	})
+}
+
+func TestJournalInverseDeleteIssues(t *testing.T) {
+	aug := dueOnDate(2019, 8, 1)
+	e := &journalEntry{ID: 1, Changes: []*change{
+		{Kind: createChange, Repo: "acme/a", Number: 5, Title: "M2",
+			New: milestoneFields{Title: "M2", State: "open", DueOn: aug}},
+	}}
+	undo := e.Inverse().Changes[0]
+
+	// Undoing a create deletes the milestone only if no issues have been assigned to it since.
+	m := testMilestone(5, "M2", "open", aug, 0)
+	if err := checkChangeCurrent(undo, m, "journal entry 1 was recorded", "undo the later changes first"); err != nil {
+		t.Errorf("empty milestone: %v", err)
+	}
+	closed := 2
+	m = testMilestone(5, "M2", "open", aug, 1)
+	m.ClosedIssues = &closed
+	err := checkChangeCurrent(undo, m, "journal entry 1 was recorded", "undo the later changes first")
+	if err == nil || !strings.Contains(err.Error(), "has had 3 issues assigned to it since journal entry 1") {
+		t.Errorf("milestone with issues: got error %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keychainService is the service name under which tokens are kept in the OS keychain, one per GitHub host.
+const keychainService = "ghmm"
+
+// errKeychainUnsupported is returned where ghmm doesn't know how to reach the OS keychain.
+var errKeychainUnsupported = errors.New("storing tokens in the OS keychain is only supported on macOS, and on " +
+	"Linux with secret-tool (libsecret); use `ghmm token save` for an encrypted token file instead")
+
+// The OS keychain is reached through the tools each OS provides for the purpose, which saves linking against
+// platform libraries: `security` on macOS, and libsecret's `secret-tool` on Linux desktops.
+
+// keychainGet returns the token stored in the keychain for the given host, or "" if there isn't one.
+func keychainGet(host string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", host, "-w")
+	case "linux":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return "", nil
+		}
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "host", host)
+	default:
+		return "", nil
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		// Both tools fail when there is no such item, which is no error here.
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "reading token from the OS keychain")
+	}
+	tok := strings.TrimSpace(string(out))
+	registerSecret(tok)
+	return tok, nil
+}
+
+// keychainSet stores the token in the keychain for the given host, replacing any already there.
+func keychainSet(host, tok string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// Passed as an argument, the token would be visible to every local user in the process list, so the
+		// command is fed to security's interactive mode on stdin instead.
+		registerSecret(tok)
+		return runSecurityCommand([]string{"add-generic-password", "-U", "-s", keychainService, "-a", host,
+			"-l", "ghmm (" + host + ")", "-w", tok}, "storing token in the OS keychain")
+	case "linux":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return errKeychainUnsupported
+		}
+		cmd = exec.Command("secret-tool", "store", "--label", "ghmm ("+host+")",
+			"service", keychainService, "host", host)
+		cmd.Stdin = strings.NewReader(tok)
+	default:
+		return errKeychainUnsupported
+	}
+	return runKeychain(cmd, "storing token in the OS keychain")
+}
+
+// keychainDelete removes the token stored in the keychain for the given host, if any.
+func keychainDelete(host string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", host)
+	case "linux":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return errKeychainUnsupported
+		}
+		cmd = exec.Command("secret-tool", "clear", "service", keychainService, "host", host)
+	default:
+		return errKeychainUnsupported
+	}
+	return runKeychain(cmd, "removing token from the OS keychain")
+}
+
+// runSecurityCommand runs a command in macOS's security tool by way of its interactive mode, which reads it from
+// stdin rather than the command line. That mode exits successfully even when the command fails, reporting the
+// failure only on stderr.
+func runSecurityCommand(args []string, what string) error {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a) + `"`
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(strings.Join(quoted, " ") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.Errorf("%s: %s", what, msg)
+	} else if err != nil {
+		return errors.Wrap(err, what)
+	}
+	return nil
+}
+
+// runKeychain runs a keychain tool, including whatever it printed in any error.
+func runKeychain(cmd *exec.Cmd, what string) error {
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return errors.Wrapf(err, "%s: %s", what, msg)
+		}
+		return errors.Wrap(err, what)
+	}
+	return nil
+}
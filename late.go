@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// doLateLandings finds issues and PRs that were closed after the (closed) milestone they belong to was,
+// so that release notes and patch-release decisions can account for them. If milestone is empty, every
+// closed milestone is checked.
+func doLateLandings(orgOrRepo, milestone string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "closed")
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(
+		column{Name: "REPO"},
+		column{Name: "MILESTONE"},
+		column{Name: "NUMBER"},
+		column{Name: "KIND"},
+		column{Name: "MILESTONE CLOSED"},
+		column{Name: "LANDED"},
+		column{Name: "TITLE"},
+		column{Name: "URL", Wide: true},
+	)
+	th := currentTheme()
+	var c int
+	for _, rs := range snap.Repos {
+		for _, m := range rs.Milestones {
+			if milestone != "" && m.GetTitle() != milestone {
+				continue
+			}
+			closedAt := m.GetClosedAt()
+			if closedAt.IsZero() {
+				continue
+			}
+
+			issues, err := ghmmClient(gh).ListMilestoneIssues(context.Background(), rs.Repo, m.GetNumber(), "closed")
+			if err != nil {
+				return errors.Wrapf(err, "checking for late landings in milestone %s", m.GetTitle())
+			}
+			for _, iss := range issues {
+				if !iss.GetClosedAt().After(closedAt) {
+					continue
+				}
+				kind := "issue"
+				if iss.IsPullRequest() {
+					kind = "pr"
+				}
+				tab.AddRow(
+					cell{Text: string(rs.Repo), Color: th.Repo},
+					cell{Text: m.GetTitle()},
+					cell{Text: "#" + strconv.Itoa(iss.GetNumber())},
+					cell{Text: kind},
+					cell{Text: closedAt.Format("Mon Jan _2 2006")},
+					cell{Text: iss.GetClosedAt().Format("Mon Jan _2 2006"), Color: th.Warning},
+					cell{Text: iss.GetTitle()},
+					cell{Text: iss.GetHTMLURL()},
+				)
+				c++
+			}
+		}
+	}
+	tab.Print()
+
+	if c > 0 {
+		warnf("found %d issues and PRs that landed after their milestone was closed", c)
+	}
+	return nil
+}
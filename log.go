@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// logLevel controls how much diagnostic output is written to stderr. Results (tables, JSON, and the changes
+// made or planned) are always written to stdout, whatever the level.
+type logLevel int
+
+const (
+	quietLevel   logLevel = iota // only results and errors.
+	normalLevel                  // results, errors, warnings, and progress notes.
+	verboseLevel                 // all of the above, plus per-request detail.
+)
+
+var (
+	// verbose logs per-request detail, such as the URLs requested and the rate limit remaining.
+	verbose bool
+	// quiet suppresses everything but results and errors.
+	quiet bool
+
+	level = normalLevel
+)
+
+// checkLogLevel sets the log level from --verbose and --quiet.
+func checkLogLevel() error {
+	switch {
+	case verbose && quiet:
+		return errors.New("--verbose and --quiet cannot be used together")
+	case verbose:
+		level = verboseLevel
+	case quiet:
+		level = quietLevel
+	}
+	return nil
+}
+
+// infof prints a progress or informational note to stderr, unless --quiet was passed.
+func infof(format string, args ...interface{}) {
+	if level >= normalLevel {
+		fmt.Fprintf(stderr, format+"\n", args...)
+	}
+}
+
+// debugf prints detail to stderr, only if --verbose was passed.
+func debugf(format string, args ...interface{}) {
+	if level >= verboseLevel {
+		fmt.Fprintf(stderr, "debug: "+format+"\n", args...)
+	}
+}
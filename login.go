@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// doAuthLogin reads a token, checks that GitHub accepts it, and stores it in the OS keychain, from which
+// later commands pick it up when no other token is given. With web, it first opens the page for creating a
+// token in the browser.
+func doAuthLogin(web bool) error {
+	host := credentialHost()
+	if web {
+		page := fmt.Sprintf("https://%s/settings/tokens/new?scopes=repo&description=ghmm", host)
+		fmt.Fprintf(stderr, "Create a token with the repo scope at %s, then paste it here.\n", page)
+		if err := openBrowser(page); err != nil {
+			warnf("could not open a browser: %v", err)
+		}
+	}
+
+	tok, err := readTokenInput()
+	if err != nil {
+		return err
+	} else if tok == "" {
+		return errors.New("missing token to log in with")
+	}
+	registerSecret(tok)
+
+	gh, err := clientForToken(tok)
+	if err != nil {
+		return err
+	}
+	user, _, err := gh.Users.Get(context.Background(), "")
+	if err != nil {
+		return errors.Wrapf(err, "checking the token with %s", host)
+	}
+	if err = keychainSet(host, tok); err != nil {
+		return err
+	}
+
+	successf("logged in to %s as %s; the token is stored in the OS keychain", host, user.GetLogin())
+	return nil
+}
+
+// doAuthStatus reports how requests are authenticated, and as whom.
+func doAuthStatus() error {
+	host := credentialHost()
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case app.configured():
+		fmt.Fprintf(stdout, "Authenticating to %s as GitHub App %d", host, app.ID)
+		if app.Installation != 0 {
+			fmt.Fprintf(stdout, " (installation %d)", app.Installation)
+		}
+		fmt.Fprintln(stdout)
+	case tokenOrigin == "":
+		fmt.Fprintf(stdout, "Not logged in to %s; run `ghmm auth login`, or pass --token\n", host)
+	default:
+		user, resp, err := gh.Users.Get(context.Background(), "")
+		if err != nil {
+			return errors.Wrapf(err, "checking the token from %s", tokenOrigin)
+		}
+		fmt.Fprintf(stdout, "Logged in to %s as %s, with the token from %s\n", host, user.GetLogin(), tokenOrigin)
+		if scopes := resp.Header.Get("X-OAuth-Scopes"); scopes != "" {
+			fmt.Fprintf(stdout, "Token scopes: %s\n", scopes)
+		}
+	}
+
+	if len(cfg.Tokens) > 0 {
+		var owners []string
+		for owner := range cfg.Tokens {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+		fmt.Fprintf(stdout, "Using the config file's tokens for: %s\n", strings.Join(owners, ", "))
+	}
+	return nil
+}
+
+// doAuthLogout removes the token stored by doAuthLogin.
+func doAuthLogout() error {
+	host := credentialHost()
+	tok, err := keychainGet(host)
+	if err != nil {
+		return err
+	} else if tok == "" {
+		fmt.Fprintf(stdout, "not logged in to %s with `ghmm auth login`\n", host)
+		return nil
+	}
+	if err = keychainDelete(host); err != nil {
+		return err
+	}
+	successf("logged out of %s, removing its token from the OS keychain", host)
+	return nil
+}
+
+// clientForToken returns a GitHub client authenticated with just the given token, regardless of what else
+// is configured.
+func clientForToken(tok string) (*github.Client, error) {
+	hc := &http.Client{Transport: tokenTransport(&statsTransport{base: http.DefaultTransport}, tok)}
+	base := resolveBaseURL()
+	if base == "" {
+		return github.NewClient(hc), nil
+	}
+	api, uploads, err := enterpriseURLs(base)
+	if err != nil {
+		return nil, err
+	}
+	return github.NewEnterpriseClient(api, uploads, hc)
+}
+
+// openBrowser opens the URL in the user's web browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
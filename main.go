@@ -1,26 +1,36 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/google/go-github/v19/github"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 )
 
 var (
 	// token will contain the GitHub access token if configured.
 	token string
+	// tokenFilePath is the location of the encrypted token file, if not the default.
+	tokenFilePath string
+	// baseURL is the API endpoint of a GitHub Enterprise Server instance, if not using github.com.
+	baseURL string
 	// yes is used to confirm mutating operations.
 	yes bool
+	// dryRun forces mutating operations to only report what they would do, even if yes is set.
+	dryRun bool
+	// updateExisting makes open edit milestones that already exist, rather than skipping them.
+	updateExisting bool
+	// noDueDate makes set and open leave milestones without a due date, rather than taking one as an argument.
+	noDueDate bool
 )
 
 func main() {
@@ -28,435 +38,2025 @@ func main() {
 	c := &cobra.Command{
 		Use:   os.Args[0],
 		Short: "A tool for managing GitHub milestones",
+		// Errors are printed below, once they've been scrubbed of secrets.
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkColorMode(); err != nil {
+				return err
+			}
+			if err := checkLogLevel(); err != nil {
+				return err
+			}
+			if err := loadConfig(); err != nil {
+				return err
+			}
+			return applyDefaults(cmd)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 	}
 	c.PersistentFlags().StringVarP(
-		&token, "token", "t", "", "GitHub access token (for private repos)")
+		&token, "token", "t", "", "GitHub access token (for private repos); "+
+			"defaults to $GITHUB_TOKEN, $GH_TOKEN, or the gh CLI's login")
+	c.PersistentFlags().Int64Var(
+		&app.ID, "app-id", 0, "Authenticate as an installation of the GitHub App with this ID, instead of with a token; "+
+			"defaults to $GHMM_APP_ID")
+	c.PersistentFlags().StringVar(
+		&app.Key, "app-key", "", "The GitHub App's private key: a PEM file, or a credential source (e.g. env:APP_KEY); "+
+			"defaults to $GHMM_APP_KEY")
+	c.PersistentFlags().Int64Var(
+		&app.Installation, "app-installation", 0, "The ID of the GitHub App installation to act as, "+
+			"if the App is installed more than once; defaults to $GHMM_APP_INSTALLATION")
+	c.PersistentFlags().StringVar(
+		&tokenFilePath, "token-file", "", "Encrypted token file to use when --token is not given "+
+			"(default ~/.config/ghmm/token.enc, if it exists)")
+	c.PersistentFlags().StringVar(
+		&baseURL, "base-url", "", "API URL of a GitHub Enterprise Server instance "+
+			"(e.g. https://github.example.com/api/v3/); defaults to $GHMM_BASE_URL")
+	c.PersistentFlags().IntVarP(
+		&concurrency, "concurrency", "j", 8, "Maximum number of repos to query concurrently")
+	c.PersistentFlags().StringVar(
+		&apiBackend, "api", apiBackend, "How to fetch milestones: rest, one request per repo, "+
+			"or graphql, many repos per request (fewer requests for large orgs)")
+	c.PersistentFlags().DurationVar(
+		&maxWait, "max-wait", maxWait, "Longest to pause for GitHub rate limits before giving up (0 never waits)")
+	c.PersistentFlags().StringVar(
+		&repoListOpts.Type, "repo-type", repoListOpts.Type,
+		"Which of an org's repos to list: all, public, private, forks, sources, or member")
+	c.PersistentFlags().StringVar(
+		&repoListOpts.Sort, "repo-sort", repoListOpts.Sort,
+		"The order to list an org's repos in: created, updated, pushed, or full_name")
+	c.PersistentFlags().IntVar(
+		&repoListOpts.PerPage, "repo-per-page", repoListOpts.PerPage, "How many repos to fetch per request (1-100)")
+	c.PersistentFlags().DurationVar(
+		&repoCacheTTL, "repo-cache-ttl", repoCacheTTL, "How long to reuse an org's cached repo list (0 never caches it)")
+	c.PersistentFlags().BoolVar(
+		&refreshRepos, "refresh", false, "Fetch org repo lists afresh, rather than using cached ones")
+	c.PersistentFlags().BoolVar(
+		&repoListOpts.IncludeArchived, "include-archived", false, "Include archived repos, which are skipped by default")
+	c.PersistentFlags().BoolVar(
+		&repoListOpts.IncludeForks, "include-forks", false, "Include forked repos, which are skipped by default")
+	c.PersistentFlags().StringVar(
+		&reposFile, "repos-file", "", "Operate on exactly the repos listed in this file, one owner/name per line, "+
+			"instead of an org's")
+	c.PersistentFlags().StringArrayVar(
+		&selector.Include, "include", nil, "Only operate on repos matching this glob pattern (e.g. 'acme/sdk-*')")
+	c.PersistentFlags().StringArrayVar(
+		&selector.Exclude, "exclude", nil, "Never operate on repos matching this glob pattern (e.g. 'acme/*-archive')")
+	c.PersistentFlags().StringSliceVar(
+		&selector.Languages, "language", nil, "Only operate on repos whose primary language is one of these")
+	c.PersistentFlags().StringArrayVar(
+		&selector.Properties, "property", nil, "Only operate on repos with this custom property value (name=value)")
+	c.PersistentFlags().StringVar(
+		&selector.Role, "role", "", "Only operate on repos where the token has at least this access: "+
+			"read, write, maintain, or admin")
+	c.PersistentFlags().IntVar(
+		&maxWidth, "max-width", 80, "Maximum width of any one column in tabular output (0 for unlimited)")
+	c.PersistentFlags().BoolVar(
+		&full, "full", false, "Never truncate column values, regardless of --max-width")
+	c.PersistentFlags().BoolVarP(
+		&verbose, "verbose", "v", false, "Log per-request detail, such as URLs and the rate limit remaining, to stderr")
+	c.PersistentFlags().BoolVarP(
+		&quiet, "quiet", "q", false, "Print only results and errors, suppressing warnings and progress notes")
+	c.PersistentFlags().BoolVar(
+		&verboseWarnings, "verbose-warnings", false, "Print every warning individually instead of aggregating them")
+	c.PersistentFlags().BoolVar(
+		&strict, "strict", false, "Exit with status 2 on any warning, not just on milestone drift")
+	c.PersistentFlags().BoolVar(
+		&explain, "explain", false, "After the command finishes, report the API calls made, time spent, and rate limit used")
+	c.PersistentFlags().StringVar(
+		&summaryFile, "summary", "", "Write a summary of any changes applied, including failures to retry, to this file")
+	c.PersistentFlags().DurationVar(
+		&notifyAfter, "notify", 0, "Show a desktop notification when a command running at least this long "+
+			"(e.g. 1m) finishes, or when it needs input")
+	c.PersistentFlags().StringVar(
+		&colorMode, "color", "auto", "Colorize output: always, never, or auto (only when writing to a terminal)")
+	c.PersistentFlags().StringVar(
+		&auditLog, "audit-log", "", "Append a record of every plan of milestone changes, and its results, to this file "+
+			"(or to the system log, if syslog); defaults to $GHMM_AUDIT_LOG")
+	c.PersistentFlags().StringVar(
+		&slackWebhook, "notify-slack", "", "Post applied milestone changes and drift warnings to this Slack webhook URL")
+	c.PersistentFlags().StringVar(
+		&timezone, "timezone", "", "Time zone (e.g. Europe/Berlin) whose calendar days due dates fall on "+
+			"(default: due at 7am UTC)")
+	c.PersistentFlags().BoolVarP(
+		&wide, "wide", "w", false, "Show all columns (state, description, URLs, issue and PR counts) in tabular output")
 
 	// # List all milestones open in the given organization (across all repos):
 	// $ ghmm list pulumi
+	var listOpts listOptions
 	listCmd := &cobra.Command{
 		Use:   "list",
-		Short: "List milestones in an org or repo",
+		Short: "List milestones in one or more orgs or repos",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
 			if len(args) < 1 {
 				return errors.New("missing repo or organization name")
+			} else if listOpts.CompleteOnly && listOpts.IncompleteOnly {
+				return errors.New("--complete-only and --incomplete-only are mutually exclusive")
+			} else if err := checkOutputFormat(listOpts.Output, "text", "json", "csv"); err != nil {
+				return err
+			} else if err = checkMilestoneState(listOpts.State); err != nil {
+				return err
+			} else if err = checkMilestoneSort(listOpts.Sort); err != nil {
+				return err
+			} else if err = listOpts.Match.compile(); err != nil {
+				return err
+			} else if listOpts.format, err = checkFormat(listOpts.Format, listOpts.Output); err != nil {
+				return err
 			}
-			return doListMilestones(args[0])
+			return doListMilestones(joinTargets(args), listOpts)
 		},
 	}
+	addMatchFlags(listCmd, &listOpts.Match)
+	listCmd.PersistentFlags().BoolVar(
+		&listOpts.CompleteOnly, "complete-only", false, "Only show milestones present in every repo")
+	listCmd.PersistentFlags().BoolVar(
+		&listOpts.IncompleteOnly, "incomplete-only", false, "Only show milestones missing from at least one repo")
+	listCmd.PersistentFlags().StringVar(
+		&listOpts.State, "state", "open", "Which milestones to show: open, closed, or all")
+	listCmd.PersistentFlags().StringVar(
+		&listOpts.Sort, "sort", "due", "Order to show milestones in: due (soonest first), title, or repos (most first)")
+	listCmd.PersistentFlags().StringVarP(
+		&listOpts.Output, "output", "o", "text", "Output format: text, json, or csv")
+	listCmd.PersistentFlags().StringVar(
+		&listOpts.Format, "format", "", "Print each milestone with this Go template "+
+			"(e.g. '{{.Title}} {{date .DueOn}} {{len .Repos}}') instead of a table")
+	listCmd.PersistentFlags().BoolVar(
+		&listOpts.Train, "train", false,
+		"Check each milestone's coverage against the release train listed in the org's milestone spec")
+	listCmd.PersistentFlags().StringVar(
+		&listOpts.SpecFile, "spec", "", "Read the release train from this milestone spec file (implies --train)")
+	completeArgs(listCmd, "org")
 	c.AddCommand(listCmd)
 
+	// # Verify from CI that every open milestone is in every repo with the same due date, exiting with 2 if not:
+	// $ ghmm check pulumi
+	var checkTrain bool
+	var checkSpecFile string
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check that an org's open milestones agree across its repos, for gating CI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			}
+			return doCheckMilestones(joinTargets(args), checkTrain, checkSpecFile)
+		},
+	}
+	checkCmd.PersistentFlags().BoolVar(
+		&checkTrain, "train", false,
+		"Check each milestone's coverage against the release train listed in the org's milestone spec")
+	checkCmd.PersistentFlags().StringVar(
+		&checkSpecFile, "spec", "", "Read the release train from this milestone spec file (implies --train)")
+	completeArgs(checkCmd, "org")
+	c.AddCommand(checkCmd)
+
+	// # Check for drift every 15 minutes, reporting new problems as they appear:
+	// $ ghmm watch pulumi --interval 15m
+	watchOpts := watchOptions{Interval: 15 * time.Minute, DueWithin: 72 * time.Hour}
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Periodically check an org's milestones for drift, and report new problems",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if watchOpts.Interval < time.Minute {
+				return errors.New("--interval must be at least 1m")
+			}
+			return doWatch(args[0], watchOpts)
+		},
+	}
+	watchCmd.PersistentFlags().DurationVar(
+		&watchOpts.Interval, "interval", watchOpts.Interval, "How long to wait between checks")
+	watchCmd.PersistentFlags().DurationVar(
+		&watchOpts.DueWithin, "due-within", watchOpts.DueWithin, "Report open milestones coming due within this long")
+	completeArgs(watchCmd, "org")
+	c.AddCommand(watchCmd)
+
+	// # Show which repos a command would operate on, and why any were excluded:
+	// $ ghmm repos pulumi
+	reposCmd := &cobra.Command{
+		Use:   "repos",
+		Short: "Show the repos an org or repo resolves to",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			}
+			return doListRepos(args[0])
+		},
+	}
+	completeArgs(reposCmd, "org")
+	c.AddCommand(reposCmd)
+
+	// # Show how close a milestone is to completion, in each repo and overall:
+	// $ ghmm status pulumi '0.20'
+	var statusOutput string
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show a milestone's open and closed issue counts and completion",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title (not its ID)")
+			} else if err := checkOutputFormat(statusOutput, "text", "csv"); err != nil {
+				return err
+			}
+			return doStatus(args[0], args[1], statusOutput)
+		},
+	}
+	statusCmd.PersistentFlags().StringVarP(
+		&statusOutput, "output", "o", "text", "Output format: text or csv")
+	addDuplicateFlags(statusCmd)
+	completeArgs(statusCmd, "org", "milestone")
+	c.AddCommand(statusCmd)
+
+	// # Show everything about one milestone in every repo: numbers, states, due dates, descriptions, and URLs:
+	// $ ghmm show pulumi '0.20'
+	var showOutput, showFormat string
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the full detail of a milestone in every repo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title (not its ID)")
+			} else if err := checkOutputFormat(showOutput, "text", "json"); err != nil {
+				return err
+			}
+			format, err := checkFormat(showFormat, showOutput)
+			if err != nil {
+				return err
+			}
+			return doShowMilestone(args[0], args[1], showOutput, format)
+		},
+	}
+	showCmd.PersistentFlags().StringVarP(
+		&showOutput, "output", "o", "text", "Output format: text or json")
+	showCmd.PersistentFlags().StringVar(
+		&showFormat, "format", "", "Print the milestone with this Go template (the same as list's) instead of a table")
+	completeArgs(showCmd, "org", "milestone")
+	c.AddCommand(showCmd)
+
+	// # Write a markdown report on a milestone's status, to paste into a tracking issue or wiki page:
+	// $ ghmm report --format markdown pulumi '0.20'
+	var reportFormat string
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Write a status report for a milestone",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title (not its ID)")
+			} else if err := checkOutputFormat(reportFormat, "markdown"); err != nil {
+				return err
+			}
+			return doReport(args[0], args[1])
+		},
+	}
+	reportCmd.PersistentFlags().StringVar(
+		&reportFormat, "format", "markdown", "Report format: markdown")
+	addDuplicateFlags(reportCmd)
+	completeArgs(reportCmd, "org", "milestone")
+	c.AddCommand(reportCmd)
+
+	// # Export the due dates of every open milestone as calendar events, to import into a team calendar:
+	// $ ghmm calendar pulumi --output ics > milestones.ics
+	var calendarOutput string
+	calendarCmd := &cobra.Command{
+		Use:   "calendar",
+		Short: "Export open milestones' due dates as calendar events",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if err := checkOutputFormat(calendarOutput, "ics"); err != nil {
+				return err
+			}
+			return doCalendar(args[0])
+		},
+	}
+	calendarCmd.PersistentFlags().StringVarP(
+		&calendarOutput, "output", "o", "ics", "Output format: ics")
+	completeArgs(calendarCmd, "org")
+	c.AddCommand(calendarCmd)
+
+	// # Show how a milestone's open and closed issue counts have changed over time, week by week:
+	// $ ghmm burndown pulumi '0.20' --every 1w
+	var burndownEvery string
+	burndownCmd := &cobra.Command{
+		Use:   "burndown",
+		Short: "Show a milestone's open and closed issue counts over time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title (not its ID)")
+			}
+			return doBurndown(args[0], args[1], burndownEvery)
+		},
+	}
+	burndownCmd.PersistentFlags().StringVar(
+		&burndownEvery, "every", "1d", "How often to sample the counts, in days or weeks (e.g. 1d or 1w)")
+	addDuplicateFlags(burndownCmd)
+	completeArgs(burndownCmd, "org", "milestone")
+	c.AddCommand(burndownCmd)
+
 	// # Change a milestone date (across all repos, based on the name):
 	// $ ghmm set pulumi '0.20' '1/13/2019'
+	var setMatch titleMatch
 	setCmd := &cobra.Command{
 		Use:   "set",
 		Short: "Set a milestone's date",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// With --match, milestones are chosen by pattern, so no title comes before the due date. With
+			// --no-due-date, no due date follows the title.
+			titles, dates := 1, 1
+			if setMatch.Given() {
+				titles = 0
+			}
+			if noDueDate {
+				dates = 0
+			}
+			args = withDefaultTarget(args, 1+titles+dates)
 			if len(args) < 1 {
 				return errors.New("missing repo or organization name")
-			} else if len(args) < 2 {
+			} else if len(args) < 1+titles {
 				return errors.New("missing milestone title whose date to set (not its ID)")
-			} else if len(args) < 3 {
-				return errors.New("missing milestone due date")
+			} else if len(args) < 1+titles+dates {
+				return errors.New("missing milestone due date (pass --no-due-date to clear it)")
+			} else if err := setMatch.compile(); err != nil {
+				return err
 			}
 
-			t, err := parseMilestoneDueOn(args[2])
-			if err != nil {
-				return err
+			// Any number of orgs and repos may come before the milestone title and due date.
+			n := len(args)
+			var due dueDate
+			if !noDueDate {
+				var err error
+				if due, err = parseDueDate(args[n-1]); err != nil {
+					return err
+				}
 			}
 
-			return doSetMilestone(args[0], args[1], t)
+			var milestone string
+			if titles > 0 {
+				milestone = args[n-dates-1]
+			}
+			return doSetMilestone(joinTargets(args[:n-dates-titles]), milestone, &setMatch, due)
 		},
 	}
-	setCmd.PersistentFlags().BoolVarP(
-		&yes, "yes", "y", false, "Actually perform the close operation instead of just dry-running it")
+	addMutationFlags(setCmd, "set")
+	addMatchFlags(setCmd, &setMatch)
+	addPlanFlags(setCmd)
+	addDuplicateFlags(setCmd)
+	setCmd.PersistentFlags().BoolVar(
+		&noDueDate, "no-due-date", false, "Clear the milestone's due date, rather than setting a new one")
+	completeArgs(setCmd, "org", "milestone")
 	c.AddCommand(setCmd)
 
+	// # Slip every open 0.2x milestone by a week, keeping their due dates in step:
+	// $ ghmm shift pulumi --by +1w --match '0\.2[0-9]'
+	var shiftBy string
+	var shiftMatch titleMatch
+	shiftCmd := &cobra.Command{
+		Use:   "shift",
+		Short: "Move the due dates of all (or all matching) open milestones by an offset",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if shiftBy == "" {
+				return errors.New("missing --by offset, like +1w")
+			} else if err := shiftMatch.compile(); err != nil {
+				return err
+			}
+			return doShiftMilestones(joinTargets(args), shiftBy, &shiftMatch)
+		},
+	}
+	addMutationFlags(shiftCmd, "shift")
+	addPlanFlags(shiftCmd)
+	addMatchFlags(shiftCmd, &shiftMatch)
+	shiftCmd.PersistentFlags().StringVar(
+		&shiftBy, "by", "", "How far to move due dates: a number of days, weeks, or months, like +1w or -3d")
+	completeArgs(shiftCmd, "org")
+	c.AddCommand(shiftCmd)
+
 	// # Close a milestone (across all repos, based on the name):
 	// $ ghmm close pulumi '0.20'
+	var closeOpts closeOptions
+	var closeMatch titleMatch
 	closeCmd := &cobra.Command{
 		Use:   "close",
 		Short: "Close a milestone by name",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
-				return errors.New("missing repo or organization name")
-			} else if len(args) < 2 {
-				return errors.New("missing milestone title to close (not its ID)")
-			}
-			return doCloseMilestone(args[0], args[1])
+			// With --match, milestones are chosen by pattern, so no title follows the orgs and repos.
+			titles := 1
+			if closeMatch.Given() {
+				titles = 0
+			}
+			args = withDefaultTarget(args, 1+titles)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 1+titles {
+				return errors.New("missing milestone title to close (not its ID)")
+			} else if err := closeMatch.compile(); err != nil {
+				return err
+			} else if closeMatch.Given() && (closeOpts.EnsureNext || closeOpts.MoveOpenTo != "") {
+				return errors.New("--ensure-next and --move-open-to follow on from a single milestone, " +
+					"so they cannot be used with --match")
+			} else if closeOpts.MoveOpenTo != "" && closeOpts.WhenComplete {
+				return errors.New("--move-open-to and --when-complete are mutually exclusive")
+			} else if closeOpts.MoveOpenTo != "" && (postPlan != "" || planOut != "") {
+				return errors.New("--move-open-to moves issues directly, so it cannot be used with --post-plan or --out")
+			}
+			// Any number of orgs and repos may come before the milestone title.
+			n := len(args)
+			var milestone string
+			if titles > 0 {
+				milestone = args[n-1]
+			}
+			if closeOpts.MoveOpenTo != "" && closeOpts.MoveOpenTo == milestone {
+				return errors.New("--move-open-to must name a different milestone than the one being closed")
+			}
+			return doCloseMilestone(joinTargets(args[:n-titles]), milestone, &closeMatch, closeOpts)
+		},
+	}
+	addMutationFlags(closeCmd, "close")
+	addMatchFlags(closeCmd, &closeMatch)
+	addPlanFlags(closeCmd)
+	addDuplicateFlags(closeCmd)
+	closeCmd.PersistentFlags().BoolVar(
+		&closeOpts.EnsureNext, "ensure-next", false,
+		"Open the next milestone in any repo that would otherwise be left with no open milestones")
+	closeCmd.PersistentFlags().BoolVar(
+		&closeOpts.WhenComplete, "when-complete", false,
+		"Close the milestone only if no repo has open issues or PRs in it; otherwise close nothing")
+	closeCmd.PersistentFlags().StringVar(
+		&closeOpts.MoveOpenTo, "move-open-to", "",
+		"Move the milestone's remaining open issues and PRs to this milestone, in every repo, before closing it")
+	completeArgs(closeCmd, "org", "milestone")
+	c.AddCommand(closeCmd)
+
+	// # Reopen a milestone that was closed too soon (across all repos, based on the name):
+	// $ ghmm reopen pulumi '0.20'
+	reopenCmd := &cobra.Command{
+		Use:   "reopen",
+		Short: "Reopen a closed milestone by name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to reopen (not its ID)")
+			}
+			return doReopenMilestone(args[0], args[1])
+		},
+	}
+	addMutationFlags(reopenCmd, "reopen")
+	addPlanFlags(reopenCmd)
+	completeArgs(reopenCmd, "org", "milestone")
+	c.AddCommand(reopenCmd)
+
+	// # Open a milestone (across all repos, based on the name):
+	// $ ghmm open pulumi '0.20' '1/13/2019'
+	openCmd := &cobra.Command{
+		Use:   "open",
+		Short: "Open a milestone with a given name and due date",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// With --no-due-date, no due date follows the title.
+			dates := 1
+			if noDueDate {
+				dates = 0
+			}
+			args = withDefaultTarget(args, 2+dates)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to open")
+			} else if len(args) < 2+dates {
+				return errors.New("missing milestone due date (pass --no-due-date to open it without one)")
+			}
+
+			// Any number of orgs and repos may come before the milestone title and due date.
+			n := len(args)
+			var due dueDate
+			if !noDueDate {
+				var err error
+				if due, err = parseDueDate(args[n-1]); err != nil {
+					return err
+				}
+			}
+
+			return doOpenMilestone(joinTargets(args[:n-dates-1]), args[n-dates-1], due.From(time.Time{}),
+				openDescription)
+		},
+	}
+	addMutationFlags(openCmd, "open")
+	addPlanFlags(openCmd)
+	addDuplicateFlags(openCmd)
+	openCmd.PersistentFlags().BoolVar(
+		&updateExisting, "update-existing", false,
+		"Converge repos that already have the milestone to the requested due date and state")
+	openCmd.PersistentFlags().StringVar(
+		&openDescription, "description", "", "The milestone's description")
+	openCmd.PersistentFlags().BoolVar(
+		&noDueDate, "no-due-date", false, "Open the milestone without a due date")
+	completeArgs(openCmd, "org")
+	c.AddCommand(openCmd)
+
+	// # Set milestone M42's description across all repos in the given organization:
+	// $ ghmm describe pulumi M42 'Spring release: the new CLI and provider SDKs'
+	describeCmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Set a milestone's description",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 3)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title whose description to set (not its ID)")
+			} else if len(args) < 3 {
+				return errors.New("missing milestone description (pass '' to clear it)")
+			}
+			return doDescribeMilestone(args[0], args[1], args[2])
+		},
+	}
+	addMutationFlags(describeCmd, "describe")
+	addPlanFlags(describeCmd)
+	addDuplicateFlags(describeCmd)
+	completeArgs(describeCmd, "org", "milestone")
+	c.AddCommand(describeCmd)
+
+	// # Create a milestone in just the repos that lack it, leaving existing ones alone; safe to run repeatedly:
+	// $ ghmm ensure pulumi '0.20' '1/13/2019'
+	var reconcileDue bool
+	ensureCmd := &cobra.Command{
+		Use:   "ensure",
+		Short: "Create a milestone only in the repos where it is missing",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 3)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to ensure")
+			} else if len(args) < 3 {
+				return errors.New("missing milestone due date")
+			}
+
+			due, err := parseDueDate(args[2])
+			if err != nil {
+				return err
+			}
+
+			return doEnsureMilestone(args[0], args[1], due.From(time.Time{}), reconcileDue)
+		},
+	}
+	addMutationFlags(ensureCmd, "ensure")
+	addPlanFlags(ensureCmd)
+	addDuplicateFlags(ensureCmd)
+	ensureCmd.PersistentFlags().BoolVar(
+		&reconcileDue, "reconcile-due", false, "Also change the due date of existing milestones to the given one")
+	completeArgs(ensureCmd, "org")
+	c.AddCommand(ensureCmd)
+
+	// # Open a patch release milestone (0.20.1) in just the repos that have 0.20, due in a week, moving the
+	// # open issues labeled "regression" into it:
+	// $ ghmm patch pulumi '0.20' --due +1w --label regression
+	patchOpts := patchOptions{Due: "+1w"}
+	patchCmd := &cobra.Command{
+		Use:   "patch",
+		Short: "Open a patch release milestone in the repos that have its parent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing title of the milestone to patch (not its ID)")
+			}
+			return doPatchMilestone(args[0], args[1], patchOpts)
+		},
+	}
+	addMutationFlags(patchCmd, "patch")
+	addPlanFlags(patchCmd)
+	addDuplicateFlags(patchCmd)
+	patchCmd.PersistentFlags().StringVar(
+		&patchOpts.Due, "due", patchOpts.Due, "The patch milestone's due date: 1/2/2006, or relative to today, like +1w")
+	patchCmd.PersistentFlags().StringSliceVar(
+		&patchOpts.Label, "label", nil, "Move open issues with any of these labels from the parent milestone into the patch")
+	completeArgs(patchCmd, "org", "milestone")
+	c.AddCommand(patchCmd)
+
+	// # Punt the open issues and PRs labeled "stretch" in milestone 0.20 to 0.21, across all repos:
+	// $ ghmm move-issues pulumi '0.20' '0.21' --label stretch
+	moveOpts := moveIssuesOptions{State: "open"}
+	moveCmd := &cobra.Command{
+		Use:   "move-issues",
+		Short: "Move issues and PRs from one milestone to another across repos",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 3)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing title of the milestone to move issues from (not its ID)")
+			} else if len(args) < 3 {
+				return errors.New("missing title of the milestone to move issues to (not its ID)")
+			} else if args[1] == args[2] {
+				return errors.New("the milestones to move issues from and to must differ")
+			} else if err := checkMilestoneState(moveOpts.State); err != nil {
+				return err
+			}
+			return doMoveIssues(args[0], args[1], args[2], moveOpts)
+		},
+	}
+	addMutationFlags(moveCmd, "move")
+	addDuplicateFlags(moveCmd)
+	moveCmd.PersistentFlags().StringSliceVar(
+		&moveOpts.Label, "label", nil, "Only move issues and PRs with any of these labels")
+	moveCmd.PersistentFlags().StringVar(
+		&moveOpts.State, "state", moveOpts.State, "Which issues and PRs to move: open, closed, or all")
+	completeArgs(moveCmd, "org", "milestone", "milestone")
+	c.AddCommand(moveCmd)
+
+	// # Put every open P0 issue without a milestone, in any of the org's repos, into milestone 0.21:
+	// $ ghmm assign pulumi '0.21' --query 'is:open label:P0 no:milestone'
+	var assignQuery string
+	assignCmd := &cobra.Command{
+		Use:   "assign",
+		Short: "Assign the issues matching a search query across repos to a milestone",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing title of the milestone to assign issues to (not its ID)")
+			} else if assignQuery == "" {
+				return errors.New("missing --query to find the issues to assign")
+			}
+			return doAssignIssues(args[0], args[1], assignQuery)
+		},
+	}
+	addMutationFlags(assignCmd, "assign")
+	addDuplicateFlags(assignCmd)
+	assignCmd.PersistentFlags().StringVar(
+		&assignQuery, "query", "", "Assign the issues and PRs matching this GitHub search query "+
+			"(e.g. 'is:open label:P0 no:milestone'), which is limited to the target's repos")
+	completeArgs(assignCmd, "org", "milestone")
+	c.AddCommand(assignCmd)
+
+	// # Make a milestone's due date and state agree across all repos, using the values most repos have:
+	// $ ghmm sync pulumi '0.20'
+	var syncOpts syncOptions
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Reconcile a milestone's due date and state across repos",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to sync (not its ID)")
+			}
+			return doSyncMilestone(args[0], args[1], syncOpts)
+		},
+	}
+	addMutationFlags(syncCmd, "sync")
+	addPlanFlags(syncCmd)
+	addDuplicateFlags(syncCmd)
+	syncCmd.PersistentFlags().StringVar(
+		&syncOpts.DueOn, "due-on", "", "The due date every repo should have (default: the most common one)")
+	syncCmd.PersistentFlags().StringVar(
+		&syncOpts.State, "state", "", "The state every repo should have, open or closed (default: the most common one)")
+	completeArgs(syncCmd, "org", "milestone")
+	c.AddCommand(syncCmd)
+
+	// # Push milestone M42 back a week, rename it, and describe it, all in one pass over the org's repos:
+	// $ ghmm edit pulumi M42 --due +1w --title 'Spring Release' --description 'The new CLI'
+	var editOpts editOptions
+	var editDescription string
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Change a milestone's due date, title, description, and state at once",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to edit (not its ID)")
+			}
+			if cmd.Flags().Changed("description") {
+				editOpts.Description = &editDescription
+			}
+			if err := editOpts.check(); err != nil {
+				return err
+			}
+			return doEditMilestone(args[0], args[1], editOpts)
+		},
+	}
+	addMutationFlags(editCmd, "edit")
+	addPlanFlags(editCmd)
+	addDuplicateFlags(editCmd)
+	editCmd.PersistentFlags().StringVar(
+		&editOpts.Due, "due", "", "The new due date (e.g. 8/1/2019), or one relative to the current one (e.g. +1w)")
+	editCmd.PersistentFlags().StringVar(
+		&editOpts.Title, "title", "", "The new title")
+	editCmd.PersistentFlags().StringVar(
+		&editDescription, "description", "", "The new description ('' to clear it)")
+	editCmd.PersistentFlags().StringVar(
+		&editOpts.State, "state", "", "The new state: open or closed")
+	completeArgs(editCmd, "org", "milestone")
+	c.AddCommand(editCmd)
+
+	// # Bootstrap a new repo with the open milestones of an existing one:
+	// $ ghmm clone pulumi/pulumi pulumi/pulumi-new
+	cloneCmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Copy a repo's open milestones into other repos",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing source repo to copy milestones from")
+			} else if len(args) < 2 {
+				return errors.New("missing target repo (or organization) to copy milestones into")
+			}
+			return doCloneMilestones(args[0], joinTargets(args[1:]))
+		},
+	}
+	addMutationFlags(cloneCmd, "clone")
+	addPlanFlags(cloneCmd)
+	c.AddCommand(cloneCmd)
+
+	// # Add every milestone open elsewhere in the org to a newly created repo:
+	// $ ghmm onboard pulumi pulumi-new
+	onboardCmd := &cobra.Command{
+		Use:   "onboard",
+		Short: "Create the org's open milestones in a newly created repo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing new repo to onboard")
+			}
+			return doOnboardRepo(args[0], args[1])
+		},
+	}
+	addMutationFlags(onboardCmd, "onboard")
+	addPlanFlags(onboardCmd)
+	completeArgs(onboardCmd, "org")
+	c.AddCommand(onboardCmd)
+
+	// # Rename a milestone (across all repos, based on the name):
+	// $ ghmm rename pulumi '0.20' '1.0'
+	renameCmd := &cobra.Command{
+		Use:   "rename",
+		Short: "Rename a milestone",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 3)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to rename (not its ID)")
+			} else if len(args) < 3 {
+				return errors.New("missing new milestone title")
+			}
+			return doRenameMilestone(args[0], args[1], args[2])
+		},
+	}
+	addMutationFlags(renameCmd, "rename")
+	addPlanFlags(renameCmd)
+	addDuplicateFlags(renameCmd)
+	completeArgs(renameCmd, "org", "milestone")
+	c.AddCommand(renameCmd)
+
+	// # Delete a milestone (across all repos, based on the name), removing it from any issues first:
+	// $ ghmm delete pulumi '0.20' --unassign
+	var deleteUnassign bool
+	deleteCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a milestone by name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing milestone title to delete (not its ID)")
+			}
+			return doDeleteMilestone(args[0], args[1], deleteUnassign)
+		},
+	}
+	addMutationFlags(deleteCmd, "delete")
+	addPlanFlags(deleteCmd)
+	addDuplicateFlags(deleteCmd)
+	deleteCmd.PersistentFlags().BoolVar(
+		&deleteUnassign, "unassign", false,
+		"Remove the milestone from any issues and PRs still in it, and delete it, rather than skipping such repos")
+	completeArgs(deleteCmd, "org", "milestone")
+	c.AddCommand(deleteCmd)
+
+	// # Move a milestone's issues from one repo to another, keeping them in the same milestone:
+	// $ ghmm transfer-issues pulumi --from pulumi-old --to pulumi --milestone '0.21'
+	var transferFrom, transferTo, transferMilestone, transferState string
+	transferCmd := &cobra.Command{
+		Use:   "transfer-issues",
+		Short: "Transfer a milestone's issues between repos, preserving their milestone",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing organization name")
+			} else if transferFrom == "" {
+				return errors.New("missing --from repo")
+			} else if transferTo == "" {
+				return errors.New("missing --to repo")
+			} else if transferMilestone == "" {
+				return errors.New("missing --milestone title whose issues to transfer")
+			} else if err := checkMilestoneState(transferState); err != nil {
+				return err
+			}
+			return doTransferIssues(args[0], transferFrom, transferTo, transferMilestone, transferState)
+		},
+	}
+	transferCmd.PersistentFlags().StringVar(
+		&transferFrom, "from", "", "Repo to transfer issues from")
+	transferCmd.PersistentFlags().StringVar(
+		&transferTo, "to", "", "Repo to transfer issues to")
+	transferCmd.PersistentFlags().StringVar(
+		&transferMilestone, "milestone", "", "Title of the milestone whose issues to transfer")
+	transferCmd.PersistentFlags().StringVar(
+		&transferState, "state", "open", "Which issues to transfer: open, closed, or all")
+	addMutationFlags(transferCmd, "transfer")
+	c.AddCommand(transferCmd)
+
+	// # Find issues and PRs that landed in a milestone after it was closed (across all repos):
+	// $ ghmm late pulumi '0.20'
+	lateCmd := &cobra.Command{
+		Use:   "late",
+		Short: "Report issues and PRs closed after their milestone was closed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			}
+			var milestone string
+			if len(args) > 1 {
+				milestone = args[1]
+			}
+			return doLateLandings(args[0], milestone)
+		},
+	}
+	completeArgs(lateCmd, "org")
+	c.AddCommand(lateCmd)
+
+	// # Show the org's milestone spec (read from milestones.yaml in its .github repo, unless --file is given):
+	// $ ghmm spec pulumi
+	var specFile string
+	specCmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Show the declarative milestone spec for an org",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing organization name")
+			}
+			return doShowSpec(args[0], specFile)
+		},
+	}
+	specCmd.PersistentFlags().StringVarP(
+		&specFile, "file", "f", "", "Read the spec from this file instead of the org's .github repo")
+	completeArgs(specCmd, "org")
+	c.AddCommand(specCmd)
+
+	// # Assign issues without a milestone according to the configured rules (a dry-run audit by default):
+	// $ ghmm rules pulumi
+	rulesCmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Assign issues without a milestone to milestones using the configured rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			}
+			return doApplyRules(args[0])
+		},
+	}
+	addMutationFlags(rulesCmd, "assign")
+	c.AddCommand(rulesCmd)
+
+	// # Run a server that reacts to GitHub webhooks, e.g. to assign new issues using the configured rules:
+	// $ ghmm serve --addr :8080 --yes
+	var serveAddr string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run a webhook server that applies milestone automation as events arrive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doServe(serveAddr)
+		},
+	}
+	serveCmd.PersistentFlags().StringVar(
+		&serveAddr, "addr", ":8080", "Address to listen for webhook deliveries on")
+	serveCmd.PersistentFlags().BoolVar(
+		&reconcileMilestones, "reconcile", false,
+		"Propagate milestones created, edited, closed, or reopened in one repo to every other repo in the org")
+	serveCmd.PersistentFlags().StringVar(
+		&freezeSpecFile, "spec", "", "Read milestone freezes from this spec file instead of each org's .github repo")
+	addMutationFlags(serveCmd, "webhook")
+	c.AddCommand(serveCmd)
+
+	// # Export an org's current milestones as a milestone spec, to converge on later with apply:
+	// $ ghmm export pulumi > milestones.yaml
+	// # Export every milestone (and optionally issue) as newline-delimited JSON, for loading into a warehouse:
+	// $ ghmm export pulumi --output ndjson --issues > milestones.ndjson
+	var exportOutput, exportState string
+	var exportIssuesFlag bool
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a snapshot of an org's milestones, as a milestone spec or for analytics",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 1)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if err := checkOutputFormat(exportOutput, "yaml", "ndjson"); err != nil {
+				return err
+			} else if err := checkMilestoneState(exportState); err != nil {
+				return err
+			}
+			if exportOutput == "yaml" {
+				if exportIssuesFlag {
+					return errors.New("--issues can only be used with --output ndjson")
+				}
+				return doExportSpec(args[0], exportState)
+			}
+			return doExport(args[0], exportState, exportIssuesFlag)
+		},
+	}
+	exportCmd.PersistentFlags().StringVarP(
+		&exportOutput, "output", "o", "yaml", "Output format: yaml (a milestone spec) or ndjson")
+	exportCmd.PersistentFlags().StringVar(
+		&exportState, "state", "all", "Which milestones to export: open, closed, or all")
+	exportCmd.PersistentFlags().BoolVar(
+		&exportIssuesFlag, "issues", false, "Also export a record for every issue and PR in each milestone")
+	c.AddCommand(exportCmd)
+
+	// # Post a dry-run plan for a teammate to approve with a :+1: reaction, then apply it once they have:
+	// $ ghmm close pulumi 0.19 --post-plan pulumi/ops#42
+	// $ ghmm apply-plan --from-comment https://github.com/pulumi/ops/issues/42#issuecomment-123456 --yes
+	var fromComment string
+	applyPlanCmd := &cobra.Command{
+		Use:   "apply-plan",
+		Short: "Apply a plan that was posted as an issue comment and approved",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromComment == "" {
+				return errors.New("missing --from-comment URL of the plan to apply")
+			}
+			return doApplyPlan(fromComment)
+		},
+	}
+	applyPlanCmd.PersistentFlags().StringVar(
+		&fromComment, "from-comment", "", "The URL of the issue comment containing the plan")
+	addMutationFlags(applyPlanCmd, "apply-plan")
+	c.AddCommand(applyPlanCmd)
+
+	// # Save a command's plan to a file, to review and approve out-of-band, and later execute exactly that plan:
+	// $ ghmm plan set pulumi '0.20' 8/1/2019 --out plan.json
+	// $ ghmm apply plan.json --yes
+	c.AddCommand(&cobra.Command{
+		Use:                "plan",
+		Short:              "Save the plan of a command that changes milestones to a file, with --out",
+		DisableFlagParsing: true,
+		// The planned command prints its own usage, if it is misused.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPlan(c, args)
+		},
+	})
+	// # Converge an org's repos on a milestone spec, creating, editing, and closing milestones as needed:
+	// $ ghmm apply pulumi milestones.yaml --yes
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a plan saved with --out, or converge an org's milestones on a milestone spec",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doApply(args)
+		},
+	}
+	applyCmd.PersistentFlags().BoolVar(
+		&prune, "prune", false, "When applying a milestone spec, close open milestones that the spec doesn't list")
+	addMutationFlags(applyCmd, "apply")
+	addPlanFlags(applyCmd)
+	c.AddCommand(applyCmd)
+
+	// # Serve JSON-RPC 2.0 requests on stdin, one per line, for other programs to drive ghmm:
+	// $ echo '{"jsonrpc":"2.0","id":1,"method":"list","params":{"target":"pulumi"}}' | ghmm rpc
+	rpcCmd := &cobra.Command{
+		Use:   "rpc",
+		Short: "Serve JSON-RPC requests on stdin (list, check, set, close, sync)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doRPC()
+		},
+	}
+	rpcCmd.PersistentFlags().BoolVar(
+		&dryRun, "dry-run", false, "Only report what would change, even if a request asks to apply its changes")
+	c.AddCommand(rpcCmd)
+
+	// # Save snapshots of an org's milestones, and later compare them to see what changed in between:
+	// $ ghmm snapshot save pulumi monday
+	// $ ghmm snapshot diff monday friday
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save and compare snapshots of milestones",
+	}
+	snapshotSaveCmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save a named snapshot of an org's or repo's milestones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = withDefaultTarget(args, 2)
+			if len(args) < 1 {
+				return errors.New("missing repo or organization name")
+			} else if len(args) < 2 {
+				return errors.New("missing snapshot name")
+			}
+			return doSaveSnapshot(args[0], args[1])
+		},
+	}
+	snapshotDiffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report what changed between two saved snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("missing names of the two snapshots to compare")
+			}
+			return doDiffSnapshots(args[0], args[1])
+		},
+	}
+	snapshotListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doListSnapshots()
+		},
+	}
+	snapshotCmd.AddCommand(snapshotSaveCmd, snapshotDiffCmd, snapshotListCmd)
+	c.AddCommand(snapshotCmd)
+
+	// # Retry just the changes that failed in the last run that applied changes:
+	// $ ghmm retry --last --yes
+	var retryFrom string
+	var retryLast bool
+	retryCmd := &cobra.Command{
+		Use:   "retry",
+		Short: "Retry the changes that failed in a previous run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if retryFrom == "" && !retryLast {
+				return errors.New("missing --from-summary file or --last")
+			} else if retryFrom != "" && retryLast {
+				return errors.New("--from-summary and --last are mutually exclusive")
+			}
+			return doRetry(retryFrom)
+		},
+	}
+	retryCmd.PersistentFlags().StringVar(
+		&retryFrom, "from-summary", "", "Retry the failed changes recorded in this run summary file (see --summary)")
+	retryCmd.PersistentFlags().BoolVar(
+		&retryLast, "last", false, "Retry the failed changes from the last run that applied changes")
+	addMutationFlags(retryCmd, "retry")
+	c.AddCommand(retryCmd)
+
+	// # Undo the changes made by the last run that applied any (see the journal's entries with --list):
+	// $ ghmm undo --last --yes
+	var undoID int
+	var undoLast, undoList bool
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Reverse the changes a previous run made, as recorded in the undo journal",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if undoList {
+				return doListJournal()
+			} else if undoID == 0 && !undoLast {
+				return errors.New("missing --id or --last (pass --list to see the journal's entries)")
+			} else if undoID != 0 && undoLast {
+				return errors.New("--id and --last are mutually exclusive")
+			}
+			return doUndo(undoID)
+		},
+	}
+	undoCmd.PersistentFlags().IntVar(
+		&undoID, "id", 0, "Undo the changes recorded in the journal entry with this ID")
+	undoCmd.PersistentFlags().BoolVar(
+		&undoLast, "last", false, "Undo the changes from the last run that applied changes")
+	undoCmd.PersistentFlags().BoolVar(
+		&undoList, "list", false, "List the journal's entries, newest first, instead of undoing any")
+	addMutationFlags(undoCmd, "undo")
+	c.AddCommand(undoCmd)
+
+	// # Log in, storing a token in the OS keychain so that it never needs to be passed on the command line:
+	// $ ghmm auth login --web
+	var loginWeb bool
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the token stored in the OS keychain",
+	}
+	authLoginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Store a token (read from the terminal or stdin) in the OS keychain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doAuthLogin(loginWeb)
+		},
+	}
+	authLoginCmd.PersistentFlags().BoolVar(
+		&loginWeb, "web", false, "Open the page for creating a token in the browser first")
+	authStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show how requests are authenticated, and as whom",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doAuthStatus()
+		},
+	}
+	authLogoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the token stored in the OS keychain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doAuthLogout()
+		},
+	}
+	authCmd.AddCommand(authLoginCmd, authStatusCmd, authLogoutCmd)
+	c.AddCommand(authCmd)
+
+	// # Store a token in an encrypted file, unlocked by a passphrase (or $GHMM_TOKEN_KEY), so that it
+	// # never needs to be passed on the command line or live in a plaintext file:
+	// $ ghmm token save
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the encrypted token file",
+	}
+	tokenSaveCmd := &cobra.Command{
+		Use:   "save",
+		Short: "Encrypt a token (read from the terminal or stdin) and store it in the token file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doSaveToken()
 		},
 	}
-	closeCmd.PersistentFlags().BoolVarP(
-		&yes, "yes", "y", false, "Actually perform the close operation instead of just dry-running it")
-	c.AddCommand(closeCmd)
+	tokenCmd.AddCommand(tokenSaveCmd)
+	c.AddCommand(tokenCmd)
 
-	// # Open a milestone (across all repos, based on the name):
-	// $ ghmm open pulumi '0.20' '1/13/2019'
-	openCmd := &cobra.Command{
-		Use:   "open",
-		Short: "Open a milestone with a given name and due date",
+	// # Enable shell completion, including of orgs and milestone titles, in bash (or zsh, or fish):
+	// $ source <(ghmm completion bash)
+	completionCmd := &cobra.Command{
+		Use:   "completion",
+		Short: "Print the shell completion script for bash, zsh, or fish",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("missing repo or organization name")
-			} else if len(args) < 2 {
-				return errors.New("missing milestone title to open")
-			} else if len(args) < 3 {
-				return errors.New("missing milestone due date")
-			}
-
-			t, err := parseMilestoneDueOn(args[2])
-			if err != nil {
-				return err
+				return errors.New("missing shell: bash, zsh, or fish")
 			}
-
-			return doOpenMilestone(args[0], args[1], t)
+			return doCompletion(args[0])
 		},
 	}
-	openCmd.PersistentFlags().BoolVarP(
-		&yes, "yes", "y", false, "Actually perform the open operation instead of just dry-running it")
-	c.AddCommand(openCmd)
+	c.AddCommand(completionCmd)
+	c.AddCommand(&cobra.Command{
+		Use:                "__complete",
+		Hidden:             true,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doComplete(c, args)
+		},
+	})
 
 	// Now run the command.
-	if err := c.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	cmd, err := c.ExecuteC()
+	warnings := flushWarnings()
+	if checkingDrift {
+		notifySlack("found milestone drift", warnings)
+	}
+	notifyDone(cmd.Name(), err)
+	if explain {
+		stats.print()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		os.Exit(exitError)
 	}
+	os.Exit(exitCode(warnings))
 }
 
-func ghClient() *github.Client {
-	var tc *http.Client
-	if token != "" {
-		tc = oauth2.NewClient(
-			context.Background(),
-			oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
-		)
+// The exit codes, besides 0 for success, so that CI can gate on milestone consistency.
+const (
+	exitError    = 1 // the command failed.
+	exitWarnings = 2 // the command succeeded, but found drift (or, with --strict, raised any warning).
+)
+
+// addMutationFlags registers the --yes and --dry-run flags shared by every command that mutates milestones.
+func addMutationFlags(cmd *cobra.Command, op string) {
+	cmd.PersistentFlags().BoolVarP(
+		&yes, "yes", "y", false, fmt.Sprintf("Actually perform the %s operation instead of just dry-running it", op))
+	cmd.PersistentFlags().BoolVar(
+		&dryRun, "dry-run", false, "Only report what would change, even if --yes is also passed")
+}
+
+// addPlanFlags registers the flags that save the dry-run plan of a command that computes a plan of milestone
+// changes, to be applied later: --post-plan and --out.
+func addPlanFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&postPlan, "post-plan", "", "Post the dry-run plan as a comment on an issue (owner/repo#number) for approval")
+	cmd.PersistentFlags().StringVar(
+		&planOut, "out", "", "Save the dry-run plan to this file, for `ghmm apply` to execute later")
+}
+
+// applying returns true if mutating commands should actually make their changes, rather than dry-running
+// them. This is the case only if --yes was passed, and --dry-run was not; --dry-run always wins.
+func applying() bool {
+	return yes && !dryRun
+}
+
+func ghClient() (*github.Client, error) {
+	api, uploads := "https://api.github.com/", ""
+	if base := resolveBaseURL(); base != "" {
+		var err error
+		if api, uploads, err = enterpriseURLs(base); err != nil {
+			return nil, err
+		}
+	}
+
+	var rt http.RoundTripper = &throttleTransport{base: &statsTransport{base: http.DefaultTransport}}
+	// Responses are cached beneath authentication, so that each is cached along with the token it was fetched with.
+	rt = &etagTransport{base: rt}
+	var scope string
+	if app.configured() {
+		// Authenticate as the App installation by default, still honoring any per-owner tokens.
+		def, err := appTransport(rt, api)
+		if err != nil {
+			return nil, err
+		}
+		ct := newCredentialTransport(rt, "", cfg.Tokens)
+		ct.def = def
+		rt, scope = ct, fmt.Sprintf("app:%d:%d", app.ID, app.Installation)
+		debugf("authenticating to %s as GitHub App %d", api, app.ID)
+	} else {
+		tok, err := resolveToken()
+		if err != nil {
+			return nil, err
+		}
+		if tok != "" || len(cfg.Tokens) > 0 {
+			rt = newCredentialTransport(rt, tok, cfg.Tokens)
+		}
+		scope = tok
+		if tok != "" {
+			debugf("authenticating to %s with the token from %s", api, tokenOrigin)
+		} else {
+			debugf("making unauthenticated requests to %s", api)
+		}
 	}
-	return github.NewClient(tc)
+	hc := &http.Client{Transport: rt}
+
+	if uploads == "" {
+		setRepoCacheScope("", scope)
+		return github.NewClient(hc), nil
+	}
+	setRepoCacheScope(api, scope)
+	return github.NewEnterpriseClient(api, uploads, hc)
 }
 
-type repo string
+// resolveBaseURL determines the GitHub Enterprise Server API URL to use, if any: --base-url if it was given,
+// otherwise $GHMM_BASE_URL, and otherwise the config file's setting.
+func resolveBaseURL() string {
+	if baseURL != "" {
+		return baseURL
+	} else if env := os.Getenv("GHMM_BASE_URL"); env != "" {
+		return env
+	}
+	return cfg.BaseURL
+}
 
-func (r repo) Owner() string {
-	s := string(r)
-	return s[:strings.Index(s, "/")]
+// enterpriseURLs computes the REST API and upload URLs for a GitHub Enterprise Server instance. The base may
+// be given either as the API URL itself, or just as the server's URL, in which case /api/v3/ is implied.
+func enterpriseURLs(base string) (string, string, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", "", errors.Errorf("malformed base URL %q; expected e.g. https://github.example.com/api/v3/", base)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/api/v3/"
+	} else if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	uploads := *u
+	uploads.Path = strings.TrimSuffix(u.Path, "v3/") + "uploads/"
+	return u.String(), uploads.String(), nil
 }
 
-func (r repo) Repo() string {
-	s := string(r)
-	return s[strings.Index(s, "/")+1:]
+// checkMilestoneState validates a milestone state filter.
+func checkMilestoneState(state string) error {
+	switch state {
+	case "open", "closed", "all":
+		return nil
+	default:
+		return errors.Errorf("unrecognized milestone state %q; expected open, closed, or all", state)
+	}
 }
 
-func getRepos(gh *github.Client, orgOrRepo string) ([]repo, error) {
-	var repos []repo
-	if ix := strings.Index(orgOrRepo, "/"); ix != -1 {
-		// If just a singular repo, query it directly.
-		repos = append(repos, repo(orgOrRepo))
-	} else {
-		// If an org, use all of the repos in that org. Note that we need to loop to get all pages.
-		opts := &github.RepositoryListByOrgOptions{}
-		for {
-			rs, resp, err := gh.Repositories.ListByOrg(context.Background(), orgOrRepo, opts)
-			if err != nil {
-				return nil, errors.Wrapf(err, "listing repos by org %s", orgOrRepo)
-			}
-			for _, r := range rs {
-				if r.Archived != nil && *r.Archived {
-					continue
+// checkMilestoneSort validates a milestone sort order.
+func checkMilestoneSort(by string) error {
+	switch by {
+	case "due", "title", "repos":
+		return nil
+	default:
+		return errors.Errorf("unrecognized sort order %q; expected due, title, or repos", by)
+	}
+}
+
+// sortMilestones orders aggregated milestones by due date (soonest first, with those lacking one last), by
+// title, or by how many repos have them (most first). Ties are broken by title, so that the order is stable
+// from one run to the next.
+func sortMilestones(milestones []*milestone, by string) {
+	sort.Slice(milestones, func(i, j int) bool {
+		a, b := milestones[i], milestones[j]
+		switch by {
+		case "due":
+			if !a.DueOn.Equal(b.DueOn) {
+				if a.DueOn.IsZero() || b.DueOn.IsZero() {
+					return b.DueOn.IsZero()
 				}
-				repos = append(repos, repo(r.GetFullName()))
+				return a.DueOn.Before(b.DueOn)
 			}
-			if resp.NextPage == 0 {
-				break
+		case "repos":
+			if len(a.Repos) != len(b.Repos) {
+				return len(a.Repos) > len(b.Repos)
 			}
-			opts.Page = resp.NextPage
 		}
-	}
-	return repos, nil
+		return a.Title < b.Title
+	})
 }
 
-type milestone struct {
-	State string
-	DueOn time.Time
-	Repos map[repo]bool
+// listOptions controls which milestones list shows, and how.
+type listOptions struct {
+	CompleteOnly   bool   // only show milestones present in every repo (or every release train repo).
+	IncompleteOnly bool   // only show milestones missing from at least one repo (or release train repo).
+	State          string // which milestones to show: open, closed, or all.
+	Sort           string // the order to show them in: due, title, or repos.
+	Output         string // the output format: "text", "json", or "csv".
+	Train          bool   // judge coverage against the release train in the org's milestone spec.
+	SpecFile       string // read the release train from this milestone spec file instead.
+	Match          titleMatch
+	Format         string // a Go template to print each milestone with, instead of a table.
+
+	format *template.Template
 }
 
-func (m *milestone) RepoNames() []repo {
-	var repos []repo
-	for r := range m.Repos {
-		repos = append(repos, r)
-	}
-	return repos
+// milestoneJSON is the structure of each milestone in list's JSON output.
+type milestoneJSON struct {
+	Title        string              `json:"title"`
+	State        string              `json:"state"`
+	DueOn        *time.Time          `json:"dueOn,omitempty"`
+	Description  string              `json:"description,omitempty"`
+	OpenIssues   int                 `json:"openIssues"`
+	ClosedIssues int                 `json:"closedIssues"`
+	Complete     *int                `json:"complete,omitempty"` // the percentage closed, if there are any.
+	Work         *workCountsJSON     `json:"work,omitempty"`
+	Repos        []repoMilestoneJSON `json:"repos"`
 }
 
-func parseMilestoneDueOn(d string) (time.Time, error) {
-	t, err := time.Parse("1/2/2006", d)
-	if err != nil {
-		return time.Time{}, errors.Wrap(err, "malformed date; please use 1/2/2006 format")
-	}
-	t = t.Add(time.Hour * 7) // All GitHub milestones at 7am.
-	return t, nil
+// workCountsJSON splits a milestone's work into issues and pull requests. (The top-level openIssues and
+// closedIssues counts include both, as GitHub reports them.)
+type workCountsJSON struct {
+	OpenIssues   int `json:"openIssues"`
+	ClosedIssues int `json:"closedIssues"`
+	OpenPRs      int `json:"openPRs"`
+	ClosedPRs    int `json:"closedPRs"`
 }
 
-func doListMilestones(orgOrRepo string) error {
-	gh := ghClient()
+// repoMilestoneJSON records whether a milestone is present in a single repo, and if so, its number.
+type repoMilestoneJSON struct {
+	Repo    string `json:"repo"`
+	Present bool   `json:"present"`
+	Number  int    `json:"number,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
 
-	// First get the list of repos under consideration.
-	repos, err := getRepos(gh, orgOrRepo)
+func doListMilestones(orgOrRepo string, opts listOptions) error {
+	gh, err := ghClient()
 	if err != nil {
 		return err
 	}
 
-	// Now, for each of them, loop over and query the milestones.
-	milestones := make(map[string]*milestone)
-	for _, r := range repos {
-		ms, _, err := gh.Issues.ListMilestones(context.Background(), r.Owner(), r.Repo(), nil)
-		if err != nil {
-			return errors.Wrapf(err, "listing milestones for repo %s", r)
-		}
-
-		for _, m := range ms {
-			t, s, d := m.GetTitle(), m.GetState(), m.GetDueOn()
-			exist, ok := milestones[t]
-			if ok {
-				if exist.State != m.GetState() {
-					fmt.Fprintf(os.Stderr,
-						"warning: milestone %s in repo %s has a different state "+
-							"(has %s, expect %s) than other repos (%v)\n",
-						t, r, s, exist.State, exist.RepoNames())
-				} else if exist.DueOn != d {
-					fmt.Fprintf(os.Stderr,
-						"warning: milestone %s in repo %s has a different due date "+
-							"(has %v, expect) %v than other repos (%v)\n",
-						t, r, d, exist.DueOn, exist.RepoNames())
-				}
-				exist.Repos[r] = true
-			} else {
-				milestones[t] = &milestone{
-					State: s,
-					DueOn: d,
-					Repos: map[repo]bool{r: true},
-				}
-			}
+	// Snapshot the milestones across all repos under consideration, grouping them by title.
+	snap, err := takeSnapshot(gh, orgOrRepo, opts.State)
+	if err != nil {
+		return err
+	}
+	if opts.Train || opts.SpecFile != "" {
+		if err = useSpecTrain(gh, snap, opts.SpecFile); err != nil {
+			return err
+		}
+	}
+	// The split between issues and PRs is only shown in the wide and JSON views, or by templates that ask for
+	// it, so don't pay for it otherwise.
+	if wide || opts.Output == "json" || strings.Contains(opts.Format, ".Work") {
+		if err = snap.FetchWorkCounts(gh); err != nil {
+			return err
 		}
 	}
+	checkingDrift = true
+	milestones := snap.Aggregate()
 
-	// Ensure that the full set of repos was accounted for in each milestone and warn if any are missing.
-	for t, ms := range milestones {
-		for _, repo := range repos {
-			if !ms.Repos[repo] {
-				fmt.Fprintf(os.Stderr, "warning: milestone %s is missing from repo %s\n", t, repo)
-			}
+	var shown []*milestone
+	for _, ms := range milestones {
+		complete := snap.Covered(ms)
+		if (opts.CompleteOnly && !complete) || (opts.IncompleteOnly && complete) || !opts.Match.Matches(ms.Title) {
+			continue
 		}
+		shown = append(shown, ms)
 	}
+	sortMilestones(shown, opts.Sort)
 
 	// Finally actually print out the list of milestones.
-	for t, ms := range milestones {
+	if opts.format != nil {
+		return printMilestonesFormat(opts.format, snap, shown)
+	}
+	switch opts.Output {
+	case "json":
+		return printMilestonesJSON(snap, shown)
+	case "csv":
+		return printMilestonesCSV(snap, shown)
+	default:
+		// Once closed milestones are included, the state is worth showing alongside the rest.
+		printMilestonesTable(shown, opts.State != "open")
+		return nil
+	}
+}
+
+func printMilestonesTable(milestones []*milestone, showState bool) {
+	tab := newTable(
+		column{Name: "TITLE"},
+		column{Name: "DUE"},
+		column{Name: "STATE", Wide: !showState},
+		column{Name: "OPEN"},
+		column{Name: "CLOSED"},
+		column{Name: "COMPLETE"},
+		column{Name: "ISSUES", Wide: true},
+		column{Name: "PRS", Wide: true},
+		column{Name: "REPOS"},
+		column{Name: "SLIP", Wide: true},
+		column{Name: "DESCRIPTION", Wide: true},
+		column{Name: "URLS", Wide: true},
+	)
+	th := currentTheme()
+	now := time.Now()
+	for _, ms := range milestones {
 		var repos []string
 		for repo := range ms.Repos {
 			repos = append(repos, string(repo))
 		}
 		sort.Strings(repos)
-		var repoList string
-		for i, repo := range repos {
-			if i > 0 {
-				repoList += ","
+		var urls []string
+		for _, r := range repos {
+			urls = append(urls, ms.URLs[repo(r)])
+		}
+
+		var dueColor string
+		if ms.State == "open" && !ms.DueOn.IsZero() && ms.DueOn.Before(now) {
+			dueColor = th.Overdue
+		}
+		var issues, prs string
+		if w := ms.Work; w != nil {
+			issues = fmt.Sprintf("%d open, %d closed", w.OpenIssues, w.ClosedIssues)
+			prs = fmt.Sprintf("%d open, %d closed", w.OpenPRs, w.ClosedPRs)
+		}
+
+		tab.AddRow(
+			cell{Text: ms.Title},
+			cell{Text: inDueZone(ms.DueOn).Format("Mon Jan _2 2006"), Color: dueColor},
+			cell{Text: ms.State},
+			cell{Text: strconv.Itoa(ms.OpenIssues)},
+			cell{Text: strconv.Itoa(ms.ClosedIssues)},
+			cell{Text: completion(ms.OpenIssues, ms.ClosedIssues)},
+			cell{Text: issues},
+			cell{Text: prs},
+			cell{Text: strings.Join(repos, ","), Color: th.Repo},
+			cell{Text: ms.Slip.String()},
+			cell{Text: ms.Description},
+			cell{Text: strings.Join(urls, ",")},
+		)
+	}
+	tab.Print()
+}
+
+func printMilestonesJSON(snap *orgSnapshot, milestones []*milestone) error {
+	return printJSON(milestonesJSON(snap, milestones))
+}
+
+// printMilestonesCSV prints a row for each milestone in each repo that has it, with that repo's issue counts.
+func printMilestonesCSV(snap *orgSnapshot, milestones []*milestone) error {
+	var rows [][]string
+	for _, ms := range milestones {
+		for _, rs := range snap.Repos {
+			if !ms.Repos[rs.Repo] {
+				continue
 			}
-			repoList += repo
+			for _, m := range rs.Milestones {
+				if m.GetNumber() != ms.Numbers[rs.Repo] {
+					continue
+				}
+				rows = append(rows, []string{
+					m.GetTitle(),
+					csvDate(m.GetDueOn()),
+					m.GetState(),
+					string(rs.Repo),
+					strconv.Itoa(m.GetNumber()),
+					strconv.Itoa(m.GetOpenIssues()),
+					strconv.Itoa(m.GetClosedIssues()),
+					m.GetHTMLURL(),
+				})
+			}
+		}
+	}
+	return printCSV([]string{"title", "due_on", "state", "repo", "number", "open_issues", "closed_issues", "url"}, rows)
+}
+
+// milestonesJSON converts aggregated milestones to their JSON form.
+func milestonesJSON(snap *orgSnapshot, milestones []*milestone) []milestoneJSON {
+	out := []milestoneJSON{}
+	for _, ms := range milestones {
+		mj := milestoneJSON{
+			Title:        ms.Title,
+			State:        ms.State,
+			Description:  ms.Description,
+			OpenIssues:   ms.OpenIssues,
+			ClosedIssues: ms.ClosedIssues,
+		}
+		if !ms.DueOn.IsZero() {
+			d := ms.DueOn
+			mj.DueOn = &d
 		}
+		if total := ms.OpenIssues + ms.ClosedIssues; total > 0 {
+			pct := ms.ClosedIssues * 100 / total
+			mj.Complete = &pct
+		}
+		if w := ms.Work; w != nil {
+			mj.Work = &workCountsJSON{
+				OpenIssues:   w.OpenIssues,
+				ClosedIssues: w.ClosedIssues,
+				OpenPRs:      w.OpenPRs,
+				ClosedPRs:    w.ClosedPRs,
+			}
+		}
+		for _, rs := range snap.Repos {
+			rj := repoMilestoneJSON{Repo: string(rs.Repo), Present: ms.Repos[rs.Repo]}
+			if rj.Present {
+				rj.Number, rj.URL = ms.Numbers[rs.Repo], ms.URLs[rs.Repo]
+			}
+			mj.Repos = append(mj.Repos, rj)
+		}
+		out = append(out, mj)
+	}
+	return out
+}
+
+func doSaveToken() error {
+	path := tokenFilePath
+	if path == "" {
+		path = defaultTokenFilePath()
+	}
 
-		fmt.Printf("%s\t%s\t%v\n", t, ms.DueOn.Format("Mon Jan _2 2006"), repoList)
+	tok, err := readTokenInput()
+	if err != nil {
+		return err
+	} else if tok == "" {
+		return errors.New("missing token to save")
+	}
+	if err = saveEncryptedToken(path, tok); err != nil {
+		return err
 	}
 
+	fmt.Fprintf(stdout, "saved encrypted token to %s\n", path)
 	return nil
 }
 
-func doSetMilestone(orgOrRepo string, milestone string, newDueOn time.Time) error {
-	gh := ghClient()
+func doSetMilestone(orgOrRepo string, milestone string, match *titleMatch, due dueDate) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
 
-	// First get the list of repos under consideration.
-	repos, err := getRepos(gh, orgOrRepo)
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
 	if err != nil {
 		return err
 	}
+	titles, err := matchedTitles(snap, milestone, match)
+	if err != nil {
+		return err
+	}
+	var p plan
+	for _, title := range titles {
+		p.Merge(planSetMilestone(snap, title, due))
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
 
-	// Now, for each of them, loop over and set the milestones that match.
-	c := 0
-	for _, r := range repos {
-		ms, _, err := gh.Issues.ListMilestones(context.Background(), r.Owner(), r.Repo(), nil)
-		if err != nil {
-			return errors.Wrapf(err, "listing milestones for repo %s", r)
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("set %d milestone due dates", c)
+		} else {
+			fmt.Fprintf(stdout, "would set %d milestone due dates; re-run with --yes to edit them\n", c)
 		}
+	}
 
-		_, changed, err := changeMilestoneDueOn(gh, r, ms, milestone, newDueOn)
-		if err != nil {
-			return err
-		} else if changed {
-			c++
+	return nil
+}
+
+// planSetMilestone plans to set the due date of every matching milestone in the snapshot, leaving their
+// states alone; set snapshots only open milestones, so closed ones are left to reopen. A relative due date is
+// taken from each milestone's current one, and the zero due date clears it.
+func planSetMilestone(snap *orgSnapshot, milestone string, due dueDate) *plan {
+	var p plan
+	for _, rs := range snap.Repos {
+		if m := rs.Milestone(milestone); m != nil {
+			f := fieldsOf(m)
+			f.DueOn = due.From(m.GetDueOn())
+			p.Edit(rs.Repo, m, f)
+		}
+	}
+	return &p
+}
+
+func doDescribeMilestone(orgOrRepo, milestone, description string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	// Plan to set the description in every repo that has the milestone, leaving any that already match.
+	var p plan
+	for _, rs := range snap.Repos {
+		if m := rs.Milestone(milestone); m != nil {
+			f := fieldsOf(m)
+			f.Description = description
+			p.Edit(rs.Repo, m, f)
 		}
 	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
 
-	if c > 0 {
-		if yes {
-			fmt.Printf("set %d milestone due dates\n", c)
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("set %d milestone descriptions", c)
 		} else {
-			fmt.Printf("would set %d milestone due dates; re-run with --yes to edit them\n", c)
+			fmt.Fprintf(stdout, "would set %d milestone descriptions; re-run with --yes to edit them\n", c)
 		}
 	}
 
 	return nil
 }
 
-func doCloseMilestone(orgOrRepo string, milestone string) error {
-	gh := ghClient()
+// closeOptions controls how close behaves.
+type closeOptions struct {
+	EnsureNext   bool   // open the next milestone where closing this one would leave none open.
+	WhenComplete bool   // close nothing unless the milestone has no open issues or PRs in any repo.
+	MoveOpenTo   string // the title of a milestone to move remaining open issues and PRs to before closing.
+}
 
-	// First get the list of repos under consideration.
-	repos, err := getRepos(gh, orgOrRepo)
+func doCloseMilestone(orgOrRepo string, milestone string, match *titleMatch, opts closeOptions) error {
+	gh, err := ghClient()
 	if err != nil {
 		return err
 	}
 
-	// Now, for each of them, loop over and close the milestones that match.
-	c := 0
-	for _, r := range repos {
-		ms, _, err := gh.Issues.ListMilestones(context.Background(), r.Owner(), r.Repo(), nil)
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+	titles, err := matchedTitles(snap, milestone, match)
+	if err != nil {
+		return err
+	}
+	// Plan every matching milestone before applying any, so that --when-complete closes none of them unless
+	// all of them are complete.
+	var p plan
+	var next string
+	for _, title := range titles {
+		tp, tn, err := planCloseMilestone(snap, title, opts)
 		if err != nil {
-			return errors.Wrapf(err, "listing milestones for repo %s", r)
+			return err
 		}
-
-		for _, m := range ms {
-			t, n, s := m.GetTitle(), m.GetNumber(), m.GetState()
-			if t == milestone && s == "open" {
-				// See if there are any issues open in this milestone.
-				opts := &github.IssueListByRepoOptions{Milestone: strconv.Itoa(n)}
-				issues, _, err := gh.Issues.ListByRepo(context.Background(), r.Owner(), r.Repo(), opts)
-				if err != nil {
-					return errors.Wrapf(err, "checking for open milestone %s issues in repo %s", t, r)
-				}
-				for _, iss := range issues {
-					fmt.Fprintf(os.Stderr, "warning: issue #%d in repo %s still active in milestone %s",
-						iss.GetNumber(), r, t)
-				}
-
-				if yes {
-					s = "closed"
-					m.State = &s
-					_, _, err := gh.Issues.EditMilestone(context.Background(), r.Owner(), r.Repo(), n, m)
-					if err != nil {
-						return errors.Wrapf(err, "closing milestone %s (#%d) in repo %s", t, n, r)
-					}
-					fmt.Printf("closed milestone %s (#%d) in repo %s\n", t, n, r)
-				} else {
-					fmt.Printf("would close milestone %s (#%d) in repo %s\n", t, n, r)
-				}
-
-				c++
-			}
+		p.Merge(tp)
+		next = tn
+	}
+	var moved int
+	if opts.MoveOpenTo != "" {
+		if moved, err = moveOpenIssues(gh, snap, &p, opts.MoveOpenTo); err != nil {
+			return err
 		}
 	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
 
-	if c > 0 {
-		if yes {
-			fmt.Printf("closed %d milestones\n", c)
+	if moved > 0 {
+		if applying() {
+			successf("moved %d open issues and PRs from milestone %s to %s", moved, milestone, opts.MoveOpenTo)
+		} else {
+			fmt.Fprintf(stdout, "would move %d open issues and PRs from milestone %s to %s\n",
+				moved, milestone, opts.MoveOpenTo)
+		}
+	}
+	if c, o := p.Count(editChange), p.Count(createChange); c > 0 {
+		if applying() {
+			if o > 0 {
+				successf("closed %d milestones and opened %d %s milestones", c, o, next)
+			} else {
+				successf("closed %d milestones", c)
+			}
 		} else {
-			fmt.Printf("would close %d milestones; re-run with --yes to close them\n", c)
+			if o > 0 {
+				fmt.Fprintf(stdout, "would close %d milestones and open %d %s milestones; "+
+					"re-run with --yes to do so\n", c, o, next)
+			} else {
+				fmt.Fprintf(stdout, "would close %d milestones; re-run with --yes to close them\n", c)
+			}
 		}
 	}
 
 	return nil
 }
 
-func doOpenMilestone(orgOrRepo, milestone string, dueOn time.Time) error {
-	gh := ghClient()
+// planCloseMilestone plans to close every matching open milestone, warning about any that still have
+// open issues, and returns the title of the next milestone if it is to be opened where need be.
+func planCloseMilestone(snap *orgSnapshot, milestone string, opts closeOptions) (*plan, string, error) {
+	var next string
+	var cadence time.Duration
+	if opts.EnsureNext {
+		var err error
+		if next, err = nextTitle(milestone, cfg.Next.Bump); err != nil {
+			return nil, "", err
+		}
+		if cadence, err = parseCadence(cfg.Next.Cadence); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// With --when-complete, refuse to close anything until all of the milestone's work is done everywhere.
+	if opts.WhenComplete {
+		var incomplete, open int
+		for _, rs := range snap.Repos {
+			if m := rs.Milestone(milestone); m != nil && m.GetOpenIssues() > 0 {
+				fmt.Fprintf(stdout, "repo %s has %d open issues and PRs in milestone %s (#%d): %s\n",
+					rs.Repo, m.GetOpenIssues(), milestone, m.GetNumber(), m.GetHTMLURL())
+				incomplete++
+				open += m.GetOpenIssues()
+			}
+		}
+		if incomplete > 0 {
+			return nil, "", errors.Errorf("milestone %s is not complete: %d repos still have %d open issues "+
+				"and PRs in it; closed nothing", milestone, incomplete, open)
+		}
+	}
 
-	// First get the list of repos under consideration.
-	repos, err := getRepos(gh, orgOrRepo)
-	if err != nil {
-		return err
+	var p plan
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(milestone)
+		if m == nil || m.GetState() != "open" {
+			continue
+		}
+		if open := m.GetOpenIssues(); open > 0 && opts.MoveOpenTo == "" {
+			warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) in repo %s still has %d open issues",
+				milestone, m.GetNumber(), rs.Repo, open), "milestone %s still has open issues in %s", milestone)
+		}
+		f := fieldsOf(m)
+		f.State = "closed"
+		p.Edit(rs.Repo, m, f)
+
+		// If this was the repo's last open milestone, make sure that issues have somewhere to land.
+		if opts.EnsureNext && len(rs.Milestones) == 1 {
+			p.Create(rs.Repo, next, milestoneFields{State: "open", DueOn: nextDueOn(m.GetDueOn(), cadence)})
+		}
 	}
+	return &p, next, nil
+}
 
-	// Now, for each of them, loop over and create a milestone. If it already exists, see if
-	// we need to adjust the date.
-	var open, edit int
-	for _, r := range repos {
-		ms, _, err := gh.Issues.ListMilestones(context.Background(), r.Owner(), r.Repo(), nil)
-		if err != nil {
-			return errors.Wrapf(err, "listing milestones for repo %s", r)
+// moveOpenIssues moves the open issues and PRs in the milestones that a plan closes to another milestone,
+// returning how many were (or would be) moved. Every repo with open issues to move must already have an
+// open milestone to move them to; if any doesn't, nothing is moved.
+func moveOpenIssues(gh *github.Client, snap *orgSnapshot, p *plan, to string) (int, error) {
+	type move struct {
+		repo     repo
+		from, to *github.Milestone
+	}
+	var moves []move
+	var missing []string
+	var from string
+	for _, rs := range snap.Repos {
+		var m *github.Milestone
+		for _, c := range p.Changes {
+			if c.Kind == editChange && c.Repo == rs.Repo {
+				m = rs.MilestoneNumbered(c.Number)
+			}
 		}
+		if m == nil || m.GetOpenIssues() == 0 {
+			continue
+		}
+		from = m.GetTitle()
+		target := rs.Milestone(to)
+		if target == nil || target.GetState() != "open" {
+			missing = append(missing, string(rs.Repo))
+			continue
+		}
+		moves = append(moves, move{repo: rs.Repo, from: m, to: target})
+	}
+	if len(missing) > 0 {
+		return 0, errors.Errorf("milestone %s is not open in %s, which still have open issues in milestone %s; "+
+			"open it there first (e.g., with `ghmm open`)", to, strings.Join(missing, ", "), from)
+	}
 
-		exists, changed, err := changeMilestoneDueOn(gh, r, ms, milestone, dueOn)
+	var moved int
+	for _, mv := range moves {
+		n, err := moveIssues(gh, mv.repo, mv.from, "open", mv.to.GetNumber(), to, nil)
+		moved += n
 		if err != nil {
-			return err
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// openDescription is the description given to milestones created by open.
+var openDescription string
+
+// planOpenMilestone plans to create the milestone in every repo that has no milestone by that title, in any
+// state, since GitHub requires titles to be unique within a repo. Existing milestones are left alone, with a
+// warning if they differ from what was asked for, unless update is set, in which case they are reopened and
+// given the requested due date and description.
+func planOpenMilestone(snap *orgSnapshot, milestone string, dueOn time.Time, description string,
+	update bool) (*plan, int) {
+	var p plan
+	var existing int
+	want := milestoneFields{Title: milestone, State: "open", DueOn: dueOn, Description: description}
+	for _, rs := range snap.Repos {
+		if len(rs.MilestonesTitled(milestone)) == 0 {
+			p.Create(rs.Repo, milestone, want)
+			continue
 		}
 
-		if exists {
-			if changed {
-				edit++
+		existing++
+		m := rs.Milestone(milestone)
+		if m == nil {
+			continue
+		} else if update {
+			f := fieldsOf(m)
+			f.State, f.DueOn = want.State, want.DueOn
+			if description != "" {
+				f.Description = description
 			}
+			p.Edit(rs.Repo, m, f)
+		} else if s := m.GetState(); s != want.State {
+			warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) already exists in repo %s but is %s; skipping it "+
+				"(pass --update-existing to reopen it)", milestone, m.GetNumber(), rs.Repo, s),
+				"milestone %s already exists but is not open in %s; pass --update-existing to reopen them",
+				milestone)
 		} else {
-			if yes {
-				o := "open"
-				m := &github.Milestone{
-					Title: &milestone,
-					DueOn: &dueOn,
-					State: &o,
-				}
-				res, _, err := gh.Issues.CreateMilestone(context.Background(), r.Owner(), r.Repo(), m)
-				if err != nil {
-					return errors.Wrapf(err, "opening milestone %s in repo %s", milestone, r)
-				}
-				fmt.Printf("opened milestone %s (#%d) in repo %s with a due date on %v\n",
-					milestone, res.Number, r, dueOn)
-			} else {
-				fmt.Printf("would open milestone %s in repo %s with a due date on %v\n", milestone, r, dueOn)
+			if d := m.GetDueOn(); !d.Equal(want.DueOn) {
+				warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) already exists in repo %s with a different due "+
+					"date (has %v, want %v); skipping it (pass --update-existing to change it)",
+					milestone, m.GetNumber(), rs.Repo, d, want.DueOn),
+					"milestone %s already exists with a different due date in %s; "+
+						"pass --update-existing to change them", milestone)
+			}
+			if d := m.GetDescription(); description != "" && d != description {
+				warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) already exists in repo %s with a different "+
+					"description (has %q, want %q); skipping it (pass --update-existing to change it)",
+					milestone, m.GetNumber(), rs.Repo, d, description),
+					"milestone %s already exists with a different description in %s; "+
+						"pass --update-existing to change them", milestone)
 			}
-			open++
 		}
 	}
+	return &p, existing
+}
+
+func doOpenMilestone(orgOrRepo, milestone string, dueOn time.Time, description string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	p, existing := planOpenMilestone(snap, milestone, dueOn, description, updateExisting)
+	if existing > 0 && !updateExisting {
+		infof("skipping %d repos that already have milestone %s", existing, milestone)
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
 
-	if open > 0 || edit > 0 {
-		if yes {
-			fmt.Printf("opened %d and edited %d milestones\n", open, edit)
+	if open, edit := p.Count(createChange), p.Count(editChange); open > 0 || edit > 0 {
+		if applying() {
+			successf("opened %d and edited %d milestones", open, edit)
 		} else {
-			fmt.Printf("would open %d and edit %d milestones; re-run with --yes to do so\n", open, edit)
+			fmt.Fprintf(stdout, "would open %d and edit %d milestones; re-run with --yes to do so\n", open, edit)
 		}
 	}
 
 	return nil
 }
 
-// changeMilestoneDueOn looks in a list of milestones, for the given repo, for a match. If there is an open
-// milestone with a different due date, it will be changed. The function returns two bools: one says whether
-// the milestone in question was found, the other says whether the milestone was edited.
-func changeMilestoneDueOn(gh *github.Client, r repo, ms []*github.Milestone,
-	milestone string, newDueOn time.Time) (bool, bool, error) {
-	for _, m := range ms {
-		o := "open"
-		t, n, s, d := m.GetTitle(), m.GetNumber(), m.GetState(), m.GetDueOn()
-		if t == milestone {
-			if s != o || d != newDueOn {
-				if yes {
-					m.State = &o
-					m.DueOn = &newDueOn
-					_, _, err := gh.Issues.EditMilestone(context.Background(), r.Owner(), r.Repo(), n, m)
-					if err != nil {
-						return false, false, errors.Wrapf(err, "editing milestone %s (#%d) in repo %s", t, n, r)
-					}
-					fmt.Printf("changed milestone %s (#%d) in repo %s due date from %v to %v\n",
-						t, n, r, d, newDueOn)
-				} else {
-					fmt.Printf("would change milestone %s (#%d) in repo %s due date from %v to %v\n",
-						t, n, r, d, newDueOn)
-				}
+func doRenameMilestone(orgOrRepo, oldTitle, newTitle string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
 
-				return true, true, nil
-			}
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	// Plan to retitle the milestone in every repo that has it, skipping any repo where the new title is
+	// already taken, since GitHub requires titles to be unique within a repo.
+	var p plan
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(oldTitle)
+		if m == nil {
+			continue
+		}
+		if others := rs.MilestonesTitled(newTitle); len(others) > 0 {
+			warnRepo(rs.Repo, fmt.Sprintf("repo %s already has a milestone %s (#%d); not renaming %s (#%d)",
+				rs.Repo, newTitle, others[0].GetNumber(), oldTitle, m.GetNumber()),
+				"not renaming milestone %s to %s, which already exists in %s", oldTitle, newTitle)
+			continue
+		}
+		f := fieldsOf(m)
+		f.Title = newTitle
+		p.Edit(rs.Repo, m, f)
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("renamed %d milestones", c)
+		} else {
+			fmt.Fprintf(stdout, "would rename %d milestones; re-run with --yes to rename them\n", c)
+		}
+	}
+
+	return nil
+}
 
-			return true, false, nil
+func doDeleteMilestone(orgOrRepo, milestone string, unassign bool) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	// Plan to delete the milestone from every repo that has it. Where issues are still in it, deleting it
+	// would silently drop them from the milestone, so skip such repos unless --unassign was passed.
+	var p plan
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(milestone)
+		if m == nil {
+			continue
+		}
+		if n := m.GetOpenIssues() + m.GetClosedIssues(); n > 0 && !unassign {
+			warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) in repo %s still has %d issues; skipping it "+
+				"(pass --unassign to remove them from it and delete it)", milestone, m.GetNumber(), rs.Repo, n),
+				"milestone %s still has issues in %s; pass --unassign to delete it anyway", milestone)
+			continue
+		}
+		p.Delete(rs.Repo, m, unassign)
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("deleted %d milestones", c)
+		} else {
+			fmt.Fprintf(stdout, "would delete %d milestones; re-run with --yes to delete them\n", c)
 		}
 	}
 
-	return false, false, nil
+	return nil
 }
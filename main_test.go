@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+func TestPlanSetMilestone(t *testing.T) {
+	jul, aug := dueOnDate(2019, 7, 1), dueOnDate(2019, 8, 1)
+	snap := testSnapshot(
+		&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul, 0)}},
+		&repoSnapshot{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(2, "M1", "open", aug, 0)}},
+		&repoSnapshot{Repo: "acme/c", Milestones: []*github.Milestone{testMilestone(3, "M1", "open", time.Time{}, 0)}},
+		&repoSnapshot{Repo: "acme/d", Milestones: []*github.Milestone{testMilestone(4, "M2", "open", jul, 0)}},
+	)
+
+	tests := []struct {
+		name      string
+		milestone string
+		due       string
+		want      []string
+	}{
+		{
+			name:      "absolute",
+			milestone: "M1",
+			due:       "8/1/2019",
+			want: []string{
+				`edit acme/a#1 M1 open 2019-08-01 ""`,
+				`edit acme/c#3 M1 open 2019-08-01 ""`,
+			},
+		},
+		{
+			name:      "relative",
+			milestone: "M1",
+			due:       "+1w",
+			want: []string{
+				`edit acme/a#1 M1 open 2019-07-08 ""`,
+				`edit acme/b#2 M1 open 2019-08-08 ""`,
+				`edit acme/c#3 M1 open ` + inDueZone(todayDueOn().AddDate(0, 0, 7)).Format(isoDateFormat) + ` ""`,
+			},
+		},
+		{
+			name:      "clear",
+			milestone: "M1",
+			want: []string{
+				`edit acme/a#1 M1 open none ""`,
+				`edit acme/b#2 M1 open none ""`,
+			},
+		},
+		{
+			name:      "already due",
+			milestone: "M2",
+			due:       "7/1/2019",
+		},
+		{
+			name:      "missing",
+			milestone: "M3",
+			due:       "7/1/2019",
+		},
+	}
+	for _, test := range tests {
+		var due dueDate
+		if test.due != "" {
+			var err error
+			if due, err = parseDueDate(test.due); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		checkPlan(t, test.name, planSetMilestone(snap, test.milestone, due), test.want)
+	}
+}
+
+func TestPlanOpenMilestone(t *testing.T) {
+	jul := dueOnDate(2019, 7, 1)
+	snap := testSnapshot(
+		&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul, 0)}},
+		&repoSnapshot{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(2, "M1", "closed", jul, 0)}},
+		&repoSnapshot{Repo: "acme/c", Milestones: []*github.Milestone{testMilestone(3, "M2", "open", jul, 0)}},
+	)
+
+	tests := []struct {
+		name     string
+		update   bool
+		want     []string
+		warnings int
+	}{
+		{
+			// A closed milestone by the title still takes it, so it is skipped rather than created again.
+			name:     "closed exists",
+			want:     []string{`create acme/c#0 M1 open 2019-07-01 ""`},
+			warnings: 1,
+		},
+		{
+			name:   "update existing",
+			update: true,
+			want: []string{
+				`edit acme/b#2 M1 open 2019-07-01 ""`,
+				`create acme/c#0 M1 open 2019-07-01 ""`,
+			},
+		},
+	}
+	for _, test := range tests {
+		takeWarnings()
+		p, existing := planOpenMilestone(snap, "M1", jul, "", test.update)
+		checkPlan(t, test.name, p, test.want)
+		if existing != 2 {
+			t.Errorf("%s: got %d existing, want 2", test.name, existing)
+		}
+		if w := takeWarnings(); len(w) != test.warnings {
+			t.Errorf("%s: got warnings %v, want %d", test.name, w, test.warnings)
+		} else if len(w) > 0 && !strings.Contains(w[0], "acme/b but is closed") {
+			t.Errorf("%s: got warning %q", test.name, w[0])
+		}
+	}
+}
+
+func TestPlanCloseMilestone(t *testing.T) {
+	jul := dueOnDate(2019, 7, 1)
+	snap := testSnapshot(
+		&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{
+			testMilestone(1, "v1.2", "open", jul, 0),
+			testMilestone(2, "v1.3", "open", time.Time{}, 0),
+		}},
+		&repoSnapshot{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(7, "v1.2", "open", jul, 3)}},
+		&repoSnapshot{Repo: "acme/c", Milestones: []*github.Milestone{testMilestone(4, "v1.1", "open", jul, 0)}},
+	)
+
+	tests := []struct {
+		name     string
+		opts     closeOptions
+		want     []string
+		warnings int
+		err      string
+	}{
+		{
+			name: "close",
+			want: []string{
+				`edit acme/a#1 v1.2 closed 2019-07-01 ""`,
+				`edit acme/b#7 v1.2 closed 2019-07-01 ""`,
+			},
+			warnings: 1,
+		},
+		{
+			name: "ensure next",
+			opts: closeOptions{EnsureNext: true},
+			want: []string{
+				`edit acme/a#1 v1.2 closed 2019-07-01 ""`,
+				`edit acme/b#7 v1.2 closed 2019-07-01 ""`,
+				`create acme/b#0 v1.3 open 2019-07-15 ""`,
+			},
+			warnings: 1,
+		},
+		{
+			name: "move open",
+			opts: closeOptions{MoveOpenTo: "v1.3"},
+			want: []string{
+				`edit acme/a#1 v1.2 closed 2019-07-01 ""`,
+				`edit acme/b#7 v1.2 closed 2019-07-01 ""`,
+			},
+		},
+		{
+			name: "when complete",
+			opts: closeOptions{WhenComplete: true},
+			err:  "milestone v1.2 is not complete: 1 repos still have 3 open issues and PRs in it; closed nothing",
+		},
+	}
+	for _, test := range tests {
+		takeWarnings()
+		p, next, err := planCloseMilestone(snap, "v1.2", test.opts)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		checkPlan(t, test.name, p, test.want)
+		if test.opts.EnsureNext && next != "v1.3" {
+			t.Errorf("%s: got next milestone %q", test.name, next)
+		}
+		if w := takeWarnings(); len(w) != test.warnings {
+			t.Errorf("%s: got warnings %v, want %d", test.name, w, test.warnings)
+		} else if len(w) > 0 && !strings.Contains(w[0], "acme/b still has 3 open issues") {
+			t.Errorf("%s: got warning %q", test.name, w[0])
+		}
+	}
+}
+
+func TestPlanCloseMilestoneNextSettings(t *testing.T) {
+	defer func(next nextConfig) { cfg.Next = next }(cfg.Next)
+	snap := testSnapshot(&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{
+		testMilestone(1, "v1.2.3", "open", dueOnDate(2019, 7, 1), 0),
+	}})
+
+	cfg.Next = nextConfig{Bump: "minor", Cadence: "1w"}
+	p, next, err := planCloseMilestone(snap, "v1.2.3", closeOptions{EnsureNext: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlan(t, "minor", p, []string{
+		`edit acme/a#1 v1.2.3 closed 2019-07-01 ""`,
+		`create acme/a#0 v1.3.0 open 2019-07-08 ""`,
+	})
+	if next != "v1.3.0" {
+		t.Errorf("got next milestone %q", next)
+	}
+
+	for _, bad := range []nextConfig{{Bump: "build"}, {Cadence: "fortnightly"}} {
+		cfg.Next = bad
+		if _, _, err := planCloseMilestone(snap, "v1.2.3", closeOptions{EnsureNext: true}); err == nil {
+			t.Errorf("planned with bad settings %+v", bad)
+		}
+	}
+	if _, _, err := planCloseMilestone(snap, "Spring", closeOptions{}); err != nil {
+		t.Errorf("closing without --ensure-next needs no version: %v", err)
+	}
+}
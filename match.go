@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// titleMatch selects a family of milestones by title: with a regular expression (--match), which must match
+// the whole title, or a shell-style glob (--match-glob), such as 'v0.2*'.
+type titleMatch struct {
+	Regexp string
+	Glob   string
+
+	re *regexp.Regexp
+}
+
+// addMatchFlags registers the --match and --match-glob flags on a command.
+func addMatchFlags(cmd *cobra.Command, m *titleMatch) {
+	cmd.PersistentFlags().StringVar(
+		&m.Regexp, "match", "", "Operate on every milestone whose whole title matches this regular expression "+
+			"(e.g. 'v0\\.2[0-9]'), instead of one title")
+	cmd.PersistentFlags().StringVar(
+		&m.Glob, "match-glob", "", "Operate on every milestone whose title matches this glob pattern "+
+			"(e.g. 'v0.2*'), instead of one title")
+}
+
+// Given returns true if a pattern was given.
+func (m *titleMatch) Given() bool {
+	return m.Regexp != "" || m.Glob != ""
+}
+
+// compile validates the pattern, if one was given.
+func (m *titleMatch) compile() error {
+	switch {
+	case m.Regexp != "" && m.Glob != "":
+		return errors.New("--match and --match-glob are mutually exclusive")
+	case m.Regexp != "":
+		re, err := regexp.Compile("^(?:" + m.Regexp + ")$")
+		if err != nil {
+			return errors.Wrapf(err, "malformed --match pattern %q", m.Regexp)
+		}
+		m.re = re
+	case m.Glob != "":
+		if _, err := path.Match(m.Glob, ""); err != nil {
+			return errors.Wrapf(err, "malformed --match-glob pattern %q", m.Glob)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the title matches the pattern, or if no pattern was given.
+func (m *titleMatch) Matches(title string) bool {
+	switch {
+	case m.re != nil:
+		return m.re.MatchString(title)
+	case m.Glob != "":
+		ok, _ := path.Match(m.Glob, title)
+		return ok
+	default:
+		return true
+	}
+}
+
+// String returns the pattern as given.
+func (m *titleMatch) String() string {
+	if m.Glob != "" {
+		return m.Glob
+	}
+	return m.Regexp
+}
+
+// matchedTitles returns the titles a command operates on: the one given, or, if a pattern was given instead,
+// every matching title in the snapshot, of which there must be at least one.
+func matchedTitles(snap *orgSnapshot, milestone string, m *titleMatch) ([]string, error) {
+	if !m.Given() {
+		return []string{milestone}, nil
+	}
+	titles := snap.MatchingTitles(m)
+	if len(titles) == 0 {
+		return nil, errors.Errorf("no milestones in %s match %q", snap.Target, m.String())
+	}
+	return titles, nil
+}
+
+// MatchingTitles returns the distinct titles of the snapshot's milestones that match the pattern, sorted.
+func (snap *orgSnapshot) MatchingTitles(m *titleMatch) []string {
+	seen := make(map[string]bool)
+	var titles []string
+	for _, rs := range snap.Repos {
+		for _, ms := range rs.Milestones {
+			if t := ms.GetTitle(); !seen[t] && m.Matches(t) {
+				seen[t] = true
+				titles = append(titles, t)
+			}
+		}
+	}
+	sort.Strings(titles)
+	return titles
+}
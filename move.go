@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// moveIssuesOptions controls which issues move-issues moves.
+type moveIssuesOptions struct {
+	Label []string // only move issues and PRs with any of these labels.
+	State string   // which issues and PRs to move: open, closed, or all.
+}
+
+// doMoveIssues moves the issues and PRs in one milestone to another, in every repo that has the first. Every
+// repo with issues to move must already have the second milestone open; if any doesn't, nothing is moved.
+func doMoveIssues(orgOrRepo, from, to string, opts moveIssuesOptions) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	type move struct {
+		repo     repo
+		from, to *github.Milestone
+	}
+	var moves []move
+	var missing []string
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(from)
+		if m == nil {
+			continue
+		}
+		var n int
+		switch opts.State {
+		case "open":
+			n = m.GetOpenIssues()
+		case "closed":
+			n = m.GetClosedIssues()
+		default:
+			n = m.GetOpenIssues() + m.GetClosedIssues()
+		}
+		if n == 0 {
+			continue
+		}
+		target := rs.Milestone(to)
+		if target == nil || target.GetState() != "open" {
+			missing = append(missing, string(rs.Repo))
+			continue
+		}
+		moves = append(moves, move{repo: rs.Repo, from: m, to: target})
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("milestone %s is not open in %s, which have issues to move from milestone %s; "+
+			"open it there first (e.g., with `ghmm open`)", to, strings.Join(missing, ", "), from)
+	}
+
+	repos := make([]repo, len(moves))
+	for i, mv := range moves {
+		repos[i] = mv.repo
+	}
+	if err = checkWriteAccess(gh, repos); err != nil {
+		return err
+	}
+
+	var filter func(*github.Issue) bool
+	if len(opts.Label) > 0 {
+		filter = func(iss *github.Issue) bool { return hasAnyLabel(iss, opts.Label) }
+	}
+	var moved, touched int
+	for _, mv := range moves {
+		n, err := moveIssues(gh, mv.repo, mv.from, opts.State, mv.to.GetNumber(), to, filter)
+		moved += n
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			touched++
+		}
+	}
+
+	switch {
+	case moved == 0:
+		infof("no issues or PRs to move from milestone %s to %s", from, to)
+	case applying():
+		successf("moved %d issues and PRs from milestone %s to %s in %d repos", moved, from, to, touched)
+	default:
+		fmt.Fprintf(stdout, "would move %d issues and PRs from milestone %s to %s in %d repos; "+
+			"re-run with --yes to move them\n", moved, from, to, touched)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nextConfig controls how the milestone following a given one is named and scheduled.
+type nextConfig struct {
+	// Bump is the version component incremented to name the next milestone: "major", "minor", "patch",
+	// or "" to increment the last numeric component of the title, whatever it is.
+	Bump string `yaml:"bump"`
+	// Cadence is the time between milestones, such as "14d" or "2w". It defaults to two weeks.
+	Cadence string `yaml:"cadence"`
+}
+
+// defaultCadence is the time between milestones when none is configured.
+const defaultCadence = 14 * 24 * time.Hour
+
+// versionRE picks apart a milestone title containing a dotted version number, like "v0.21" or "M42".
+var versionRE = regexp.MustCompile(`^(\D*)(\d+(?:\.\d+)*)(.*)$`)
+
+// nextTitle computes the title of the milestone that follows the given one, per the bump setting.
+func nextTitle(title, bump string) (string, error) {
+	match := versionRE.FindStringSubmatch(title)
+	if match == nil {
+		return "", errors.Errorf("cannot compute the milestone after %q: its title contains no version number", title)
+	}
+	parts := strings.Split(match[2], ".")
+
+	ix := len(parts) - 1
+	switch bump {
+	case "":
+	case "major":
+		ix = 0
+	case "minor":
+		ix = 1
+	case "patch":
+		ix = 2
+	default:
+		return "", errors.Errorf("unrecognized version bump %q; expected major, minor, or patch", bump)
+	}
+	for len(parts) <= ix {
+		parts = append(parts, "0")
+	}
+
+	n, err := strconv.Atoi(parts[ix])
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing version in milestone title %q", title)
+	}
+	parts[ix] = strconv.Itoa(n + 1)
+	for i := ix + 1; i < len(parts); i++ {
+		parts[i] = "0"
+	}
+	return match[1] + strings.Join(parts, ".") + match[3], nil
+}
+
+// parseCadence parses a duration expressed in days or weeks, such as "14d" or "2w".
+func parseCadence(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultCadence, nil
+	}
+	if len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err == nil && n > 0 {
+			switch s[len(s)-1] {
+			case 'd':
+				return time.Duration(n) * 24 * time.Hour, nil
+			case 'w':
+				return time.Duration(n) * 7 * 24 * time.Hour, nil
+			}
+		}
+	}
+	return 0, errors.Errorf("malformed cadence %q; expected a number of days or weeks, like 14d or 2w", s)
+}
+
+// nextDueOn computes the due date of the milestone that follows one due on the given date.
+func nextDueOn(dueOn time.Time, cadence time.Duration) time.Time {
+	if dueOn.IsZero() {
+		dueOn = todayDueOn()
+	}
+	return dueOn.Add(cadence)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	// notifyAfter, if positive, sends a desktop notification when a command that ran at least this long
+	// finishes, and whenever a command stops to wait for input.
+	notifyAfter time.Duration
+	// started is when this invocation began.
+	started = time.Now()
+)
+
+// notifyCommand returns the command that shows a desktop notification on this platform, or nil if there
+// is no known way to do so.
+func notifyCommand(title, message string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message),
+			appleScriptQuote(title))
+		return exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(10000, %s, %s, 'Info')
+Start-Sleep -Seconds 10
+$n.Dispose()`, powerShellQuote(title), powerShellQuote(message))
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return nil
+		}
+		return exec.Command("notify-send", "--app-name=ghmm", title, message)
+	}
+}
+
+func appleScriptQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func powerShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// notify shows a desktop notification, if they were asked for. Notifications are a convenience, so any
+// failure to show one is ignored.
+func notify(title, message string) {
+	if notifyAfter <= 0 {
+		return
+	}
+	// Don't wait for the notification, which on some platforms lingers until it is dismissed.
+	if cmd := notifyCommand(title, redact(message)); cmd != nil && cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
+
+// notifyInput lets the user know that a command is waiting for them to answer a prompt.
+func notifyInput(prompt string) {
+	notify("ghmm needs your input", prompt)
+}
+
+// notifyDone lets the user know that a long-running command has finished.
+func notifyDone(command string, err error) {
+	if elapsed := time.Since(started); elapsed >= notifyAfter {
+		if err != nil {
+			notify("ghmm "+command+" failed", err.Error())
+		} else {
+			notify("ghmm "+command+" finished", fmt.Sprintf("completed in %v", elapsed.Round(time.Second)))
+		}
+	}
+}
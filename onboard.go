@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// doOnboardRepo creates, in a newly created repo, every milestone open anywhere else in the org that the repo
+// doesn't yet have, so that it joins the release schedule straight away.
+func doOnboardRepo(org, newRepo string) error {
+	r, err := ghmm.ParseRepo(string(ghmm.QualifyRepo(org, newRepo)))
+	if err != nil {
+		return err
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, org, "open")
+	if err != nil {
+		return err
+	}
+	union := unionMilestones(snap, r)
+	if len(union) == 0 {
+		fmt.Fprintf(stdout, "no open milestones in %s to onboard %s to\n", org, r)
+		return nil
+	}
+
+	// Read the new repo directly, rather than expecting it in the snapshot, since it is likely newer than the
+	// cached repo list. Closed milestones count too, since titles must be unique within a repo.
+	have, err := ghmmClient(gh).ListMilestones(context.Background(), r, "all")
+	if err != nil {
+		return errors.Wrapf(err, "listing the milestones of repo %s", r)
+	}
+	titles := make(map[string]bool)
+	for _, m := range have {
+		titles[m.GetTitle()] = true
+	}
+
+	var p plan
+	for _, f := range union {
+		if !titles[f.Title] {
+			p.Create(r, f.Title, f)
+		}
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("onboarded %s with %d milestones", r, c)
+		} else {
+			fmt.Fprintf(stdout, "would onboard %s with %d milestones; re-run with --yes to create them\n", r, c)
+		}
+	} else {
+		fmt.Fprintf(stdout, "repo %s already has every open milestone in %s\n", r, org)
+	}
+	return nil
+}
+
+// unionMilestones returns one set of fields for every title open in any of the snapshot's repos other than
+// the given one, sorted by due date. Where repos disagree about a milestone's due date, the one most of them
+// agree on is used, taking its description from the first repo with that date.
+func unionMilestones(snap *orgSnapshot, except repo) []milestoneFields {
+	type candidate struct {
+		fields milestoneFields
+		votes  int
+	}
+	byTitle := make(map[string][]*candidate)
+	var titles []string
+	for _, rs := range snap.Repos {
+		if rs.Repo == except {
+			continue
+		}
+		for _, m := range rs.Milestones {
+			t := m.GetTitle()
+			cands, ok := byTitle[t]
+			if !ok {
+				titles = append(titles, t)
+			}
+			var found bool
+			for _, c := range cands {
+				if c.fields.DueOn.Equal(m.GetDueOn()) {
+					c.votes++
+					found = true
+					break
+				}
+			}
+			if !found {
+				f := fieldsOf(m)
+				f.State = "open"
+				byTitle[t] = append(cands, &candidate{fields: f, votes: 1})
+			}
+		}
+	}
+
+	var union []milestoneFields
+	for _, t := range titles {
+		best := byTitle[t][0]
+		for _, c := range byTitle[t][1:] {
+			if c.votes > best.votes {
+				best = c
+			}
+		}
+		union = append(union, best.fields)
+	}
+	sort.SliceStable(union, func(i, j int) bool {
+		return union[i].DueOn.Before(union[j].DueOn)
+	})
+	return union
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// maxWidth is the maximum number of characters any one column of tabular output may occupy.
+	maxWidth int
+	// full disables column truncation altogether, printing every value in its entirety.
+	full bool
+	// wide enables all columns in tabular output, rather than just the compact default set.
+	wide bool
+)
+
+// ellipsis marks the spot where a truncated column value was cut off.
+const ellipsis = "..."
+
+// truncate shortens a column value so that it fits within the configured maximum width, replacing the
+// elided tail with an ellipsis. If --full was passed, or no maximum width is set, s is returned as-is.
+func truncate(s string) string {
+	if full || maxWidth <= 0 {
+		return s
+	}
+	rs := []rune(s)
+	if len(rs) <= maxWidth {
+		return s
+	}
+	if maxWidth <= len(ellipsis) {
+		return string(rs[:maxWidth])
+	}
+	return string(rs[:maxWidth-len(ellipsis)]) + ellipsis
+}
+
+// column describes a single column of tabular output.
+type column struct {
+	Name string // the column's name.
+	Wide bool   // true if the column is only shown in --wide mode.
+}
+
+// cell is a single value in a table, along with the theme color (if any) used to render it.
+type cell struct {
+	Text  string
+	Color string
+}
+
+// table accumulates rows of tabular output so that every command renders them the same way.
+type table struct {
+	cols []column
+	rows [][]cell
+}
+
+func newTable(cols ...column) *table {
+	return &table{cols: cols}
+}
+
+// AddRow appends a row to the table. There must be exactly one value per column, wide or not.
+func (t *table) AddRow(cells ...cell) {
+	if len(cells) != len(t.cols) {
+		panic(fmt.Sprintf("table row has %d values, expected %d", len(cells), len(t.cols)))
+	}
+	t.rows = append(t.rows, cells)
+}
+
+// columnGap separates the columns of tabular output.
+const columnGap = "  "
+
+// Print renders the table to stdout under a header row, omitting wide-only columns unless --wide was passed.
+// Columns are aligned by padding each value to the widest in its column. (text/tabwriter can't be used for
+// this, since it would count the escape codes of colored values towards their width.)
+func (t *table) Print() {
+	if len(t.rows) == 0 {
+		return
+	}
+	var cols []int
+	for i, col := range t.cols {
+		if !col.Wide || wide {
+			cols = append(cols, i)
+		}
+	}
+
+	// Measure the values as they will actually be printed, truncated but not yet painted.
+	header := make([]string, len(t.cols))
+	texts := make([][]string, len(t.rows))
+	widths := make([]int, len(t.cols))
+	for _, i := range cols {
+		header[i] = t.cols[i].Name
+		widths[i] = utf8.RuneCountInString(header[i])
+	}
+	for r, row := range t.rows {
+		texts[r] = make([]string, len(t.cols))
+		for _, i := range cols {
+			texts[r][i] = truncate(row[i].Text)
+			if n := utf8.RuneCountInString(texts[r][i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	line := func(vals []string, colors []cell) string {
+		var b strings.Builder
+		for j, i := range cols {
+			v := vals[i]
+			if colors != nil {
+				b.WriteString(paint(os.Stdout, v, colors[i].Color))
+			} else {
+				b.WriteString(v)
+			}
+			if j < len(cols)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+				b.WriteString(columnGap)
+			}
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+	fmt.Fprintln(stdout, line(header, nil))
+	for r, row := range t.rows {
+		fmt.Fprintln(stdout, line(texts[r], row))
+	}
+}
+
+// printJSON writes v to stdout as indented JSON, for consumption by jq and other automation.
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(b))
+	return err
+}
+
+// printCSV writes a header and rows to stdout as CSV, for dropping into spreadsheets.
+func printCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// csvDate formats a due date for CSV output, leaving it empty if there is none.
+func csvDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// checkOutputFormat validates an --output flag against the formats a command supports.
+func checkOutputFormat(format string, allowed ...string) error {
+	for _, a := range allowed {
+		if format == a {
+			return nil
+		}
+	}
+	return errors.Errorf("unrecognized output format %q; expected %s", format, strings.Join(allowed, ", "))
+}
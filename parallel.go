@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+)
+
+// concurrency bounds how many repos are queried at once.
+var concurrency = ghmm.DefaultConcurrency
+
+// parallel calls fn for every index in [0, n), using at most concurrency workers at a time. Callers should
+// store results by index so that they can be merged deterministically. If any calls fail, the error from the
+// lowest failing index is returned.
+func parallel(n int, fn func(i int) error) error {
+	return ghmm.Parallel(concurrency, n, fn)
+}
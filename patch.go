@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// patchOptions controls how patch creates a patch release milestone.
+type patchOptions struct {
+	Due   string   // the due date: 1/2/2006, or relative to today, like +1w.
+	Label []string // roll open issues in the parent milestone with any of these labels into the patch.
+}
+
+func doPatchMilestone(orgOrRepo, parent string, opts patchOptions) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	title, err := nextTitle(parent, "patch")
+	if err != nil {
+		return err
+	}
+	due, err := parseDueDate(opts.Due)
+	if err != nil {
+		return err
+	}
+	dueOn := due.From(time.Time{})
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	// Plan to create the patch milestone in just the repos that have its parent, linking back to it.
+	var p plan
+	parents := make(map[repo]*github.Milestone)
+	for _, rs := range snap.Repos {
+		pm := rs.Milestone(parent)
+		if pm == nil {
+			continue
+		}
+		if existing := rs.MilestonesTitled(title); len(existing) > 0 {
+			warnRepo(rs.Repo, fmt.Sprintf("milestone %s (#%d) already exists in repo %s; skipping it",
+				title, existing[0].GetNumber(), rs.Repo), "milestone %s already exists in %s", title)
+			continue
+		}
+		parents[rs.Repo] = pm
+		p.Create(rs.Repo, title, milestoneFields{
+			State:       "open",
+			DueOn:       dueOn,
+			Description: fmt.Sprintf("Patch release for %s: %s", parent, pm.GetHTMLURL()),
+		})
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	// Roll the requested labeled issues from each parent milestone into its new patch milestone.
+	var moved int
+	if len(opts.Label) > 0 {
+		for _, c := range p.Changes {
+			n, err := moveIssues(gh, c.Repo, parents[c.Repo], "open", c.Number, c.Title, func(iss *github.Issue) bool {
+				return hasAnyLabel(iss, opts.Label)
+			})
+			if err != nil {
+				return err
+			}
+			moved += n
+		}
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("opened %d %s milestones and moved %d issues into them", c, title, moved)
+		} else {
+			fmt.Fprintf(stdout, "would open %d %s milestones and move %d issues into them; "+
+				"re-run with --yes to do so\n", c, title, moved)
+		}
+	}
+	return nil
+}
+
+// moveIssues moves the issues and PRs in the given state (open, closed, or all) in a milestone that satisfy
+// the filter (or all of them, if it is nil) into another milestone, returning how many were (or, in a
+// dry-run, would be) moved.
+func moveIssues(gh *github.Client, r repo, from *github.Milestone, state string, to int, toTitle string,
+	filter func(*github.Issue) bool) (int, error) {
+	issues, err := ghmmClient(gh).ListMilestoneIssues(context.Background(), r, from.GetNumber(), state)
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int
+	for _, iss := range issues {
+		if filter != nil && !filter(iss) {
+			continue
+		}
+		if applying() {
+			req := &github.IssueRequest{Milestone: &to}
+			if _, _, err = gh.Issues.Edit(context.Background(), r.Owner(), r.Name(), iss.GetNumber(), req); err != nil {
+				return moved, errors.Wrapf(err, "moving issue %s#%d to milestone %s", r, iss.GetNumber(), toTitle)
+			}
+			fmt.Fprintf(stdout, "moved issue %s#%d from milestone %s to %s\n",
+				r, iss.GetNumber(), from.GetTitle(), toTitle)
+		} else {
+			fmt.Fprintf(stdout, "would move issue %s#%d from milestone %s to %s\n",
+				r, iss.GetNumber(), from.GetTitle(), toTitle)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// hasAnyLabel returns true if the issue carries any of the given labels, ignoring case.
+func hasAnyLabel(iss *github.Issue, labels []string) bool {
+	for _, l := range iss.Labels {
+		for _, want := range labels {
+			if strings.EqualFold(l.GetName(), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+var (
+	permsMu sync.Mutex
+	// repoPerms records the token's permissions on repos we have already seen during discovery, so that
+	// access checks need not query them again.
+	repoPerms = make(map[repo]map[string]bool)
+)
+
+// rememberPerms records the permissions reported for a repository, if any.
+func rememberPerms(r repo, gr *github.Repository) {
+	if gr.Permissions == nil {
+		return
+	}
+	permsMu.Lock()
+	defer permsMu.Unlock()
+	repoPerms[r] = *gr.Permissions
+}
+
+// canWrite returns true if the given permissions are sufficient to create and edit milestones.
+func canWrite(perms map[string]bool) bool {
+	return perms["admin"] || perms["maintain"] || perms["push"]
+}
+
+// checkWriteAccess verifies, before anything is mutated, that the token may edit milestones in every
+// one of the given repos. This avoids discovering 403s halfway through a bulk edit and leaving an org
+// half-updated. When committing changes, any inaccessible repo is an error; during a dry-run, they are
+// merely reported as warnings.
+func checkWriteAccess(gh *github.Client, repos []repo) error {
+	isDenied := make([]bool, len(repos))
+	err := parallel(len(repos), func(i int) error {
+		r := repos[i]
+		permsMu.Lock()
+		perms, ok := repoPerms[r]
+		permsMu.Unlock()
+		if !ok {
+			gr, _, err := gh.Repositories.Get(context.Background(), r.Owner(), r.Name())
+			if err != nil {
+				if skipUnauthorized(err, r) {
+					return nil
+				}
+				return errors.Wrapf(err, "checking permissions for repo %s", r)
+			}
+			rememberPerms(r, gr)
+			if gr.Permissions != nil {
+				perms = *gr.Permissions
+			}
+		}
+		isDenied[i] = !canWrite(perms)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+	for i, r := range repos {
+		if isDenied[i] {
+			denied = append(denied, string(r))
+		}
+	}
+
+	if len(denied) > 0 {
+		if applying() {
+			return errors.Errorf("the token lacks write access to %d repos; nothing was changed: %s",
+				len(denied), strings.Join(denied, ", "))
+		}
+		warnf("the token lacks write access to %d repos, so changes to them would fail: %s",
+			len(denied), strings.Join(denied, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package ghmm manages GitHub milestones across many repos at once. It discovers an org's repos, lists and
+// edits their milestones, and detects drift between like-titled milestones in different repos. The ghmm
+// command is a thin wrapper around it.
+package ghmm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// DefaultConcurrency is how many requests a Client makes at once unless told otherwise.
+const DefaultConcurrency = 8
+
+// SSOHeader is the header GitHub uses to signal that a token has not been authorized for an org that
+// enforces SAML single sign-on.
+const SSOHeader = "X-GitHub-SSO"
+
+// Client manages milestones using a GitHub API client. Its methods are safe for concurrent use.
+type Client struct {
+	// GitHub is the API client used for every request.
+	GitHub *github.Client
+	// Concurrency bounds how many repos are queried at once.
+	Concurrency int
+	// Warnf, if set, is called with non-fatal problems, such as listings that may be incomplete.
+	Warnf func(format string, args ...interface{})
+}
+
+// NewClient returns a Client that makes requests with the given GitHub API client.
+func NewClient(gh *github.Client) *Client {
+	return &Client{GitHub: gh, Concurrency: DefaultConcurrency}
+}
+
+func (c *Client) warnf(format string, args ...interface{}) {
+	if c.Warnf != nil {
+		c.Warnf(format, args...)
+	}
+}
+
+// WarnPartialResults warns if GitHub omitted results from a listing because the token lacks SAML SSO
+// authorization for some of the orgs involved.
+func (c *Client) WarnPartialResults(resp *github.Response, what string) {
+	if resp == nil {
+		return
+	}
+	if h := resp.Header.Get(SSOHeader); strings.HasPrefix(h, "partial-results") {
+		c.warnf("%s may be incomplete: the token is not authorized for SAML single sign-on in every org", what)
+	}
+}
+
+// Parallel calls fn for every index in [0, n), using at most the given number of workers at a time. Callers
+// should store results by index so that they can be merged deterministically. If any calls fail, the error
+// from the lowest failing index is returned.
+func Parallel(workers, n int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	errs := make([]error, n)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
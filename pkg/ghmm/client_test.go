@@ -0,0 +1,102 @@
+package ghmm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// newTestClient returns a Client whose requests are served by the given handler, and the server, which the
+// caller must close.
+func newTestClient(t *testing.T, h http.HandlerFunc) (*Client, *httptest.Server) {
+	srv := httptest.NewServer(h)
+	gh := github.NewClient(nil)
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh.BaseURL = u
+	return NewClient(gh), srv
+}
+
+func TestParallel(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		var running, most int32
+		results := make([]int, 20)
+		err := Parallel(workers, len(results), func(i int) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			results[i] = i * i
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		for i, r := range results {
+			if r != i*i {
+				t.Fatalf("%d workers: result %d is %d", workers, i, r)
+			}
+		}
+		if limit := int32(workers); limit > 0 && most > limit {
+			t.Errorf("%d workers: %d calls ran at once", workers, most)
+		}
+	}
+}
+
+func TestParallelReturnsLowestError(t *testing.T) {
+	err := Parallel(4, 10, func(i int) error {
+		if i == 3 || i == 7 {
+			return fmt.Errorf("call %d failed", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "call 3 failed" {
+		t.Errorf("got %v, want the error from call 3", err)
+	}
+	if err = Parallel(4, 0, func(int) error { return errors.New("called") }); err != nil {
+		t.Errorf("with no calls: got %v", err)
+	}
+}
+
+func TestWarnPartialResults(t *testing.T) {
+	tests := []struct {
+		header string
+		warned bool
+	}{
+		{header: "", warned: false},
+		{header: "partial-results; organizations=123,456", warned: true},
+		{header: "required; url=https://github.com/orgs/acme/sso", warned: false},
+	}
+	for _, test := range tests {
+		var warnings []string
+		c := &Client{Warnf: func(format string, args ...interface{}) {
+			warnings = append(warnings, fmt.Sprintf(format, args...))
+		}}
+		resp := &github.Response{Response: &http.Response{Header: http.Header{}}}
+		if test.header != "" {
+			resp.Header.Set(SSOHeader, test.header)
+		}
+		c.WarnPartialResults(resp, "repo list for org acme")
+		if got := len(warnings) > 0; got != test.warned {
+			t.Errorf("header %q: warned %v, want %v (%v)", test.header, got, test.warned, warnings)
+		}
+	}
+
+	// A nil response, or a client without Warnf, is fine.
+	(&Client{}).WarnPartialResults(nil, "anything")
+	(&Client{}).WarnPartialResults(&github.Response{Response: &http.Response{Header: http.Header{
+		SSOHeader: {"partial-results"},
+	}}}, "anything")
+}
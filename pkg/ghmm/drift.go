@@ -0,0 +1,111 @@
+package ghmm
+
+import (
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// RepoMilestones holds the milestones of a single repo.
+type RepoMilestones struct {
+	Repo       Repo
+	Milestones []*github.Milestone
+}
+
+// DriftKind says how a milestone in one repo has drifted from its like-titled counterparts in the others.
+type DriftKind string
+
+const (
+	// DuplicateDrift means several of a repo's milestones share a title.
+	DuplicateDrift DriftKind = "duplicate"
+	// StateDrift means the milestone's state differs from that in the repos seen before it.
+	StateDrift DriftKind = "state"
+	// DueDrift means the milestone's due date differs from that in the repos seen before it.
+	DueDrift DriftKind = "due"
+	// MissingDrift means a repo that is expected to have the milestone doesn't.
+	MissingDrift DriftKind = "missing"
+	// UnexpectedDrift means a repo that isn't expected to have the milestone (one outside of the release
+	// train, say) does.
+	UnexpectedDrift DriftKind = "unexpected"
+)
+
+// Drift is a single disagreement between a repo's milestone and the rest of the repos.
+type Drift struct {
+	Kind  DriftKind
+	Repo  Repo
+	Title string
+
+	// For duplicates, the like-titled milestones.
+	Duplicates []*github.Milestone
+	// For state and due date drift, the repo's values, the values expected of it, and the repos seen so
+	// far that have the expected values.
+	State, ExpectState string
+	DueOn, ExpectDueOn time.Time
+	Others             []Repo
+}
+
+// DetectDrift compares the like-titled milestones across repos, reporting any drift between them:
+// duplicate titles within a repo, states or due dates that disagree, and milestones that are missing
+// from some repos. The first repo with a given milestone sets the expected state and due date. If
+// expected isn't nil, it says which repos should have every milestone (e.g., those in a release train),
+// and milestones in any other repo are reported too; otherwise, every repo should have every milestone.
+func DetectDrift(repos []*RepoMilestones, expected func(Repo) bool) []*Drift {
+	if expected == nil {
+		expected = func(Repo) bool { return true }
+	}
+
+	type first struct {
+		state string
+		dueOn time.Time
+		repos []Repo
+		have  map[Repo]bool
+	}
+	var drifts []*Drift
+	var titles []string
+	firsts := make(map[string]*first)
+	for _, rm := range repos {
+		r := rm.Repo
+		seen := make(map[string][]*github.Milestone)
+		for _, m := range rm.Milestones {
+			seen[m.GetTitle()] = append(seen[m.GetTitle()], m)
+		}
+		for _, m := range rm.Milestones {
+			t, s, d := m.GetTitle(), m.GetState(), m.GetDueOn()
+			if matches := seen[t]; len(matches) > 1 {
+				// Report each set of duplicates just once, on the second of them.
+				if matches[1] == m {
+					drifts = append(drifts, &Drift{Kind: DuplicateDrift, Repo: r, Title: t, Duplicates: matches})
+				}
+				if matches[0] != m {
+					continue
+				}
+			}
+			f, ok := firsts[t]
+			if !ok {
+				firsts[t] = &first{state: s, dueOn: d, repos: []Repo{r}, have: map[Repo]bool{r: true}}
+				titles = append(titles, t)
+				continue
+			}
+			if f.state != s {
+				drifts = append(drifts, &Drift{Kind: StateDrift, Repo: r, Title: t,
+					State: s, ExpectState: f.state, Others: append([]Repo(nil), f.repos...)})
+			} else if !f.dueOn.Equal(d) {
+				drifts = append(drifts, &Drift{Kind: DueDrift, Repo: r, Title: t,
+					DueOn: d, ExpectDueOn: f.dueOn, Others: append([]Repo(nil), f.repos...)})
+			}
+			f.repos = append(f.repos, r)
+			f.have[r] = true
+		}
+	}
+
+	for _, t := range titles {
+		for _, rm := range repos {
+			if in, has := expected(rm.Repo), firsts[t].have[rm.Repo]; in && !has {
+				drifts = append(drifts, &Drift{Kind: MissingDrift, Repo: rm.Repo, Title: t})
+			} else if !in && has {
+				drifts = append(drifts, &Drift{Kind: UnexpectedDrift, Repo: rm.Repo, Title: t})
+			}
+		}
+	}
+	return drifts
+}
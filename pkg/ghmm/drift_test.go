@@ -0,0 +1,109 @@
+package ghmm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+func testMilestone(number int, title, state string, due time.Time) *github.Milestone {
+	m := &github.Milestone{Number: &number, Title: &title, State: &state}
+	if !due.IsZero() {
+		m.DueOn = &due
+	}
+	return m
+}
+
+func TestDetectDrift(t *testing.T) {
+	jul := time.Date(2019, 7, 1, 7, 0, 0, 0, time.UTC)
+	aug := time.Date(2019, 8, 1, 7, 0, 0, 0, time.UTC)
+	// The same instant in another zone is not drift.
+	julLA := jul.In(time.FixedZone("PDT", -7*60*60))
+
+	type drift struct {
+		Kind  DriftKind
+		Repo  Repo
+		Title string
+	}
+	tests := []struct {
+		name     string
+		repos    []*RepoMilestones
+		expected func(Repo) bool
+		want     []drift
+	}{
+		{
+			name: "consistent",
+			repos: []*RepoMilestones{
+				{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+				{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(4, "M1", "open", julLA)}},
+			},
+		},
+		{
+			name: "state and due date",
+			repos: []*RepoMilestones{
+				{Repo: "acme/a", Milestones: []*github.Milestone{
+					testMilestone(1, "M1", "open", jul), testMilestone(2, "M2", "open", aug)}},
+				{Repo: "acme/b", Milestones: []*github.Milestone{
+					testMilestone(1, "M1", "closed", jul), testMilestone(2, "M2", "open", jul)}},
+			},
+			want: []drift{{StateDrift, "acme/b", "M1"}, {DueDrift, "acme/b", "M2"}},
+		},
+		{
+			name: "missing",
+			repos: []*RepoMilestones{
+				{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+				{Repo: "acme/b"},
+			},
+			want: []drift{{MissingDrift, "acme/b", "M1"}},
+		},
+		{
+			name: "duplicates",
+			repos: []*RepoMilestones{
+				{Repo: "acme/a", Milestones: []*github.Milestone{
+					testMilestone(1, "M1", "open", jul), testMilestone(2, "M1", "closed", aug)}},
+			},
+			want: []drift{{DuplicateDrift, "acme/a", "M1"}},
+		},
+		{
+			name: "release train",
+			repos: []*RepoMilestones{
+				{Repo: "acme/cli", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+				{Repo: "acme/sdk"},
+				{Repo: "acme/docs", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+			},
+			expected: func(r Repo) bool { return r != "acme/docs" },
+			want:     []drift{{MissingDrift, "acme/sdk", "M1"}, {UnexpectedDrift, "acme/docs", "M1"}},
+		},
+	}
+	for _, test := range tests {
+		var got []drift
+		for _, d := range DetectDrift(test.repos, test.expected) {
+			got = append(got, drift{d.Kind, d.Repo, d.Title})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDetectDriftDetails(t *testing.T) {
+	jul := time.Date(2019, 7, 1, 7, 0, 0, 0, time.UTC)
+	aug := time.Date(2019, 8, 1, 7, 0, 0, 0, time.UTC)
+	drifts := DetectDrift([]*RepoMilestones{
+		{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+		{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul)}},
+		{Repo: "acme/c", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", aug)}},
+	}, nil)
+	if len(drifts) != 1 {
+		t.Fatalf("got %d drifts, want 1", len(drifts))
+	}
+	d := drifts[0]
+	if !d.DueOn.Equal(aug) || !d.ExpectDueOn.Equal(jul) {
+		t.Errorf("got due date %v, expected %v", d.DueOn, d.ExpectDueOn)
+	}
+	if !reflect.DeepEqual(d.Others, []Repo{"acme/a", "acme/b"}) {
+		t.Errorf("got others %v", d.Others)
+	}
+}
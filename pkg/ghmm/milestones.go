@@ -0,0 +1,153 @@
+package ghmm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// MilestoneFields are the mutable fields of a milestone.
+type MilestoneFields struct {
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	DueOn       time.Time `json:"dueOn"`
+	Description string    `json:"description,omitempty"`
+}
+
+// FieldsOf returns the current values of a milestone's mutable fields. Edits start from these, changing
+// just the fields they are concerned with.
+func FieldsOf(m *github.Milestone) MilestoneFields {
+	return MilestoneFields{
+		Title:       m.GetTitle(),
+		State:       m.GetState(),
+		DueOn:       m.GetDueOn(),
+		Description: m.GetDescription(),
+	}
+}
+
+// Equal returns true if both sets of fields have the same values, regardless of time zone.
+func (f MilestoneFields) Equal(other MilestoneFields) bool {
+	return f.Title == other.Title && f.State == other.State && f.DueOn.Equal(other.DueOn) &&
+		f.Description == other.Description
+}
+
+// ListMilestones returns all of a repo's milestones in the given state (open, closed, or all), fetching
+// every page of them.
+func (c *Client) ListMilestones(ctx context.Context, r Repo, state string) ([]*github.Milestone, error) {
+	var all []*github.Milestone
+	opts := &github.MilestoneListOptions{State: state, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		ms, resp, err := c.GitHub.Issues.ListMilestones(ctx, r.Owner(), r.Name(), opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing milestones for repo %s", r)
+		}
+		all = append(all, ms...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListMilestoneIssues returns the issues and PRs in the given state within a milestone.
+func (c *Client) ListMilestoneIssues(ctx context.Context, r Repo, number int, state string) ([]*github.Issue, error) {
+	var issues []*github.Issue
+	opts := &github.IssueListByRepoOptions{Milestone: strconv.Itoa(number), State: state}
+	for {
+		is, resp, err := c.GitHub.Issues.ListByRepo(ctx, r.Owner(), r.Name(), opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing issues in milestone #%d of repo %s", number, r)
+		}
+		issues = append(issues, is...)
+		if resp.NextPage == 0 {
+			return issues, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// CreateMilestone creates a milestone with the given fields. A zero due date or empty description is
+// left unset.
+func (c *Client) CreateMilestone(ctx context.Context, r Repo, fields MilestoneFields) (*github.Milestone, error) {
+	m := &github.Milestone{Title: &fields.Title, State: &fields.State}
+	if !fields.DueOn.IsZero() {
+		m.DueOn = &fields.DueOn
+	}
+	if fields.Description != "" {
+		m.Description = &fields.Description
+	}
+	res, _, err := c.GitHub.Issues.CreateMilestone(ctx, r.Owner(), r.Name(), m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening milestone %s in repo %s", fields.Title, r)
+	}
+	return res, nil
+}
+
+// EditMilestone changes a milestone's fields from their old values to the new ones. Only the fields that
+// differ are sent, so that concurrent edits to the others aren't clobbered. go-github can't clear a
+// milestone's due date, since it omits nil fields, so the edit is made by hand.
+func (c *Client) EditMilestone(ctx context.Context, r Repo, number int, old, new MilestoneFields) error {
+	body := make(map[string]interface{})
+	if old.Title != new.Title {
+		body["title"] = new.Title
+	}
+	if old.State != new.State {
+		body["state"] = new.State
+	}
+	if !old.DueOn.Equal(new.DueOn) {
+		if new.DueOn.IsZero() {
+			body["due_on"] = nil
+		} else {
+			body["due_on"] = new.DueOn
+		}
+	}
+	if old.Description != new.Description {
+		body["description"] = new.Description
+	}
+	req, err := c.GitHub.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/milestones/%d", r.Owner(), r.Name(), number),
+		body)
+	if err != nil {
+		return err
+	}
+	if _, err = c.GitHub.Do(ctx, req, nil); err != nil {
+		return errors.Wrapf(err, "editing milestone %s (#%d) in repo %s", old.Title, number, r)
+	}
+	return nil
+}
+
+// DeleteMilestone deletes a milestone. GitHub removes it from any issues and PRs still in it.
+func (c *Client) DeleteMilestone(ctx context.Context, r Repo, number int) error {
+	if _, err := c.GitHub.Issues.DeleteMilestone(ctx, r.Owner(), r.Name(), number); err != nil {
+		return errors.Wrapf(err, "deleting milestone #%d in repo %s", number, r)
+	}
+	return nil
+}
+
+// UnassignMilestone removes every issue and PR from a milestone.
+func (c *Client) UnassignMilestone(ctx context.Context, r Repo, number int) error {
+	issues, err := c.ListMilestoneIssues(ctx, r, number, "all")
+	if err != nil {
+		return err
+	}
+	return Parallel(c.Concurrency, len(issues), func(i int) error {
+		return c.ClearIssueMilestone(ctx, r, issues[i].GetNumber())
+	})
+}
+
+// ClearIssueMilestone removes an issue or PR from whatever milestone it is in. go-github can't clear an
+// issue's milestone, since it omits nil fields, so the edit is made by hand.
+func (c *Client) ClearIssueMilestone(ctx context.Context, r Repo, n int) error {
+	req, err := c.GitHub.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", r.Owner(), r.Name(), n),
+		map[string]interface{}{"milestone": nil})
+	if err != nil {
+		return err
+	}
+	if _, err = c.GitHub.Do(ctx, req, nil); err != nil {
+		return errors.Wrapf(err, "removing issue #%d in repo %s from its milestone", n, r)
+	}
+	return nil
+}
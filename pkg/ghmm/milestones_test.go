@@ -0,0 +1,137 @@
+package ghmm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMilestoneFieldsEqual(t *testing.T) {
+	due := time.Date(2019, 7, 1, 7, 0, 0, 0, time.UTC)
+	f := MilestoneFields{Title: "M1", State: "open", DueOn: due, Description: "d"}
+	if !f.Equal(f) {
+		t.Error("identical fields are not equal")
+	}
+	other := f
+	other.DueOn = due.In(time.FixedZone("PDT", -7*60*60))
+	if !f.Equal(other) {
+		t.Error("the same due date in another zone is not equal")
+	}
+	for _, other := range []MilestoneFields{
+		{Title: "M2", State: "open", DueOn: due, Description: "d"},
+		{Title: "M1", State: "closed", DueOn: due, Description: "d"},
+		{Title: "M1", State: "open", Description: "d"},
+		{Title: "M1", State: "open", DueOn: due},
+	} {
+		if f.Equal(other) {
+			t.Errorf("%+v equals %+v", f, other)
+		}
+	}
+
+	m := testMilestone(3, "M1", "open", due)
+	m.Description = &f.Description
+	if got := FieldsOf(m); !got.Equal(f) {
+		t.Errorf("FieldsOf = %+v, want %+v", got, f)
+	}
+}
+
+func TestEditMilestone(t *testing.T) {
+	jul := time.Date(2019, 7, 1, 7, 0, 0, 0, time.UTC)
+	aug := time.Date(2019, 8, 1, 7, 0, 0, 0, time.UTC)
+	old := MilestoneFields{Title: "M1", State: "open", DueOn: jul, Description: "d"}
+	tests := []struct {
+		name string
+		new  MilestoneFields
+		want map[string]interface{}
+	}{
+		{
+			name: "nothing",
+			new:  old,
+			want: map[string]interface{}{},
+		},
+		{
+			name: "title and state",
+			new:  MilestoneFields{Title: "M2", State: "closed", DueOn: jul, Description: "d"},
+			want: map[string]interface{}{"title": "M2", "state": "closed"},
+		},
+		{
+			name: "due date",
+			new:  MilestoneFields{Title: "M1", State: "open", DueOn: aug, Description: "d"},
+			want: map[string]interface{}{"due_on": "2019-08-01T07:00:00Z"},
+		},
+		{
+			name: "clearing the due date and description",
+			new:  MilestoneFields{Title: "M1", State: "open"},
+			want: map[string]interface{}{"due_on": nil, "description": ""},
+		},
+	}
+	for _, test := range tests {
+		var got map[string]interface{}
+		c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PATCH" || r.URL.Path != "/repos/acme/a/milestones/7" {
+				http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "{}")
+		})
+		err := c.EditMilestone(context.Background(), "acme/a", 7, old, test.new)
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: sent %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEditMilestoneError(t *testing.T) {
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Validation Failed"}`, http.StatusUnprocessableEntity)
+	})
+	defer srv.Close()
+	old := MilestoneFields{Title: "M1", State: "open"}
+	err := c.EditMilestone(context.Background(), "acme/a", 7, old, MilestoneFields{Title: "M2", State: "open"})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if want := "editing milestone M1 (#7) in repo acme/a"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %q", err)
+	}
+}
+
+func TestListMilestones(t *testing.T) {
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "all" {
+			t.Errorf("listed %s milestones, want all", got)
+		}
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=2>; rel="next"`, r.Host, r.URL.Path))
+			fmt.Fprint(w, `[{"number": 1, "title": "M1"}, {"number": 2, "title": "M2"}]`)
+		case "2":
+			fmt.Fprint(w, `[{"number": 3, "title": "M3"}]`)
+		}
+	})
+	defer srv.Close()
+
+	ms, err := c.ListMilestones(context.Background(), "acme/a", "all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, m := range ms {
+		titles = append(titles, m.GetTitle())
+	}
+	if want := []string{"M1", "M2", "M3"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("got %v, want %v", titles, want)
+	}
+}
@@ -0,0 +1,117 @@
+package ghmm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// Repo names a repo in owner/name form.
+type Repo string
+
+// Owner returns the org or user that owns the repo.
+func (r Repo) Owner() string {
+	s := string(r)
+	return s[:strings.Index(s, "/")]
+}
+
+// Name returns the repo's name within its owner.
+func (r Repo) Name() string {
+	s := string(r)
+	return s[strings.Index(s, "/")+1:]
+}
+
+// ParseRepo validates that s names a repo in owner/name form.
+func ParseRepo(s string) (Repo, error) {
+	ix := strings.Index(s, "/")
+	if ix <= 0 || ix == len(s)-1 || strings.Count(s, "/") != 1 {
+		return "", errors.Errorf("malformed repo %q; expected owner/name", s)
+	}
+	return Repo(s), nil
+}
+
+// QualifyRepo turns a bare repo name into an owner/name one, using the given org as the owner.
+func QualifyRepo(org, name string) Repo {
+	if _, err := ParseRepo(name); err == nil {
+		return Repo(name)
+	}
+	return Repo(org + "/" + name)
+}
+
+// RepoListOptions controls how an org's repos are listed.
+type RepoListOptions struct {
+	Type    string // which repos to list: all, public, private, forks, sources, or member.
+	Sort    string // the order to list them in: created, updated, pushed, or full_name.
+	PerPage int    // how many repos to fetch per request, up to 100.
+}
+
+// DefaultRepoListOptions lists every repo, by name, 100 at a time.
+var DefaultRepoListOptions = RepoListOptions{Type: "all", Sort: "full_name", PerPage: 100}
+
+// Check validates the options.
+func (opts RepoListOptions) Check() error {
+	switch opts.Type {
+	case "all", "public", "private", "forks", "sources", "member":
+	default:
+		return errors.Errorf("unrecognized repo type %q; expected all, public, private, forks, sources, or member",
+			opts.Type)
+	}
+	switch opts.Sort {
+	case "created", "updated", "pushed", "full_name":
+	default:
+		return errors.Errorf("unrecognized repo sort %q; expected created, updated, pushed, or full_name", opts.Sort)
+	}
+	if opts.PerPage < 1 || opts.PerPage > 100 {
+		return errors.Errorf("repos per page must be between 1 and 100, not %d", opts.PerPage)
+	}
+	return nil
+}
+
+// ListOrgRepos lists an org's repos. The first page says how many pages there are, so the rest are then
+// fetched in parallel. go-github doesn't support sorting this list, so the requests are made by hand.
+func (c *Client) ListOrgRepos(ctx context.Context, org string, opts RepoListOptions) ([]*github.Repository, error) {
+	if err := opts.Check(); err != nil {
+		return nil, err
+	}
+	listPage := func(page int) ([]*github.Repository, *github.Response, error) {
+		u := fmt.Sprintf("orgs/%s/repos?type=%s&sort=%s&per_page=%d&page=%d",
+			org, opts.Type, opts.Sort, opts.PerPage, page)
+		req, err := c.GitHub.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var rs []*github.Repository
+		resp, err := c.GitHub.Do(ctx, req, &rs)
+		if err != nil {
+			return nil, resp, errors.Wrapf(err, "listing repos by org %s", org)
+		}
+		c.WarnPartialResults(resp, "repo list for org "+org)
+		return rs, resp, nil
+	}
+
+	first, resp, err := listPage(1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.LastPage <= 1 {
+		return first, nil
+	}
+
+	pages := make([][]*github.Repository, resp.LastPage-1)
+	err = Parallel(c.Concurrency, len(pages), func(i int) error {
+		var err error
+		pages[i], _, err = listPage(i + 2)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	all := first
+	for _, page := range pages {
+		all = append(all, page...)
+	}
+	return all, nil
+}
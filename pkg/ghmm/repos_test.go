@@ -0,0 +1,92 @@
+package ghmm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseRepo(t *testing.T) {
+	for _, s := range []string{"acme/cli", "a/b"} {
+		r, err := ParseRepo(s)
+		if err != nil || string(r) != s {
+			t.Errorf("ParseRepo(%q) = %q, %v", s, r, err)
+		}
+	}
+	for _, s := range []string{"", "acme", "/cli", "acme/", "acme/cli/extra"} {
+		if _, err := ParseRepo(s); err == nil {
+			t.Errorf("ParseRepo(%q) succeeded", s)
+		}
+	}
+
+	r := Repo("acme/cli")
+	if r.Owner() != "acme" || r.Name() != "cli" {
+		t.Errorf("got owner %q and name %q", r.Owner(), r.Name())
+	}
+	if got := QualifyRepo("acme", "cli"); got != "acme/cli" {
+		t.Errorf("QualifyRepo(acme, cli) = %s", got)
+	}
+	if got := QualifyRepo("acme", "partner/plugin"); got != "partner/plugin" {
+		t.Errorf("QualifyRepo(acme, partner/plugin) = %s", got)
+	}
+}
+
+func TestRepoListOptionsCheck(t *testing.T) {
+	if err := DefaultRepoListOptions.Check(); err != nil {
+		t.Errorf("the default options are invalid: %v", err)
+	}
+	for _, opts := range []RepoListOptions{
+		{Type: "mine", Sort: "full_name", PerPage: 100},
+		{Type: "all", Sort: "stars", PerPage: 100},
+		{Type: "all", Sort: "full_name", PerPage: 0},
+		{Type: "all", Sort: "full_name", PerPage: 101},
+	} {
+		if err := opts.Check(); err == nil {
+			t.Errorf("%+v is valid", opts)
+		}
+	}
+}
+
+func TestListOrgRepos(t *testing.T) {
+	const pages = 4
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/orgs/acme/repos" || q.Get("sort") != "full_name" || q.Get("per_page") != "2" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		if page == 1 {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=2>; rel="next", <http://%s%s?page=%d>; rel="last"`,
+				r.Host, r.URL.Path, r.Host, r.URL.Path, pages))
+			w.Header().Set(SSOHeader, "partial-results; organizations=1")
+		}
+		fmt.Fprintf(w, `[{"full_name": "acme/r%d-a"}, {"full_name": "acme/r%d-b"}]`, page, page)
+	})
+	defer srv.Close()
+	var warnings []string
+	c.Warnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	opts := DefaultRepoListOptions
+	opts.PerPage = 2
+	repos, err := c.ListOrgRepos(context.Background(), "acme", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.GetFullName())
+	}
+	if !sort.StringsAreSorted(names) || len(names) != 2*pages {
+		t.Errorf("got repos %v, want %d in order", names, 2*pages)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "repo list for org acme may be incomplete") {
+		t.Errorf("got warnings %v", warnings)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// changeKind says what a planned change does to a milestone.
+type changeKind string
+
+const (
+	createChange changeKind = "create" // create a new milestone.
+	editChange   changeKind = "edit"   // edit an existing milestone's fields.
+	deleteChange changeKind = "delete" // delete an existing milestone.
+)
+
+// milestoneFields are the mutable fields of a milestone. In an edit, only the fields that differ between
+// the old and new values are changed.
+type milestoneFields = ghmm.MilestoneFields
+
+// fieldsOf returns the current values of a milestone's mutable fields.
+var fieldsOf = ghmm.FieldsOf
+
+// change is a single planned mutation of a milestone in one repo.
+type change struct {
+	Kind   changeKind      `json:"kind"`
+	Repo   repo            `json:"repo"`
+	Number int             `json:"number,omitempty"` // the milestone's number, for edits.
+	Title  string          `json:"title"`            // the milestone's title, before any edit.
+	Old    milestoneFields `json:"old"`
+	New    milestoneFields `json:"new"`
+
+	// For deletions, the number of issues and PRs still in the milestone, and whether to remove them from
+	// it first rather than leaving GitHub to do so implicitly.
+	Issues   int  `json:"issues,omitempty"`
+	Unassign bool `json:"unassign,omitempty"`
+}
+
+// plan is the set of changes a command intends to make, computed against a snapshot.
+type plan struct {
+	Changes []*change
+}
+
+// Merge adds the changes planned in another plan to this one.
+func (p *plan) Merge(o *plan) {
+	p.Changes = append(p.Changes, o.Changes...)
+}
+
+// Create plans the creation of a new milestone in the given repo.
+func (p *plan) Create(r repo, title string, fields milestoneFields) {
+	fields.Title = title
+	p.Changes = append(p.Changes, &change{Kind: createChange, Repo: r, Title: title, New: fields})
+}
+
+// Edit plans an edit of an existing milestone, changing its fields to the new values. It returns false,
+// planning nothing, if the milestone already has those values.
+func (p *plan) Edit(r repo, m *github.Milestone, fields milestoneFields) bool {
+	old := fieldsOf(m)
+	if old.Equal(fields) {
+		return false
+	}
+	p.Changes = append(p.Changes, &change{
+		Kind:   editChange,
+		Repo:   r,
+		Number: m.GetNumber(),
+		Title:  m.GetTitle(),
+		Old:    old,
+		New:    fields,
+	})
+	return true
+}
+
+// Delete plans the deletion of an existing milestone, first removing it from any issues and PRs still in
+// it if unassign is true.
+func (p *plan) Delete(r repo, m *github.Milestone, unassign bool) {
+	p.Changes = append(p.Changes, &change{
+		Kind:     deleteChange,
+		Repo:     r,
+		Number:   m.GetNumber(),
+		Title:    m.GetTitle(),
+		Old:      fieldsOf(m),
+		Issues:   m.GetOpenIssues() + m.GetClosedIssues(),
+		Unassign: unassign,
+	})
+}
+
+// Count returns the number of planned changes of the given kind.
+func (p *plan) Count(kind changeKind) int {
+	var c int
+	for _, ch := range p.Changes {
+		if ch.Kind == kind {
+			c++
+		}
+	}
+	return c
+}
+
+// Repos returns the distinct repos the plan touches, in plan order.
+func (p *plan) Repos() []repo {
+	var repos []repo
+	seen := make(map[repo]bool)
+	for _, ch := range p.Changes {
+		if !seen[ch.Repo] {
+			seen[ch.Repo] = true
+			repos = append(repos, ch.Repo)
+		}
+	}
+	return repos
+}
+
+// succeeded returns the plan's changes, less those that failed.
+func (p *plan) succeeded(failed []*failedChange) []*change {
+	didFail := make(map[*change]bool)
+	for _, fc := range failed {
+		didFail[fc.Change] = true
+	}
+	var ok []*change
+	for _, c := range p.Changes {
+		if !didFail[c] {
+			ok = append(ok, c)
+		}
+	}
+	return ok
+}
+
+// Describe renders a human-readable account of the change, as either done or (for dry-runs) to be done.
+func (c *change) Describe(done bool) string {
+	switch c.Kind {
+	case createChange:
+		if done {
+			return fmt.Sprintf("opened milestone %s (#%d) in repo %s with a due date on %v",
+				c.Title, c.Number, c.Repo, c.New.DueOn)
+		}
+		return fmt.Sprintf("would open milestone %s in repo %s with a due date on %v", c.Title, c.Repo, c.New.DueOn)
+	case editChange:
+		var diffs []string
+		if c.Old.Title != c.New.Title {
+			diffs = append(diffs, fmt.Sprintf("title from %s to %s", c.Old.Title, c.New.Title))
+		}
+		if c.Old.State != c.New.State {
+			diffs = append(diffs, fmt.Sprintf("state from %s to %s", c.Old.State, c.New.State))
+		}
+		if !c.Old.DueOn.Equal(c.New.DueOn) {
+			diffs = append(diffs, fmt.Sprintf("due date from %v to %v", c.Old.DueOn, c.New.DueOn))
+		}
+		if c.Old.Description != c.New.Description {
+			diffs = append(diffs, fmt.Sprintf("description to %q", c.New.Description))
+		}
+		verb := "changed"
+		if !done {
+			verb = "would change"
+		}
+		return fmt.Sprintf("%s milestone %s (#%d) in repo %s %s", verb, c.Title, c.Number, c.Repo,
+			strings.Join(diffs, ", "))
+	case deleteChange:
+		verb := "deleted"
+		if !done {
+			verb = "would delete"
+		}
+		var after string
+		if c.Unassign && c.Issues > 0 {
+			after = fmt.Sprintf(" after removing it from %d issues", c.Issues)
+		}
+		return fmt.Sprintf("%s milestone %s (#%d) in repo %s%s", verb, c.Title, c.Number, c.Repo, after)
+	default:
+		panic(fmt.Sprintf("unrecognized change kind %q", c.Kind))
+	}
+}
+
+// apply performs the change against GitHub.
+func (c *change) apply(gh *github.Client) error {
+	ctx, lib := context.Background(), ghmmClient(gh)
+	switch c.Kind {
+	case createChange:
+		res, err := lib.CreateMilestone(ctx, c.Repo, c.New)
+		if err != nil {
+			return err
+		}
+		c.Number = res.GetNumber()
+	case editChange:
+		return lib.EditMilestone(ctx, c.Repo, c.Number, c.Old, c.New)
+	case deleteChange:
+		if c.Unassign {
+			if err := lib.UnassignMilestone(ctx, c.Repo, c.Number); err != nil {
+				return err
+			}
+		}
+		return lib.DeleteMilestone(ctx, c.Repo, c.Number)
+	default:
+		panic(fmt.Sprintf("unrecognized change kind %q", c.Kind))
+	}
+	return nil
+}
+
+// Apply carries out the plan if applying, and otherwise just reports what it would do, as Execute does.
+func (p *plan) Apply(gh *github.Client) error {
+	return p.Execute(gh, applying())
+}
+
+// Execute carries out the plan if apply is set, and otherwise just reports what it would do. Either way,
+// write access to every affected repo is checked up front. With --post-plan, the dry-run plan is also
+// posted for approval. When applying, a failed change doesn't stop the others; the run's outcome is
+// recorded so that just the failed changes can be retried. Either way, the plan is recorded in the audit
+// log, if it is enabled. Servers that decide per request whether to apply changes call this directly.
+func (p *plan) Execute(gh *github.Client, apply bool) error {
+	if postPlan != "" && apply {
+		return errors.New("--post-plan posts a plan for approval instead of applying it; it cannot be used with --yes")
+	} else if planOut != "" && apply {
+		return errors.New("--out saves a plan to apply later instead of applying it; it cannot be used with --yes")
+	}
+	if err := checkWriteAccess(gh, p.Repos()); err != nil {
+		return err
+	}
+	if apply {
+		failed := applyChanges(gh, p.Changes)
+		if len(p.Changes) > 0 {
+			saveRunSummary(len(p.Changes)-len(failed), failed)
+			recordJournal(p.succeeded(failed))
+			auditChanges(gh, p.Changes, true, failed)
+			notifySlackChanges(p.Changes, failed)
+		}
+		if len(failed) > 0 {
+			return errors.Errorf("%d of %d changes failed; run `ghmm retry --last --yes` to retry them",
+				len(failed), len(p.Changes))
+		}
+		return nil
+	}
+	fmt.Fprint(stdout, planDiff(p, os.Stdout))
+	auditChanges(gh, p.Changes, false, nil)
+	if planOut != "" {
+		if err := writePlanFile(p); err != nil {
+			return err
+		}
+	}
+	if postPlan != "" && len(p.Changes) > 0 {
+		return postPlanComment(gh, p)
+	}
+	return nil
+}
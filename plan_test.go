@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// testMilestone returns a milestone as GitHub would list it. A zero due date leaves it without one.
+func testMilestone(number int, title, state string, due time.Time, openIssues int) *github.Milestone {
+	m := &github.Milestone{Number: &number, Title: &title, State: &state, OpenIssues: &openIssues}
+	if !due.IsZero() {
+		m.DueOn = &due
+	}
+	return m
+}
+
+// testSnapshot returns a snapshot of the given repos' milestones, taken of the acme org.
+func testSnapshot(repos ...*repoSnapshot) *orgSnapshot {
+	return &orgSnapshot{Target: "acme", State: "all", Repos: repos}
+}
+
+// describePlan renders each of a plan's changes on a line, with the fields it changes to, for comparing
+// plans in tests.
+func describePlan(p *plan) []string {
+	var lines []string
+	for _, c := range p.Changes {
+		f := c.New
+		if c.Kind == deleteChange {
+			f = c.Old
+		}
+		due := "none"
+		if !f.DueOn.IsZero() {
+			due = inDueZone(f.DueOn).Format(isoDateFormat)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s#%d %s %s %s %q", c.Kind, c.Repo, c.Number, f.Title, f.State, due,
+			f.Description))
+	}
+	return lines
+}
+
+// checkPlan fails the test if the plan's changes aren't as described.
+func checkPlan(t *testing.T, name string, p *plan, want []string) {
+	t.Helper()
+	if got := describePlan(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got plan\n\t%v\nwant\n\t%v", name, got, want)
+	}
+}
+
+func TestPlanEdit(t *testing.T) {
+	jul := dueOnDate(2019, 7, 1)
+	m := testMilestone(1, "M1", "open", jul, 0)
+
+	var p plan
+	f := fieldsOf(m)
+	f.DueOn = jul.In(time.FixedZone("PDT", -7*60*60))
+	if p.Edit("acme/a", m, f) {
+		t.Error("planned an edit that changes nothing")
+	}
+	f.State = "closed"
+	if !p.Edit("acme/a", m, f) {
+		t.Error("planned no edit for a change of state")
+	}
+	p.Create("acme/b", "M1", milestoneFields{State: "open", DueOn: jul})
+	p.Delete("acme/c", testMilestone(3, "M1", "open", jul, 2), true)
+
+	checkPlan(t, "plan", &p, []string{
+		`edit acme/a#1 M1 closed 2019-07-01 ""`,
+		`create acme/b#0 M1 open 2019-07-01 ""`,
+		`delete acme/c#3 M1 open 2019-07-01 ""`,
+	})
+	if p.Count(editChange) != 1 || p.Count(createChange) != 1 || p.Count(deleteChange) != 1 {
+		t.Errorf("miscounted changes")
+	}
+	if got := p.Repos(); !reflect.DeepEqual(got, []repo{"acme/a", "acme/b", "acme/c"}) {
+		t.Errorf("got repos %v", got)
+	}
+	if c := p.Changes[2]; !c.Unassign || c.Issues != 2 {
+		t.Errorf("the delete doesn't unassign its 2 issues: %+v", c)
+	}
+}
+
+func TestPlanSucceeded(t *testing.T) {
+	var p plan
+	for i := 1; i <= 3; i++ {
+		p.Create(repo(fmt.Sprintf("acme/r%d", i)), "M1", milestoneFields{State: "open"})
+	}
+	ok := p.succeeded([]*failedChange{{Change: p.Changes[1], Error: "boom"}})
+	if len(ok) != 2 || ok[0] != p.Changes[0] || ok[1] != p.Changes[2] {
+		t.Errorf("got %v", ok)
+	}
+}
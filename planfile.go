@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// planOut, if set, is the file that a dry-run saves its plan to, for `ghmm apply` to execute later.
+var planOut string
+
+// writePlanFile saves a plan to the --out file, in the same form as plans posted for approval.
+func writePlanFile(p *plan) error {
+	doc, err := json.MarshalIndent(planDocument{Version: 1, Changes: p.Changes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(planOut, append(doc, '\n'), 0600); err != nil {
+		return errors.Wrapf(err, "writing plan to %s", planOut)
+	}
+	infof("saved the plan of %d changes to %s; run `ghmm apply %s --yes` to make them", len(p.Changes), planOut,
+		planOut)
+	return nil
+}
+
+// loadPlanFile reads a plan saved with --out.
+func loadPlanFile(path string) (*plan, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading plan %s", path)
+	}
+	return parsePlanDocument(b, "plan "+path)
+}
+
+// doPlan runs a command that computes a plan of milestone changes as a dry-run, saving the plan with --out.
+func doPlan(root *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing command to plan (e.g. `ghmm plan set acmecorp M42 8/1/2019 --out plan.json`)")
+	}
+	cmd, _, err := root.Find(args)
+	if err != nil || cmd == root || cmd.Flags().Lookup("out") == nil {
+		return errors.Errorf("%s does not plan milestone changes, so it cannot be planned", args[0])
+	}
+	var out bool
+	for _, arg := range args {
+		out = out || arg == "--out" || strings.HasPrefix(arg, "--out=")
+	}
+	if !out {
+		return errors.New("missing --out file to save the plan to")
+	}
+	root.SetArgs(append(args, "--dry-run"))
+	return root.Execute()
+}
+
+// doApplyPlanFile executes exactly the changes in a plan saved with --out, provided that none of the
+// milestones involved have changed since the plan was made.
+func doApplyPlanFile(path string) error {
+	p, err := loadPlanFile(path)
+	if err != nil {
+		return err
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	if err = checkPlanCurrent(gh, p, "the plan was made", "make a new plan"); err != nil {
+		return err
+	}
+	if err = checkPlanCreates(gh, p); err != nil {
+		return err
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("applied %d planned changes", c)
+		} else {
+			fmt.Fprintf(stdout, "would apply %d planned changes; re-run with --yes to apply them\n", c)
+		}
+	}
+	return nil
+}
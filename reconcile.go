@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// reconcileMilestones makes the webhook server propagate milestone changes in one repo to the rest.
+var reconcileMilestones bool
+
+// reconcileWebhookHandler keeps an org's milestones consistent as they change: when a milestone is created,
+// edited, closed, or reopened in one repo, the same change is planned for its like-titled counterparts in
+// every other repo (creating it where it is missing, if it was just created), so that drift is corrected in
+// near-real-time rather than by later sync runs. Changes made by ghmm itself are ignored, since they are
+// either its own reconciling or were made everywhere at once.
+func reconcileWebhookHandler(gh *github.Client, event interface{}, opts *webhookOptions) error {
+	ev, ok := event.(*github.MilestoneEvent)
+	if !ok || !opts.Reconcile {
+		return nil
+	}
+	switch ev.GetAction() {
+	case "created", "edited", "closed", "opened":
+	default:
+		return nil
+	}
+	if sender := ev.GetSender().GetLogin(); sender == opts.Login {
+		debugf("ignoring milestone %s event caused by ghmm itself (%s)", ev.GetAction(), sender)
+		return nil
+	}
+
+	src, m := repo(ev.GetRepo().GetFullName()), ev.GetMilestone()
+	want := fieldsOf(m)
+	from := want.Title
+	if ch := ev.GetChanges(); ch != nil && ch.Title != nil && ch.Title.From != nil {
+		from = *ch.Title.From
+	}
+
+	snap, err := takeSnapshot(gh, src.Owner(), "all")
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, rs := range snap.Repos {
+		if rs.Repo == src {
+			found = true
+		}
+	}
+	if !found {
+		// The repo isn't one of those being managed (it was excluded, say), so its milestones aren't either.
+		return nil
+	}
+
+	var p plan
+	for _, rs := range snap.Repos {
+		if rs.Repo == src {
+			continue
+		}
+		if from != want.Title && len(rs.MilestonesTitled(want.Title)) > 0 {
+			warnRepo(rs.Repo, fmt.Sprintf("milestone %s was renamed to %s in repo %s, but repo %s already has a %s; "+
+				"skipping it", from, want.Title, src, rs.Repo, want.Title),
+				"milestone %s was renamed to %s, but a milestone by that name already exists in %s", from, want.Title)
+			continue
+		}
+		if cur := rs.Milestone(from); cur != nil {
+			p.Edit(rs.Repo, cur, want)
+		} else if ev.GetAction() == "created" {
+			p.Create(rs.Repo, want.Title, want)
+		}
+	}
+	if len(p.Changes) > 0 {
+		infof("reconciling milestone %s across %s after it was %s in repo %s",
+			want.Title, src.Owner(), ev.GetAction(), src)
+	}
+	return p.Execute(gh, opts.Apply)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted replaces any secret scrubbed from output.
+const redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	// secrets holds every secret value ghmm knows about, such as resolved tokens and passphrases.
+	secrets []string
+
+	// secretPatterns match secrets that might appear in output even if we never saw their values:
+	// Authorization headers, tokens passed in URLs, and GitHub's prefixed token formats.
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(authorization:\s*)(?:(?:bearer|token|basic)\s+)?[^\s"]+`),
+		regexp.MustCompile(`(?i)([?&](?:access_token|token|client_secret)=)[^&\s"]+`),
+		regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`),
+	}
+)
+
+// registerSecret records a secret value so that it is scrubbed from everything ghmm prints.
+func registerSecret(s string) {
+	// Very short values would redact innocent text, and can't meaningfully be leaked anyway.
+	if len(s) < 4 {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, e := range secrets {
+		if e == s {
+			return
+		}
+	}
+	secrets = append(secrets, s)
+}
+
+// redact scrubs all known secrets and anything resembling a credential from s.
+func redact(s string) string {
+	secretsMu.RLock()
+	for _, sec := range secrets {
+		s = strings.Replace(s, sec, redacted, -1)
+	}
+	secretsMu.RUnlock()
+
+	for i, re := range secretPatterns {
+		if i == len(secretPatterns)-1 {
+			s = re.ReplaceAllString(s, redacted)
+		} else {
+			s = re.ReplaceAllString(s, "${1}"+redacted)
+		}
+	}
+	return s
+}
+
+// redactWriter is an io.Writer that scrubs secrets from everything written through it.
+type redactWriter struct {
+	w io.Writer
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, redact(string(p))); err != nil {
+		return 0, err
+	}
+	// Report the original length, since callers only care that all of p was consumed.
+	return len(p), nil
+}
+
+// stdout and stderr are where all of ghmm's output goes, so that secrets are always scrubbed from it.
+var (
+	stdout io.Writer = &redactWriter{w: os.Stdout}
+	stderr io.Writer = &redactWriter{w: os.Stderr}
+)
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// doReopenMilestone reopens every closed milestone with the given title, in every repo, leaving their due
+// dates and descriptions as they were.
+func doReopenMilestone(orgOrRepo, title string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "closed")
+	if err != nil {
+		return err
+	}
+	var p plan
+	for _, rs := range snap.Repos {
+		for _, m := range rs.MilestonesTitled(title) {
+			f := fieldsOf(m)
+			f.State = "open"
+			p.Edit(rs.Repo, m, f)
+		}
+	}
+	if len(p.Changes) == 0 {
+		return errors.Errorf("there are no closed milestones titled %s in %s", title, orgOrRepo)
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); applying() {
+		successf("reopened %d milestones", c)
+	} else {
+		fmt.Fprintf(stdout, "would reopen %d milestones; re-run with --yes to reopen them\n", c)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+var (
+	// repoCacheTTL is how long a cached list of an org's repos is used before it is fetched again; zero
+	// disables the cache.
+	repoCacheTTL = time.Hour
+	// refreshRepos forces org repo lists to be fetched afresh, rather than read from the cache.
+	refreshRepos bool
+	// repoCacheScope distinguishes the cached repo lists of different credentials and GitHub instances, since
+	// each may see a different set of repos. It is set when the GitHub client is created.
+	repoCacheScope string
+)
+
+// setRepoCacheScope derives repoCacheScope from the API URL and credentials in use, without recording them.
+func setRepoCacheScope(apiURL, tok string) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", apiURL, tok)
+	var owners []string
+	for owner := range cfg.Tokens {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		fmt.Fprintf(h, "%s=%s\n", owner, cfg.Tokens[owner])
+	}
+	repoCacheScope = hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// cachedRepoList is an org's repo list as saved between runs.
+type cachedRepoList struct {
+	Fetched time.Time            `json:"fetched"`
+	Repos   []*github.Repository `json:"repos"`
+}
+
+// repoCacheKey is the store key under which an org's repo list is cached. The listing options that change
+// which repos are listed, or their order, are part of the key.
+func repoCacheKey(org string) string {
+	return fmt.Sprintf("repos/%s/%s-%s-%s.json",
+		repoCacheScope, strings.ToLower(org), repoListOpts.Type, repoListOpts.Sort)
+}
+
+// listOrgRepos lists the repos in an org, using the copy cached by an earlier run if it is younger than
+// --repo-cache-ttl and --refresh wasn't given. It returns true along with the repos if they were fetched
+// afresh, since a cached list's permissions may be out of date.
+func listOrgRepos(gh *github.Client, org string) ([]*github.Repository, bool, error) {
+	useCache := repoCacheTTL > 0
+	st, err := cacheStore()
+	if err != nil {
+		warnf("could not use the repo list cache: %v", err)
+		useCache = false
+	}
+
+	key := repoCacheKey(org)
+	if useCache && !refreshRepos {
+		if b, err := st.Get(key); err == nil {
+			var cached cachedRepoList
+			if err = json.Unmarshal(b, &cached); err == nil && time.Since(cached.Fetched) < repoCacheTTL {
+				return cached.Repos, false, nil
+			}
+		} else if err != errNotFound {
+			warnf("could not read the cached repo list for %s: %v", org, err)
+		}
+	}
+
+	rs, err := ghmmClient(gh).ListOrgRepos(context.Background(), org, repoListOpts.RepoListOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	if useCache {
+		b, err := json.Marshal(&cachedRepoList{Fetched: time.Now(), Repos: rs})
+		if err == nil {
+			err = st.Put(key, b)
+		}
+		if err != nil {
+			warnf("could not cache the repo list for %s: %v", org, err)
+		}
+	}
+	return rs, true, nil
+}
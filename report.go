@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// mdEscaper escapes the characters that would otherwise be taken as markdown table syntax or formatting.
+var mdEscaper = strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`", "[", "\\[", "]", "\\]")
+
+// doReport writes a markdown document on a milestone's status, suitable for pasting into a tracking issue
+// or wiki page: its progress in each repo, the open work left in repos where it is overdue, and the repos
+// that are missing it.
+func doReport(orgOrRepo, milestone string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	type repoStatus struct {
+		repo    repo
+		m       *github.Milestone
+		overdue []*github.Issue // the open issues and PRs, if the milestone is overdue in this repo.
+	}
+	var found []*repoStatus
+	var missing []string
+	for _, rs := range snap.Repos {
+		if m := rs.Milestone(milestone); m != nil {
+			found = append(found, &repoStatus{repo: rs.Repo, m: m})
+		} else {
+			missing = append(missing, string(rs.Repo))
+		}
+	}
+	if len(found) == 0 {
+		return errors.Errorf("milestone %s does not exist in %s", milestone, orgOrRepo)
+	}
+
+	now := time.Now()
+	err = parallel(len(found), func(i int) error {
+		st := found[i]
+		d := st.m.GetDueOn()
+		if st.m.GetState() != "open" || st.m.GetOpenIssues() == 0 || d.IsZero() || !d.Before(now) {
+			return nil
+		}
+		var err error
+		st.overdue, err = ghmmClient(gh).ListMilestoneIssues(context.Background(), st.repo, st.m.GetNumber(), "open")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var open, closed int
+	var dueOn time.Time
+	for _, st := range found {
+		open += st.m.GetOpenIssues()
+		closed += st.m.GetClosedIssues()
+		if d := st.m.GetDueOn(); dueOn.IsZero() || (!d.IsZero() && d.Before(dueOn)) {
+			dueOn = d
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Milestone %s status\n\n", mdEscaper.Replace(milestone))
+	due := "none"
+	if !dueOn.IsZero() {
+		due = inDueZone(dueOn).Format("Mon Jan _2 2006")
+	}
+	fmt.Fprintf(&b, "**Due:** %s  \n", due)
+	fmt.Fprintf(&b, "**Progress:** %s complete across %d repos (%d open and %d closed issues and PRs)  \n",
+		completion(open, closed), len(found), open, closed)
+	fmt.Fprintf(&b, "**As of:** %s\n\n", now.UTC().Format(time.RFC1123))
+
+	b.WriteString("## Progress by repo\n\n")
+	b.WriteString("| Repo | State | Due | Open | Closed | Complete |\n")
+	b.WriteString("| --- | --- | --- | ---: | ---: | ---: |\n")
+	for _, st := range found {
+		m := st.m
+		d := "none"
+		if !m.GetDueOn().IsZero() {
+			d = inDueZone(m.GetDueOn()).Format("Jan _2 2006")
+		}
+		if st.overdue != nil {
+			d += " (overdue)"
+		}
+		fmt.Fprintf(&b, "| [%s](%s) | %s | %s | %d | %d | %s |\n", mdEscaper.Replace(string(st.repo)),
+			m.GetHTMLURL(), m.GetState(), d, m.GetOpenIssues(), m.GetClosedIssues(),
+			completion(m.GetOpenIssues(), m.GetClosedIssues()))
+	}
+
+	var overdue int
+	for _, st := range found {
+		overdue += len(st.overdue)
+	}
+	if overdue > 0 {
+		fmt.Fprintf(&b, "\n## Overdue (%d)\n\n", overdue)
+		for _, st := range found {
+			sort.Slice(st.overdue, func(i, j int) bool { return st.overdue[i].GetNumber() < st.overdue[j].GetNumber() })
+			for _, iss := range st.overdue {
+				fmt.Fprintf(&b, "- [%s#%d](%s) %s\n", st.repo, iss.GetNumber(), iss.GetHTMLURL(),
+					mdEscaper.Replace(iss.GetTitle()))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintf(&b, "\n## Missing from %d repos\n\n", len(missing))
+		for _, r := range missing {
+			fmt.Fprintf(&b, "- %s\n", mdEscaper.Replace(r))
+		}
+	}
+
+	_, err = fmt.Fprint(stdout, b.String())
+	return err
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// repo names a repo in owner/name form.
+type repo = ghmm.Repo
+
+// ghmmClient returns a library client for the given GitHub client, configured by the command line.
+func ghmmClient(gh *github.Client) *ghmm.Client {
+	return &ghmm.Client{GitHub: gh, Concurrency: concurrency, Warnf: warnf}
+}
+
+// resolvedRepo is a candidate repo found during discovery, and whether it was excluded from the repo set.
+type resolvedRepo struct {
+	Repo     repo
+	Excluded string             // the reason the repo was excluded, or "" if it was included.
+	Info     *github.Repository // the repo's metadata, if known.
+}
+
+// resolveRepos discovers the candidate repos for the given orgs or repos, separated by commas, and decides
+// which of them to operate on, recording the reason for every exclusion. A repo named more than once, say
+// both directly and by its org, is only included once.
+func resolveRepos(gh *github.Client, orgOrRepo string) ([]*resolvedRepo, error) {
+	var resolved []*resolvedRepo
+	seen := make(map[repo]bool)
+	add := func(rr *resolvedRepo) {
+		if key := repo(strings.ToLower(string(rr.Repo))); !seen[key] {
+			seen[key] = true
+			resolved = append(resolved, rr)
+		}
+	}
+	for _, target := range splitTargets(orgOrRepo) {
+		if ix := strings.Index(target, "/"); ix != -1 {
+			// If just a singular repo, query it directly.
+			add(&resolvedRepo{Repo: repo(target)})
+			continue
+		}
+		// If an org, use all of the repos in that org.
+		rs, fresh, err := listOrgRepos(gh, target)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			rr := &resolvedRepo{Repo: repo(r.GetFullName()), Info: r}
+			if fresh {
+				// Cached permissions may be stale, so leave checking write access to a fresh request.
+				rememberPerms(rr.Repo, r)
+			}
+			if r.GetArchived() && !repoListOpts.IncludeArchived {
+				rr.Excluded = "archived"
+			} else if r.GetFork() && !repoListOpts.IncludeForks {
+				rr.Excluded = "fork"
+			}
+			add(rr)
+		}
+	}
+	if err := selector.apply(gh, resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// splitTargets splits a comma-separated list of orgs and repos, ignoring empty entries.
+func splitTargets(orgOrRepo string) []string {
+	var targets []string
+	for _, t := range strings.Split(orgOrRepo, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// reposFile is the file, given by --repos-file, listing the exact repos to operate on.
+var reposFile string
+
+// reposFileTarget is the target read from reposFile: its repos, separated by commas.
+var reposFileTarget string
+
+// loadReposFile reads the repos listed in the given file, one owner/name per line. Blank lines are
+// ignored, as is everything following a #, so that the list can explain itself.
+func loadReposFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading repos file %s", path)
+	}
+	var repos []string
+	for i, line := range strings.Split(string(b), "\n") {
+		if ix := strings.Index(line, "#"); ix != -1 {
+			line = line[:ix]
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		r, err := ghmm.ParseRepo(line)
+		if err != nil {
+			return "", errors.Wrapf(err, "%s:%d", path, i+1)
+		}
+		repos = append(repos, string(r))
+	}
+	if len(repos) == 0 {
+		return "", errors.Errorf("repos file %s lists no repos", path)
+	}
+	return joinTargets(repos), nil
+}
+
+// joinTargets combines several org or repo arguments, each of which may itself list several separated by
+// commas, into the single target that commands operate on.
+func joinTargets(args []string) string {
+	return strings.Join(args, ",")
+}
+
+// repoListOptions controls how an org's repos are listed, and which of them are skipped.
+type repoListOptions struct {
+	ghmm.RepoListOptions
+	// IncludeArchived includes archived repos, which are otherwise skipped since they are read-only.
+	IncludeArchived bool
+	// IncludeForks includes forked repos, which are otherwise skipped since they track upstream releases.
+	IncludeForks bool
+}
+
+var repoListOpts = repoListOptions{RepoListOptions: ghmm.DefaultRepoListOptions}
+
+// getRepos returns the repos to operate on for the given org or repo.
+func getRepos(gh *github.Client, orgOrRepo string) ([]repo, error) {
+	resolved, err := resolveRepos(gh, orgOrRepo)
+	if err != nil {
+		return nil, err
+	}
+	var repos []repo
+	for _, rr := range resolved {
+		if rr.Excluded == "" {
+			repos = append(repos, rr.Repo)
+		}
+	}
+	return repos, nil
+}
+
+// accessLevel describes the token's access to a repo.
+func accessLevel(perms map[string]bool) string {
+	switch {
+	case perms["admin"]:
+		return "admin"
+	case perms["maintain"]:
+		return "maintain"
+	case perms["push"]:
+		return "write"
+	case perms["pull"]:
+		return "read"
+	default:
+		return "none"
+	}
+}
+
+// doListRepos prints the repos that the given org or repo resolves to, along with the reasons for any
+// exclusions, so that targeting can be validated before running mutations.
+func doListRepos(orgOrRepo string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	resolved, err := resolveRepos(gh, orgOrRepo)
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(
+		column{Name: "REPO"},
+		column{Name: "STATUS"},
+		column{Name: "REASON"},
+		column{Name: "ACCESS", Wide: true},
+	)
+	th := currentTheme()
+	var included int
+	for _, rr := range resolved {
+		// Repos named directly haven't been looked up yet, so make sure we can actually see them.
+		if rr.Info == nil && rr.Excluded == "" {
+			info, resp, err := gh.Repositories.Get(context.Background(), rr.Repo.Owner(), rr.Repo.Name())
+			if err != nil {
+				if _, sso := ssoAuthorizationURL(err); sso {
+					rr.Excluded = "no access (SAML SSO authorization required)"
+				} else if resp != nil && resp.StatusCode == http.StatusNotFound {
+					rr.Excluded = "no access (not found)"
+				} else {
+					return errors.Wrapf(err, "looking up repo %s", rr.Repo)
+				}
+			} else {
+				rr.Info = info
+				rememberPerms(rr.Repo, info)
+			}
+		}
+
+		var access string
+		if rr.Info != nil && rr.Info.Permissions != nil {
+			access = accessLevel(*rr.Info.Permissions)
+		}
+		status, color := "included", th.Success
+		if rr.Excluded != "" {
+			status, color = "excluded", th.Warning
+		} else {
+			included++
+		}
+		tab.AddRow(
+			cell{Text: string(rr.Repo), Color: th.Repo},
+			cell{Text: status, Color: color},
+			cell{Text: rr.Excluded},
+			cell{Text: access},
+		)
+	}
+	tab.Print()
+
+	fmt.Fprintf(stdout, "%d of %d repos included\n", included, len(resolved))
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// lastRunKey is the store key under which the summary of the last run that applied changes is kept.
+const lastRunKey = "runs/last.json"
+
+// summaryFile, if set, is where to also write the summary of a run that applies changes.
+var summaryFile string
+
+// runSummary records the outcome of applying a plan, so that the changes that failed can be retried
+// without re-scanning the whole org.
+type runSummary struct {
+	Command []string        `json:"command"` // the command line, with secrets redacted.
+	Time    time.Time       `json:"time"`
+	Applied int             `json:"applied"` // the number of changes that succeeded.
+	Failed  []*failedChange `json:"failed"`  // the changes that failed.
+}
+
+// failedChange is a change that could not be applied, and why.
+type failedChange struct {
+	Change *change `json:"change"`
+	Error  string  `json:"error"`
+}
+
+// saveRunSummary records a run's outcome in the store and, with --summary, in a file too. Failing to
+// record it is only a warning, since the changes themselves have already been made.
+func saveRunSummary(applied int, failed []*failedChange) {
+	sum := runSummary{Time: time.Now(), Applied: applied, Failed: failed}
+	for _, arg := range os.Args {
+		sum.Command = append(sum.Command, redact(arg))
+	}
+	b, err := json.MarshalIndent(sum, "", "    ")
+	if err != nil {
+		warnf("could not record run summary: %v", err)
+		return
+	}
+
+	if st, err := cacheStore(); err != nil {
+		warnf("could not record run summary: %v", err)
+	} else if err = st.Put(lastRunKey, b); err != nil {
+		warnf("could not record run summary: %v", err)
+	}
+	if summaryFile != "" {
+		if err = ioutil.WriteFile(summaryFile, b, 0600); err != nil {
+			warnf("could not write run summary to %s: %v", summaryFile, err)
+		}
+	}
+}
+
+// loadRunSummary reads a run summary from the given file or, if file is empty, the last one recorded.
+func loadRunSummary(file string) (*runSummary, error) {
+	var b []byte
+	var err error
+	if file != "" {
+		if b, err = ioutil.ReadFile(file); err != nil {
+			return nil, errors.Wrapf(err, "reading run summary %s", file)
+		}
+	} else {
+		st, err := cacheStore()
+		if err != nil {
+			return nil, err
+		}
+		if b, err = st.Get(lastRunKey); err == errNotFound {
+			return nil, errors.New("no previous run has been recorded")
+		} else if err != nil {
+			return nil, errors.Wrap(err, "reading the last run summary")
+		}
+	}
+
+	var sum runSummary
+	if err = json.Unmarshal(b, &sum); err != nil {
+		return nil, errors.Wrap(err, "parsing run summary")
+	}
+	return &sum, nil
+}
+
+// applyChanges applies each change in turn, carrying on past failures so that one bad repo doesn't
+// leave the rest of the org untouched. It returns the changes that failed.
+func applyChanges(gh *github.Client, changes []*change) []*failedChange {
+	var failed []*failedChange
+	for _, c := range changes {
+		if err := c.apply(gh); err != nil {
+			warnf("%v", err)
+			failed = append(failed, &failedChange{Change: c, Error: redact(err.Error())})
+			continue
+		}
+		fmt.Fprintln(stdout, c.Describe(true))
+	}
+	return failed
+}
+
+func doRetry(file string) error {
+	sum, err := loadRunSummary(file)
+	if err != nil {
+		return err
+	}
+	if len(sum.Failed) == 0 {
+		fmt.Fprintf(stdout, "nothing to retry: every change in the run at %v succeeded\n", sum.Time.Format(time.RFC1123))
+		return nil
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	p := &plan{}
+	for _, fc := range sum.Failed {
+		p.Changes = append(p.Changes, fc.Change)
+	}
+	infof("retrying %d failed changes across %d repos from the run at %v",
+		len(p.Changes), len(p.Repos()), sum.Time.Format(time.RFC1123))
+	// Make sure that nothing else has changed the milestones in the meantime.
+	if err = checkPlanCurrent(gh, p, "the run", "re-run the original command instead"); err != nil {
+		return err
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); applying() {
+		successf("retried %d changes", c)
+	} else {
+		fmt.Fprintf(stdout, "would retry %d changes; re-run with --yes to retry them\n", c)
+	}
+	return nil
+}
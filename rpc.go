@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// rpcMode is true while serving requests on stdin, which must then never be read for anything else.
+var rpcMode bool
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, read one per line from stdin.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response, written one per line to stdout.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcParams are the parameters accepted by the RPC methods. Each method uses just the ones it needs.
+type rpcParams struct {
+	Target       string `json:"target"`       // the org or repo to operate on.
+	Title        string `json:"title"`        // the milestone's title.
+	State        string `json:"state"`        // list and check: which milestones; sync: the canonical state.
+	DueOn        string `json:"dueOn"`        // set and sync: the due date, in 1/2/2006 format.
+	EnsureNext   bool   `json:"ensureNext"`   // close: as with --ensure-next.
+	WhenComplete bool   `json:"whenComplete"` // close: as with --when-complete.
+	Apply        bool   `json:"apply"`        // mutations: make the changes, rather than dry-running them.
+}
+
+// rpcListResult is the result of list.
+type rpcListResult struct {
+	Milestones []milestoneJSON `json:"milestones"`
+	Warnings   []string        `json:"warnings"`
+}
+
+// rpcCheckResult is the result of check.
+type rpcCheckResult struct {
+	OK       bool     `json:"ok"` // true if there were no warnings.
+	Warnings []string `json:"warnings"`
+}
+
+// rpcPlanResult is the result of a mutation: the changes planned, and whether they were made.
+type rpcPlanResult struct {
+	Changes  []*change `json:"changes"`
+	Applied  bool      `json:"applied"`
+	Warnings []string  `json:"warnings"`
+}
+
+// doRPC serves JSON-RPC 2.0 requests, one per line on stdin, writing a response per line to stdout, so
+// that other programs can drive ghmm without parsing its human-oriented output. That output goes to
+// stderr instead.
+func doRPC() error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	rpcMode = true
+	out := stdout
+	stdout = stderr
+	return serveRPCLines(gh, os.Stdin, out)
+}
+
+// serveRPCLines performs the requests read one per line from in, writing their responses to out.
+// Notifications, which are requests without an id, get no response.
+func serveRPCLines(gh *github.Client, in io.Reader, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0"}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = serveRPC(gh, &req)
+			if req.ID == nil {
+				// An explicit "id": null still gets a response; only an absent id makes a notification.
+				continue
+			}
+		}
+		if resp.ID == nil {
+			resp.ID = json.RawMessage("null")
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// serveRPC performs a single request.
+func serveRPC(gh *github.Client, req *rpcRequest) (interface{}, *rpcError) {
+	var params rpcParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+	if params.Target == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "missing target repo or organization"}
+	}
+	if params.Title == "" && req.Method != "list" && req.Method != "check" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "missing milestone title"}
+	}
+
+	// Each request decides for itself whether to apply its changes, although --dry-run still wins.
+	apply := params.Apply && !dryRun
+	takeWarnings()
+
+	result, err := callRPC(gh, req.Method, &params, apply)
+	if err != nil {
+		if err == errMethodNotFound {
+			return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + req.Method}
+		}
+		takeWarnings()
+		return nil, &rpcError{Code: rpcServerError, Message: redact(err.Error())}
+	}
+	return result, nil
+}
+
+var errMethodNotFound = errors.New("method not found")
+
+// callRPC performs a method, making any changes it plans only if apply is true.
+func callRPC(gh *github.Client, method string, params *rpcParams, apply bool) (interface{}, error) {
+	var p *plan
+	switch method {
+	case "list", "check":
+		state := params.State
+		if state == "" {
+			state = "open"
+		}
+		snap, err := takeSnapshot(gh, params.Target, state)
+		if err != nil {
+			return nil, err
+		}
+		if method == "check" {
+			snap.Aggregate()
+			warnings := takeWarnings()
+			return &rpcCheckResult{OK: len(warnings) == 0, Warnings: warnings}, nil
+		}
+		if err = snap.FetchWorkCounts(gh); err != nil {
+			return nil, err
+		}
+		milestones := snap.Aggregate()
+		var shown []*milestone
+		for _, ms := range milestones {
+			shown = append(shown, ms)
+		}
+		sortMilestones(shown, "due")
+		return &rpcListResult{Milestones: milestonesJSON(snap, shown), Warnings: takeWarnings()}, nil
+	case "set":
+		due, err := parseDueDate(params.DueOn)
+		if err != nil {
+			return nil, err
+		}
+		snap, err := takeSnapshot(gh, params.Target, "open")
+		if err != nil {
+			return nil, err
+		}
+		p = planSetMilestone(snap, params.Title, due)
+	case "close":
+		snap, err := takeSnapshot(gh, params.Target, "open")
+		if err != nil {
+			return nil, err
+		}
+		opts := closeOptions{EnsureNext: params.EnsureNext, WhenComplete: params.WhenComplete}
+		if p, _, err = planCloseMilestone(snap, params.Title, opts); err != nil {
+			return nil, err
+		}
+	case "sync":
+		snap, err := takeSnapshot(gh, params.Target, "all")
+		if err != nil {
+			return nil, err
+		}
+		opts := syncOptions{DueOn: params.DueOn, State: params.State}
+		if p, _, err = planSyncMilestone(snap, params.Title, opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errMethodNotFound
+	}
+
+	if err := p.Execute(gh, apply); err != nil {
+		return nil, err
+	}
+	changes := p.Changes
+	if changes == nil {
+		changes = []*change{}
+	}
+	return &rpcPlanResult{Changes: changes, Applied: apply, Warnings: takeWarnings()}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServeRPCLines(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"close","params":{}}`,
+		`{"jsonrpc":"2.0","method":"close","params":{}}`,
+		``,
+		`{"jsonrpc":"2.0","id":"two","method":"close","params":{"target":"acme"}}`,
+		`{"jsonrpc":"2.0","id":null,"method":"archive","params":{"target":"acme","title":"M1"}}`,
+		`{"jsonrpc":"2.0","method":"archive","params":{"target":"acme","title":"M1"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"close","params":"acme"}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := serveRPCLines(nil, strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		id   string
+		code int
+	}{
+		{id: `1`, code: rpcInvalidParams},
+		{id: `"two"`, code: rpcInvalidParams},
+		{id: `null`, code: rpcMethodNotFound},
+		{id: `3`, code: rpcInvalidParams},
+		{id: `null`, code: rpcParseError},
+	}
+	dec := json.NewDecoder(&out)
+	for i, w := range want {
+		var resp rpcResponse
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("response %d: %v", i+1, err)
+		}
+		if string(resp.ID) != w.id || resp.Error == nil || resp.Error.Code != w.code {
+			t.Errorf("response %d: got id %s and error %+v, want id %s and code %d",
+				i+1, resp.ID, resp.Error, w.id, w.code)
+		}
+	}
+	if dec.More() {
+		t.Errorf("notifications got responses")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// currentMilestone is the special rule target meaning whichever open milestone is due next.
+const currentMilestone = "current"
+
+// assignRule slots matching issues into a milestone automatically.
+type assignRule struct {
+	Name      string   `yaml:"name"`
+	Repos     []string `yaml:"repos,omitempty"`  // path.Match-style globs over owner/name; empty matches all.
+	Labels    []string `yaml:"labels,omitempty"` // labels an issue must have, all of them, to match.
+	Milestone string   `yaml:"milestone"`        // the milestone title, or "current" for the one due next.
+}
+
+// Matches returns true if the rule applies to the given issue in the given repo.
+func (ar *assignRule) Matches(r repo, iss *github.Issue) bool {
+	if len(ar.Repos) > 0 {
+		var ok bool
+		for _, pat := range ar.Repos {
+			if ok, _ = path.Match(pat, string(r)); ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	have := make(map[string]bool)
+	for _, l := range iss.Labels {
+		have[l.GetName()] = true
+	}
+	for _, l := range ar.Labels {
+		if !have[l] {
+			return false
+		}
+	}
+	return true
+}
+
+// Target picks the milestone, out of a repo's open milestones, that the rule assigns issues to.
+func (ar *assignRule) Target(ms []*github.Milestone, now time.Time) *github.Milestone {
+	if ar.Milestone != currentMilestone {
+		for _, m := range ms {
+			if m.GetTitle() == ar.Milestone && m.GetState() == "open" {
+				return m
+			}
+		}
+		return nil
+	}
+
+	// The current milestone is the open one due soonest, not counting those whose due date has passed.
+	today := now.Truncate(24 * time.Hour)
+	var best *github.Milestone
+	for _, m := range ms {
+		d := m.GetDueOn()
+		if m.GetState() != "open" || d.IsZero() || d.Before(today) {
+			continue
+		}
+		if best == nil || d.Before(best.GetDueOn()) {
+			best = m
+		}
+	}
+	return best
+}
+
+// checkRules validates the configured assignment rules.
+func checkRules(rules []*assignRule) error {
+	if len(rules) == 0 {
+		return errors.New("no assignment rules are configured; add some to the rules section of the config file")
+	}
+	for i, ar := range rules {
+		if ar.Milestone == "" {
+			return errors.Errorf("assignment rule %d (%s) is missing a milestone", i+1, ar.Name)
+		}
+		for _, pat := range ar.Repos {
+			if _, err := path.Match(pat, ""); err != nil {
+				return errors.Errorf("assignment rule %d (%s) has malformed repo pattern %q", i+1, ar.Name, pat)
+			}
+		}
+	}
+	return nil
+}
+
+// applyRules finds the first rule matching an issue that has no milestone, and assigns the issue to that
+// rule's target milestone (or, if dry-running, reports that it would). It returns true if it assigned it.
+func applyRules(gh *github.Client, rules []*assignRule, r repo, iss *github.Issue,
+	ms []*github.Milestone, apply bool) (bool, error) {
+	if iss.Milestone != nil || iss.IsPullRequest() || iss.GetState() != "open" {
+		return false, nil
+	}
+
+	for _, ar := range rules {
+		if !ar.Matches(r, iss) {
+			continue
+		}
+		m := ar.Target(ms, time.Now())
+		if m == nil {
+			warnRepo(r, fmt.Sprintf("rule %s matched issue #%d in repo %s, but the repo has no %s milestone",
+				ar.Name, iss.GetNumber(), r, ar.Milestone),
+				"rule %s matched issues in repos with no %s milestone: %s", ar.Name, ar.Milestone)
+			return false, nil
+		}
+
+		t, n := m.GetTitle(), m.GetNumber()
+		if !apply {
+			fmt.Fprintf(stdout, "would assign issue #%d in repo %s to milestone %s (rule %s)\n",
+				iss.GetNumber(), r, t, ar.Name)
+			return true, nil
+		}
+		req := &github.IssueRequest{Milestone: &n}
+		if _, _, err := gh.Issues.Edit(context.Background(), r.Owner(), r.Name(), iss.GetNumber(), req); err != nil {
+			return false, errors.Wrapf(err, "assigning issue #%d in repo %s to milestone %s", iss.GetNumber(), r, t)
+		}
+		fmt.Fprintf(stdout, "assigned issue #%d in repo %s to milestone %s (rule %s)\n",
+			iss.GetNumber(), r, t, ar.Name)
+		return true, nil
+	}
+	return false, nil
+}
+
+// doApplyRules runs the assignment rules over every open issue without a milestone across an org's repos.
+// In a dry-run, this is an audit of what the rules would have assigned.
+func doApplyRules(orgOrRepo string) error {
+	if err := checkRules(cfg.Rules); err != nil {
+		return err
+	}
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+
+	var c int
+	for _, rs := range snap.Repos {
+		opts := &github.IssueListByRepoOptions{Milestone: "none", State: "open"}
+		for {
+			issues, resp, err := gh.Issues.ListByRepo(context.Background(), rs.Repo.Owner(), rs.Repo.Name(), opts)
+			if err != nil {
+				return errors.Wrapf(err, "listing issues without a milestone in repo %s", rs.Repo)
+			}
+			for _, iss := range issues {
+				assigned, err := applyRules(gh, cfg.Rules, rs.Repo, iss, rs.Milestones, applying())
+				if err != nil {
+					return err
+				} else if assigned {
+					c++
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	if c > 0 {
+		if applying() {
+			successf("assigned %d issues to milestones", c)
+		} else {
+			fmt.Fprintf(stdout, "would assign %d issues to milestones; re-run with --yes to assign them\n", c)
+		}
+	}
+	return nil
+}
+
+// rulesWebhookHandler applies the assignment rules to issues as they are opened or labeled.
+func rulesWebhookHandler(gh *github.Client, event interface{}, opts *webhookOptions) error {
+	ev, ok := event.(*github.IssuesEvent)
+	if !ok || len(cfg.Rules) == 0 {
+		return nil
+	}
+	switch ev.GetAction() {
+	case "opened", "labeled", "reopened":
+	default:
+		return nil
+	}
+
+	r := repo(ev.GetRepo().GetFullName())
+	ms, err := ghmmClient(gh).ListMilestones(context.Background(), r, "open")
+	if err != nil {
+		return err
+	}
+	_, err = applyRules(gh, cfg.Rules, r, ev.GetIssue(), ms, opts.Apply)
+	return err
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// repoSelector narrows the repo set using name patterns and repo metadata, so that commands can target,
+// say, only the Go repos in an org without a hand-maintained list. Every criterion that is set must match.
+type repoSelector struct {
+	Include    []string // glob patterns, any of which the repo must match, if there are any.
+	Exclude    []string // glob patterns, none of which the repo may match.
+	Languages  []string // primary languages, any of which may match.
+	Properties []string // custom properties, as name=value, all of which must match.
+	Role       string   // the minimum access the token must have: read, write, maintain, or admin.
+}
+
+var selector repoSelector
+
+// roleRanks orders the access levels reported by accessLevel.
+var roleRanks = map[string]int{"none": 0, "read": 1, "write": 2, "maintain": 3, "admin": 4}
+
+// active returns true if any selection criteria were given.
+func (sel *repoSelector) active() bool {
+	return len(sel.Include) > 0 || len(sel.Exclude) > 0 ||
+		len(sel.Languages) > 0 || len(sel.Properties) > 0 || sel.Role != ""
+}
+
+// matchRepoPattern matches a repo against a glob pattern. Patterns containing a slash match the full
+// owner/name; others match just the name.
+func matchRepoPattern(pattern string, r repo) (bool, error) {
+	name := string(r)
+	if !strings.Contains(pattern, "/") {
+		name = r.Name()
+	}
+	ok, err := path.Match(pattern, name)
+	if err != nil {
+		return false, errors.Errorf("malformed repo pattern %q", pattern)
+	}
+	return ok, nil
+}
+
+// checkPatterns validates the --include and --exclude patterns.
+func (sel *repoSelector) checkPatterns() error {
+	for _, p := range append(append([]string{}, sel.Include...), sel.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return errors.Errorf("malformed repo pattern %q", p)
+		}
+	}
+	return nil
+}
+
+// parseProperties splits the name=value property criteria.
+func (sel *repoSelector) parseProperties() (map[string]string, error) {
+	props := make(map[string]string)
+	for _, p := range sel.Properties {
+		ix := strings.Index(p, "=")
+		if ix <= 0 {
+			return nil, errors.Errorf("malformed --property %q; expected name=value", p)
+		}
+		props[p[:ix]] = p[ix+1:]
+	}
+	return props, nil
+}
+
+// apply excludes the included repos that don't match the selection criteria, recording why.
+func (sel *repoSelector) apply(gh *github.Client, resolved []*resolvedRepo) error {
+	if !sel.active() {
+		return nil
+	}
+	if err := sel.checkPatterns(); err != nil {
+		return err
+	}
+	if _, ok := roleRanks[sel.Role]; sel.Role != "" && !ok {
+		return errors.Errorf("unrecognized --role %q; expected read, write, maintain, or admin", sel.Role)
+	}
+	props, err := sel.parseProperties()
+	if err != nil {
+		return err
+	}
+
+	// Filter by name first, so that excluded repos needn't have their metadata fetched.
+	for _, rr := range resolved {
+		if rr.Excluded == "" {
+			rr.Excluded = sel.patternMismatch(rr.Repo)
+		}
+	}
+
+	// Repos named directly haven't been looked up yet, but their metadata is needed to select them.
+	if len(sel.Languages) > 0 || sel.Role != "" {
+		err := parallel(len(resolved), func(i int) error {
+			rr := resolved[i]
+			if rr.Info != nil || rr.Excluded != "" {
+				return nil
+			}
+			info, _, err := gh.Repositories.Get(context.Background(), rr.Repo.Owner(), rr.Repo.Name())
+			if err != nil {
+				return errors.Wrapf(err, "looking up repo %s", rr.Repo)
+			}
+			rr.Info = info
+			rememberPerms(rr.Repo, info)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var values map[repo]map[string]string
+	if len(props) > 0 {
+		if values, err = fetchCustomProperties(gh, resolved); err != nil {
+			return err
+		}
+	}
+
+	for _, rr := range resolved {
+		if rr.Excluded == "" {
+			rr.Excluded = sel.mismatch(rr, props, values[rr.Repo])
+		}
+	}
+	return nil
+}
+
+// patternMismatch returns the reason a repo doesn't match the --include and --exclude patterns, or "" if
+// it does. The patterns have already been validated.
+func (sel *repoSelector) patternMismatch(r repo) string {
+	for _, p := range sel.Exclude {
+		if ok, _ := matchRepoPattern(p, r); ok {
+			return fmt.Sprintf("matches --exclude %s", p)
+		}
+	}
+	if len(sel.Include) == 0 {
+		return ""
+	}
+	for _, p := range sel.Include {
+		if ok, _ := matchRepoPattern(p, r); ok {
+			return ""
+		}
+	}
+	return "matches no --include pattern"
+}
+
+// mismatch returns the reason a repo doesn't match the selection criteria, or "" if it does.
+func (sel *repoSelector) mismatch(rr *resolvedRepo, props, values map[string]string) string {
+	if len(sel.Languages) > 0 {
+		lang := rr.Info.GetLanguage()
+		var matched bool
+		for _, l := range sel.Languages {
+			if strings.EqualFold(l, lang) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			if lang == "" {
+				return "no primary language"
+			}
+			return fmt.Sprintf("language is %s", lang)
+		}
+	}
+	for name, want := range props {
+		if have, ok := values[name]; !ok {
+			return fmt.Sprintf("property %s is unset", name)
+		} else if !strings.EqualFold(have, want) {
+			return fmt.Sprintf("property %s is %s", name, have)
+		}
+	}
+	if sel.Role != "" {
+		var access string
+		if rr.Info.Permissions != nil {
+			access = accessLevel(*rr.Info.Permissions)
+		} else {
+			access = "none"
+		}
+		if roleRanks[access] < roleRanks[sel.Role] {
+			return fmt.Sprintf("access is %s, not %s", access, sel.Role)
+		}
+	}
+	return ""
+}
+
+// customPropertyValue is a single custom property set on a repo. Multi-select properties have a list of
+// values, which are joined with commas.
+type customPropertyValue struct {
+	Name  string      `json:"property_name"`
+	Value interface{} `json:"value"`
+}
+
+func (v customPropertyValue) String() string {
+	switch val := v.Value.(type) {
+	case string:
+		return val
+	case []interface{}:
+		var parts []string
+		for _, p := range val {
+			parts = append(parts, fmt.Sprint(p))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return ""
+	}
+}
+
+// fetchCustomProperties returns the custom property values of the included repos, fetched for a whole
+// org at a time. go-github predates custom properties, so the requests are made by hand.
+func fetchCustomProperties(gh *github.Client, resolved []*resolvedRepo) (map[repo]map[string]string, error) {
+	ctx := context.Background()
+	owners := make(map[string]bool)
+	for _, rr := range resolved {
+		if rr.Excluded == "" {
+			owners[rr.Repo.Owner()] = true
+		}
+	}
+
+	values := make(map[repo]map[string]string)
+	for owner := range owners {
+		for page := 1; page != 0; {
+			req, err := gh.NewRequest("GET",
+				fmt.Sprintf("orgs/%s/properties/values?per_page=100&page=%d", owner, page), nil)
+			if err != nil {
+				return nil, err
+			}
+			var repos []struct {
+				FullName   string                `json:"repository_full_name"`
+				Properties []customPropertyValue `json:"properties"`
+			}
+			resp, err := gh.Do(ctx, req, &repos)
+			if err != nil {
+				return nil, errors.Wrapf(err, "listing custom properties for org %s", owner)
+			}
+			for _, r := range repos {
+				vals := make(map[string]string)
+				for _, p := range r.Properties {
+					if p.Value != nil {
+						vals[p.Name] = p.String()
+					}
+				}
+				values[repo(r.FullName)] = vals
+			}
+			page = resp.NextPage
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// webhookConfig configures the webhook server run by `ghmm serve`.
+type webhookConfig struct {
+	// Secret is the credential source (see resolveCredential) for the secret GitHub signs deliveries with.
+	Secret string `yaml:"secret"`
+	// Notify is the credential source for the URL of a Slack-style incoming webhook that is told about
+	// freeze enforcement.
+	Notify string `yaml:"notify"`
+}
+
+// webhookOptions are the settings the webhook server's handlers run with.
+type webhookOptions struct {
+	// Apply makes the handlers make their changes, rather than only reporting what they would do.
+	Apply bool
+	// Reconcile propagates milestone changes in one repo to the rest.
+	Reconcile bool
+	// Login is the user (or App bot) that ghmm acts as, so that handlers can ignore the events caused by
+	// their own changes.
+	Login string
+}
+
+// webhookHandler reacts to a single parsed webhook event. Handlers ignore events they aren't interested in.
+type webhookHandler func(gh *github.Client, event interface{}, opts *webhookOptions) error
+
+// webhookHandlers are run, in order, for every webhook delivery.
+var webhookHandlers = []webhookHandler{
+	rulesWebhookHandler,
+	freezeWebhookHandler,
+	reconcileWebhookHandler,
+}
+
+// webhookQueueSize is how many deliveries may wait to be handled before more are turned away.
+const webhookQueueSize = 100
+
+// webhookDelivery is a parsed webhook delivery, waiting to be handled.
+type webhookDelivery struct {
+	kind  string
+	id    string
+	event interface{}
+}
+
+// webhookServer receives GitHub webhook deliveries and queues them for the handlers. Deliveries are handled
+// one at a time, in the order received: handlers share the warning buffer, and reconciling two deliveries at
+// once could race on the same milestones. Queuing also lets GitHub's request return before its short
+// timeout, even when handling the delivery means snapshotting a whole org.
+type webhookServer struct {
+	gh     *github.Client
+	secret []byte
+	opts   webhookOptions
+	queue  chan *webhookDelivery
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := github.ValidatePayload(req, s.secret)
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	kind := github.WebHookType(req)
+	event, err := github.ParseWebHook(kind, payload)
+	if err != nil {
+		// Deliveries for event types we know nothing about are simply ignored.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	select {
+	case s.queue <- &webhookDelivery{kind: kind, id: req.Header.Get("X-GitHub-Delivery"), event: event}:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "too many deliveries are waiting to be handled", http.StatusServiceUnavailable)
+	}
+}
+
+// work handles queued deliveries until the queue is closed.
+func (s *webhookServer) work() {
+	for d := range s.queue {
+		fmt.Fprintf(stdout, "%s received %s event (delivery %s)\n", time.Now().Format(time.RFC3339), d.kind, d.id)
+		for _, h := range webhookHandlers {
+			if err := h(s.gh, d.event, &s.opts); err != nil {
+				warnf("handling %s event: %v", d.kind, err)
+			}
+		}
+		flushWarnings()
+	}
+}
+
+// webhookLogin returns the login that ghmm makes changes as: the authenticated user's, or the bot user's of
+// the GitHub App it authenticates as.
+func webhookLogin(gh *github.Client) (string, error) {
+	if app.configured() {
+		return appLogin(gh.BaseURL.String())
+	}
+	u, _, err := gh.Users.Get(context.Background(), "")
+	if err != nil {
+		return "", errors.Wrap(err, "looking up the authenticated user")
+	}
+	return u.GetLogin(), nil
+}
+
+func doServe(addr string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	if cfg.Webhook.Secret == "" {
+		return errors.New("no webhook secret is configured; set webhook.secret in the config file")
+	}
+	secret, err := resolveCredential(cfg.Webhook.Secret)
+	if err != nil {
+		return errors.Wrap(err, "resolving webhook secret")
+	}
+
+	srv := &webhookServer{
+		gh:     gh,
+		secret: []byte(secret),
+		opts:   webhookOptions{Apply: applying(), Reconcile: reconcileMilestones},
+		queue:  make(chan *webhookDelivery, webhookQueueSize),
+	}
+	if srv.opts.Reconcile {
+		// Reconciling reacts to milestone changes, so it must know which are its own, lest it react to them.
+		if srv.opts.Login, err = webhookLogin(gh); err != nil {
+			return err
+		}
+	}
+	go srv.work()
+
+	mode := "dry-run (audit) mode; pass --yes to make changes"
+	if srv.opts.Apply {
+		mode = "live mode"
+	}
+	infof("listening for GitHub webhooks on %s in %s", addr, mode)
+	return http.ListenAndServe(addr, srv)
+}
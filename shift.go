@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func doShiftMilestones(orgOrRepo string, by string, match *titleMatch) error {
+	offset, err := parseDueDate(by)
+	if err != nil {
+		return err
+	} else if !offset.rel {
+		return errors.Errorf("--by must be relative, like +1w or -3d, not %q", by)
+	}
+
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return err
+	}
+	p, shifts := planShiftMilestones(snap, offset, match)
+	if len(shifts) == 0 {
+		fmt.Fprintf(stdout, "no open milestones with due dates to shift\n")
+		return nil
+	}
+
+	// Summarize each milestone's move before the individual changes, since those are per repo.
+	tab := newTable(
+		column{Name: "MILESTONE"},
+		column{Name: "DUE"},
+		column{Name: "NEW DUE"},
+		column{Name: "REPOS"},
+	)
+	th := currentTheme()
+	for _, s := range shifts {
+		tab.AddRow(
+			cell{Text: s.title},
+			cell{Text: inDueZone(s.from).Format("Mon Jan _2 2006")},
+			cell{Text: inDueZone(s.to).Format("Mon Jan _2 2006"), Color: th.Warning},
+			cell{Text: fmt.Sprintf("%d", s.repos)},
+		)
+	}
+	tab.Print()
+	fmt.Fprintln(stdout)
+
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c > 0 {
+		if applying() {
+			successf("shifted %d milestone due dates by %s", c, by)
+		} else {
+			fmt.Fprintf(stdout, "would shift %d milestone due dates by %s; re-run with --yes to edit them\n", c, by)
+		}
+	}
+	return nil
+}
+
+// milestoneShift records how one milestone's due date moves, for shift's summary.
+type milestoneShift struct {
+	title    string
+	from, to time.Time
+	repos    int
+}
+
+// planShiftMilestones plans to move the due date of every open milestone matching the pattern (or every open
+// milestone, if none was given) by the given offset. Each repo's milestone moves from its own due date, so
+// any drift between repos is preserved rather than papered over; milestones without a due date are left alone.
+func planShiftMilestones(snap *orgSnapshot, offset dueDate, match *titleMatch) (*plan, []*milestoneShift) {
+	var p plan
+	byKey := make(map[string]*milestoneShift)
+	var shifts []*milestoneShift
+	for _, rs := range snap.Repos {
+		for _, m := range rs.Milestones {
+			due := m.GetDueOn()
+			if m.GetState() != "open" || due.IsZero() || !match.Matches(m.GetTitle()) {
+				continue
+			}
+			f := fieldsOf(m)
+			f.DueOn = offset.From(due)
+			if !p.Edit(rs.Repo, m, f) {
+				continue
+			}
+
+			key := m.GetTitle() + "\x00" + due.String()
+			s, ok := byKey[key]
+			if !ok {
+				s = &milestoneShift{title: m.GetTitle(), from: due, to: f.DueOn}
+				byKey[key] = s
+				shifts = append(shifts, s)
+			}
+			s.repos++
+		}
+	}
+	sort.SliceStable(shifts, func(i, j int) bool {
+		if !shifts[i].from.Equal(shifts[j].from) {
+			return shifts[i].from.Before(shifts[j].from)
+		}
+		return shifts[i].title < shifts[j].title
+	})
+	return &p, shifts
+}
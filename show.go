@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// showRepoJSON is the detail of a milestone in a single repo, as printed by show --output json.
+type showRepoJSON struct {
+	Repo         string     `json:"repo"`
+	Present      bool       `json:"present"`
+	Number       int        `json:"number,omitempty"`
+	State        string     `json:"state,omitempty"`
+	DueOn        *time.Time `json:"dueOn,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	OpenIssues   int        `json:"openIssues"`
+	ClosedIssues int        `json:"closedIssues"`
+	URL          string     `json:"url,omitempty"`
+}
+
+// Titled returns a copy of the snapshot holding just the milestones with the given title, so that they may
+// be aggregated, and checked for drift, on their own.
+func (snap *orgSnapshot) Titled(title string) *orgSnapshot {
+	c := *snap
+	c.Repos = nil
+	for _, rs := range snap.Repos {
+		c.Repos = append(c.Repos, &repoSnapshot{Repo: rs.Repo, Milestones: rs.MilestonesTitled(title)})
+	}
+	return &c
+}
+
+// doShowMilestone prints everything about a single milestone, in every repo: its number, state, due date,
+// description, issue counts, and URL, along with the repos that are missing it. If several of a repo's
+// milestones share the title, all of them are shown.
+func doShowMilestone(orgOrRepo, title, output string, format *template.Template) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	snap = snap.Titled(title)
+	ms := snap.Aggregate()[title]
+	if ms == nil {
+		return errors.Errorf("milestone %s does not exist in %s", title, orgOrRepo)
+	}
+	if format != nil {
+		return printMilestonesFormat(format, snap, []*milestone{ms})
+	}
+
+	var repos []showRepoJSON
+	for _, rs := range snap.Repos {
+		if len(rs.Milestones) == 0 {
+			repos = append(repos, showRepoJSON{Repo: string(rs.Repo)})
+			continue
+		}
+		for _, m := range rs.Milestones {
+			sr := showRepoJSON{
+				Repo:         string(rs.Repo),
+				Present:      true,
+				Number:       m.GetNumber(),
+				State:        m.GetState(),
+				Description:  m.GetDescription(),
+				OpenIssues:   m.GetOpenIssues(),
+				ClosedIssues: m.GetClosedIssues(),
+				URL:          m.GetHTMLURL(),
+			}
+			if d := m.GetDueOn(); !d.IsZero() {
+				sr.DueOn = &d
+			}
+			repos = append(repos, sr)
+		}
+	}
+	if output == "json" {
+		return printJSON(repos)
+	}
+
+	tab := newTable(
+		column{Name: "REPO"},
+		column{Name: "NUMBER"},
+		column{Name: "STATE"},
+		column{Name: "DUE"},
+		column{Name: "OPEN"},
+		column{Name: "CLOSED"},
+		column{Name: "COMPLETE"},
+		column{Name: "DESCRIPTION"},
+		column{Name: "URL"},
+	)
+	th := currentTheme()
+	now := time.Now()
+	for _, sr := range repos {
+		if !sr.Present {
+			tab.AddRow(cell{Text: sr.Repo, Color: th.Repo}, cell{}, cell{Text: "missing", Color: th.Warning},
+				cell{}, cell{}, cell{}, cell{}, cell{}, cell{})
+			continue
+		}
+		var due, dueColor string
+		if sr.DueOn != nil {
+			due = inDueZone(*sr.DueOn).Format("Mon Jan _2 2006")
+			if sr.State == "open" && sr.DueOn.Before(now) {
+				dueColor = th.Overdue
+			}
+		}
+		tab.AddRow(
+			cell{Text: sr.Repo, Color: th.Repo},
+			cell{Text: strconv.Itoa(sr.Number)},
+			cell{Text: sr.State},
+			cell{Text: due, Color: dueColor},
+			cell{Text: strconv.Itoa(sr.OpenIssues)},
+			cell{Text: strconv.Itoa(sr.ClosedIssues)},
+			cell{Text: completion(sr.OpenIssues, sr.ClosedIssues)},
+			cell{Text: sr.Description},
+			cell{Text: sr.URL},
+		)
+	}
+	tab.Print()
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// slackWebhook is the URL of a Slack incoming webhook that applied changes and drift warnings are posted to.
+var slackWebhook string
+
+// maxSlackLines bounds how many changes or warnings a single Slack message lists.
+const maxSlackLines = 20
+
+// postSlack posts a message to a Slack-style incoming webhook, as the JSON body {"text": ...}.
+func postSlack(url, text string) error {
+	b, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "posting to Slack")
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("posting to Slack: %s", resp.Status)
+	}
+	return nil
+}
+
+// notifySlack posts a message headed by the command line that produced it, listing the given lines, if
+// a Slack webhook is configured. Failing to post is only a warning, since the work is already done.
+func notifySlack(header string, lines []string) {
+	if slackWebhook == "" || len(lines) == 0 {
+		return
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		args = append(args, redact(arg))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "`ghmm %s` %s:\n", strings.Join(args, " "), header)
+	for i, line := range lines {
+		if i == maxSlackLines {
+			fmt.Fprintf(&b, "• ... and %d more\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintf(&b, "• %s\n", line)
+	}
+	if err := postSlack(slackWebhook, b.String()); err != nil {
+		warnf("could not notify Slack: %v", err)
+	}
+}
+
+// notifySlackChanges posts the outcome of applying a plan to Slack.
+func notifySlackChanges(changes []*change, failed []*failedChange) {
+	bad := make(map[*change]bool)
+	for _, f := range failed {
+		bad[f.Change] = true
+	}
+	var lines []string
+	for _, c := range changes {
+		if !bad[c] {
+			lines = append(lines, c.Describe(true))
+		}
+	}
+	for _, f := range failed {
+		lines = append(lines, "failed: "+f.Error)
+	}
+	notifySlack(fmt.Sprintf("applied %d of %d milestone changes", len(changes)-len(failed), len(changes)), lines)
+}
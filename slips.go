@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+// slipRecord tracks how a milestone's due date has moved since ghmm first saw it.
+type slipRecord struct {
+	Original time.Time `json:"original"` // the due date when first seen.
+	Current  time.Time `json:"current"`  // the due date when last seen.
+	Slips    int       `json:"slips"`    // the number of times the due date moved later.
+}
+
+// Total returns how far the due date has moved from the original.
+func (rec *slipRecord) Total() time.Duration {
+	return rec.Current.Sub(rec.Original)
+}
+
+func (rec *slipRecord) String() string {
+	if rec == nil || (rec.Slips == 0 && rec.Total() == 0) {
+		return ""
+	}
+	days := int(math.Round(rec.Total().Hours() / 24))
+	times := "times"
+	if rec.Slips == 1 {
+		times = "time"
+	}
+	return fmt.Sprintf("slipped %d %s, %+d days total", rec.Slips, times, days)
+}
+
+// slipKey identifies a single milestone in a single repo, in slip history and elsewhere.
+func slipKey(r repo, number int) string {
+	return fmt.Sprintf("%s#%d", r, number)
+}
+
+// slipHistoryKey is the store key under which slip history is persisted between runs.
+const slipHistoryKey = "slips.json"
+
+// recordSlips updates the persisted slip history with the due dates in a snapshot, returning the
+// records for the snapshot's milestones. Problems reading or writing the history are only warnings,
+// since slip tracking is informational.
+func recordSlips(snap *orgSnapshot) map[string]*slipRecord {
+	history := make(map[string]*slipRecord)
+	st, err := cacheStore()
+	if err == nil {
+		var b []byte
+		if b, err = st.Get(slipHistoryKey); err == nil {
+			err = json.Unmarshal(b, &history)
+		} else if err == errNotFound {
+			err = nil
+		}
+	}
+	if err != nil {
+		warnf("could not read due date history: %v", err)
+	}
+
+	slips := make(map[string]*slipRecord)
+	for _, rs := range snap.Repos {
+		for _, m := range rs.Milestones {
+			key := slipKey(rs.Repo, m.GetNumber())
+			slips[key] = observeDueOn(history, key, m)
+		}
+	}
+
+	if st != nil {
+		if err = saveSlipHistory(st, history); err != nil {
+			warnf("could not save due date history: %v", err)
+		}
+	}
+	return slips
+}
+
+// observeDueOn records a milestone's current due date in the history, counting a slip if it has moved
+// later since last seen.
+func observeDueOn(history map[string]*slipRecord, key string, m *github.Milestone) *slipRecord {
+	d := m.GetDueOn()
+	rec, ok := history[key]
+	if !ok {
+		rec = &slipRecord{Original: d, Current: d}
+		history[key] = rec
+	} else if !d.Equal(rec.Current) {
+		if d.After(rec.Current) {
+			rec.Slips++
+		}
+		rec.Current = d
+	}
+	return rec
+}
+
+func saveSlipHistory(st store, history map[string]*slipRecord) error {
+	b, err := json.MarshalIndent(history, "", "    ")
+	if err != nil {
+		return err
+	}
+	return st.Put(slipHistoryKey, b)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// orgSnapshot is an in-memory picture of the milestones across a set of repos, produced by a single fetch
+// phase. Every command reads from a snapshot, and commands that mutate express their changes as a plan
+// against it, so that dry-runs and real runs share one code path.
+type orgSnapshot struct {
+	Target  string          // the org or repo the snapshot was taken of.
+	State   string          // the state of milestones included: open, closed, or all.
+	Taken   time.Time       // when the snapshot was taken.
+	Repos   []*repoSnapshot // the accessible repos, in discovery order.
+	Skipped []repo          // repos that could not be read (e.g., for lack of SAML SSO authorization).
+	// Slips records how each milestone's due date has moved over time, keyed by slipKey.
+	Slips map[string]*slipRecord
+	// Counts splits each milestone's work into issues and PRs, keyed by slipKey. It is only populated
+	// once FetchWorkCounts has been called.
+	Counts map[string]*workCounts
+	// Train, if set, lists the repo patterns taking part in the release train. Milestones are then
+	// expected in exactly those repos, rather than in every repo.
+	Train []string
+}
+
+// InTrain returns true if milestones are expected in the given repo: it is part of the release train, or
+// there is no train, in which case every repo is expected to have every milestone.
+func (snap *orgSnapshot) InTrain(r repo) bool {
+	if snap.Train == nil {
+		return true
+	}
+	for _, pat := range snap.Train {
+		if ok, _ := path.Match(pat, string(r)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UseTrain judges milestone coverage against the given release train, warning about any repo it names
+// outright that isn't among the snapshot's repos.
+func (snap *orgSnapshot) UseTrain(train []string) {
+	snap.Train = train
+	have := make(map[repo]bool)
+	for _, rs := range snap.Repos {
+		have[rs.Repo] = true
+	}
+	for _, pat := range train {
+		if !strings.ContainsAny(pat, "*?[\\") && !have[repo(pat)] {
+			warnf("release train repo %s is not among the repos found", pat)
+		}
+	}
+}
+
+// useSpecTrain judges milestone coverage against the release train listed in the milestone spec: the one
+// in the given file, if any, and otherwise the one published by the snapshot target's owner.
+func useSpecTrain(gh *github.Client, snap *orgSnapshot, specFile string) error {
+	owner := snap.Target
+	if ix := strings.Index(owner, "/"); ix != -1 {
+		owner = owner[:ix]
+	}
+	s, err := loadSpec(gh, owner, specFile)
+	if err != nil {
+		return err
+	} else if len(s.Train) == 0 {
+		return errors.New("the milestone spec does not list the release train's repos")
+	}
+	snap.UseTrain(s.Train)
+	return nil
+}
+
+// Covered returns true if the milestone is in every release train repo, and no others.
+func (snap *orgSnapshot) Covered(ms *milestone) bool {
+	for _, rs := range snap.Repos {
+		if snap.InTrain(rs.Repo) != ms.Repos[rs.Repo] {
+			return false
+		}
+	}
+	return true
+}
+
+// repoSnapshot holds the milestones of a single repo, including their issue counts.
+type repoSnapshot struct {
+	Repo       repo
+	Milestones []*github.Milestone
+}
+
+// takeSnapshot fetches the repos for the given org or repo, and then the milestones in the given state
+// (open, closed, or all) within each of them.
+func takeSnapshot(gh *github.Client, orgOrRepo string, state string) (*orgSnapshot, error) {
+	repos, err := getRepos(gh, orgOrRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &orgSnapshot{Target: orgOrRepo, State: state, Taken: time.Now()}
+	// Query each repo's milestones concurrently, collecting the results by index so that the snapshot's
+	// order doesn't depend on which queries happen to finish first.
+	results := make([]*repoSnapshot, len(repos))
+	if apiBackend == "graphql" {
+		snap.Counts, err = graphQLListMilestones(gh, repos, state, results)
+	} else {
+		err = parallel(len(repos), func(i int) error {
+			r := repos[i]
+			ms, err := ghmmClient(gh).ListMilestones(context.Background(), r, state)
+			if err != nil {
+				if skipUnauthorized(err, r) {
+					return nil
+				}
+				return err
+			}
+			results[i] = &repoSnapshot{Repo: r, Milestones: ms}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, rs := range results {
+		if rs == nil {
+			snap.Skipped = append(snap.Skipped, repos[i])
+		} else {
+			snap.Repos = append(snap.Repos, rs)
+		}
+	}
+	snap.Slips = recordSlips(snap)
+	return snap, nil
+}
+
+// MilestonesTitled returns all of the repo's milestones with the given title. Titles are normally unique
+// within a repo, but duplicates do turn up (one open and one closed, say), so there may be more than one.
+func (rs *repoSnapshot) MilestonesTitled(title string) []*github.Milestone {
+	var matches []*github.Milestone
+	for _, m := range rs.Milestones {
+		if m.GetTitle() == title {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Milestone returns the repo's milestone with the given title, or nil if there isn't one. If several
+// share the title, chooseDuplicate picks between them.
+func (rs *repoSnapshot) Milestone(title string) *github.Milestone {
+	switch matches := rs.MilestonesTitled(title); len(matches) {
+	case 0:
+		return nil
+	case 1:
+		return matches[0]
+	default:
+		return chooseDuplicate(rs.Repo, title, matches)
+	}
+}
+
+// MilestoneNumbered returns the repo's milestone with the given number, or nil if there isn't one.
+func (rs *repoSnapshot) MilestoneNumbered(number int) *github.Milestone {
+	for _, m := range rs.Milestones {
+		if m.GetNumber() == number {
+			return m
+		}
+	}
+	return nil
+}
+
+// milestone aggregates all of the like-titled milestones across the repos in a snapshot.
+type milestone struct {
+	Title        string
+	State        string
+	DueOn        time.Time
+	Description  string
+	OpenIssues   int
+	ClosedIssues int
+	Repos        map[repo]bool
+	Numbers      map[repo]int
+	URLs         map[repo]string
+	Slip         *slipRecord // the due date history of whichever repo's milestone has slipped the most.
+	Work         *workCounts // the split issue and PR counts, if the snapshot has them.
+}
+
+func (m *milestone) RepoNames() []repo {
+	var repos []repo
+	for r := range m.Repos {
+		repos = append(repos, r)
+	}
+	return repos
+}
+
+// Aggregate groups the snapshot's milestones by title, warning about any drift between repos: milestones
+// whose states or due dates disagree, and milestones that are missing from some repos altogether.
+func (snap *orgSnapshot) Aggregate() map[string]*milestone {
+	milestones := make(map[string]*milestone)
+	for _, rs := range snap.Repos {
+		r := rs.Repo
+		for _, m := range rs.Milestones {
+			t := m.GetTitle()
+			exist, ok := milestones[t]
+			if ok {
+				if w := snap.Counts[slipKey(r, m.GetNumber())]; w != nil && exist.Work != nil {
+					exist.Work.add(w)
+				}
+				exist.OpenIssues += m.GetOpenIssues()
+				exist.ClosedIssues += m.GetClosedIssues()
+				exist.Repos[r] = true
+				exist.Numbers[r] = m.GetNumber()
+				exist.URLs[r] = m.GetHTMLURL()
+				if slip := snap.Slips[slipKey(r, m.GetNumber())]; slip != nil &&
+					(exist.Slip == nil || slip.Total() > exist.Slip.Total()) {
+					exist.Slip = slip
+				}
+			} else {
+				milestones[t] = &milestone{
+					Title:        t,
+					State:        m.GetState(),
+					DueOn:        m.GetDueOn(),
+					Description:  m.GetDescription(),
+					OpenIssues:   m.GetOpenIssues(),
+					ClosedIssues: m.GetClosedIssues(),
+					Repos:        map[repo]bool{r: true},
+					Numbers:      map[repo]int{r: m.GetNumber()},
+					URLs:         map[repo]string{r: m.GetHTMLURL()},
+					Slip:         snap.Slips[slipKey(r, m.GetNumber())],
+				}
+				if snap.Counts != nil {
+					milestones[t].Work = &workCounts{}
+					if w := snap.Counts[slipKey(r, m.GetNumber())]; w != nil {
+						milestones[t].Work.add(w)
+					}
+				}
+			}
+		}
+	}
+
+	repos := make([]*ghmm.RepoMilestones, len(snap.Repos))
+	for i, rs := range snap.Repos {
+		repos[i] = &ghmm.RepoMilestones{Repo: rs.Repo, Milestones: rs.Milestones}
+	}
+	for _, d := range ghmm.DetectDrift(repos, snap.InTrain) {
+		r, t := d.Repo, d.Title
+		switch d.Kind {
+		case ghmm.DuplicateDrift:
+			warnDrift(r, fmt.Sprintf("repo %s has %d milestones titled %s (%s)",
+				r, len(d.Duplicates), t, describeDuplicates(d.Duplicates)),
+				"several milestones are titled %s in %s", t)
+		case ghmm.StateDrift:
+			warnDrift(r, fmt.Sprintf("milestone %s in repo %s has a different state "+
+				"(has %s, expect %s) than other repos (%v)", t, r, d.State, d.ExpectState, d.Others),
+				"milestone %s has a different state than expected (%s) in %s", t, d.ExpectState)
+		case ghmm.DueDrift:
+			warnDrift(r, fmt.Sprintf("milestone %s in repo %s has a different due date "+
+				"(has %v, expect %v) than other repos (%v)", t, r, d.DueOn, d.ExpectDueOn, d.Others),
+				"milestone %s has a different due date than expected (%v) in %s", t, d.ExpectDueOn)
+		case ghmm.MissingDrift:
+			warnDrift(r, fmt.Sprintf("milestone %s is missing from repo %s", t, r),
+				"milestone %s is missing from %s", t)
+		case ghmm.UnexpectedDrift:
+			warnDrift(r, fmt.Sprintf("milestone %s exists in repo %s, which is not part of the release train", t, r),
+				"milestone %s exists outside of the release train in %s", t)
+		}
+	}
+	return milestones
+}
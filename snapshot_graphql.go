@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// apiBackend selects how milestones are fetched: "rest", one request (or more) per repo, or "graphql",
+// many repos per request.
+var apiBackend = "rest"
+
+// checkAPIBackend returns an error if the backend isn't one that ghmm supports.
+func checkAPIBackend(api string) error {
+	if api != "rest" && api != "graphql" {
+		return errors.Errorf("unrecognized API %q; expected rest or graphql", api)
+	}
+	return nil
+}
+
+// milestonesBatch is how many repos' milestones are queried in a single GraphQL request.
+const milestonesBatch = 25
+
+// milestonesFields is the GraphQL selection for a page of milestones, with everything the REST API would
+// report about each of them.
+const milestonesFields = `pageInfo { hasNextPage }
+      nodes {
+        id
+        number
+        title
+        description
+        state
+        url
+        dueOn
+        createdAt
+        updatedAt
+        closedAt
+        creator { login }
+        openIssues: issues(states: OPEN) { totalCount }
+        closedIssues: issues(states: CLOSED) { totalCount }
+        openPRs: pullRequests(states: OPEN) { totalCount }
+        closedPRs: pullRequests(states: [CLOSED, MERGED]) { totalCount }
+      }`
+
+// graphQLMilestone is a milestone as the GraphQL API reports it.
+type graphQLMilestone struct {
+	ID          string     `json:"id"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	URL         string     `json:"url"`
+	DueOn       *time.Time `json:"dueOn"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+	ClosedAt    *time.Time `json:"closedAt"`
+	Creator     *struct {
+		Login string `json:"login"`
+	} `json:"creator"`
+	OpenIssues   totalCount `json:"openIssues"`
+	ClosedIssues totalCount `json:"closedIssues"`
+	OpenPRs      totalCount `json:"openPRs"`
+	ClosedPRs    totalCount `json:"closedPRs"`
+}
+
+// toREST converts the milestone into the form the REST API returns, whose issue counts include PRs.
+func (m *graphQLMilestone) toREST() *github.Milestone {
+	open := m.OpenIssues.TotalCount + m.OpenPRs.TotalCount
+	closed := m.ClosedIssues.TotalCount + m.ClosedPRs.TotalCount
+	ms := &github.Milestone{
+		NodeID:       github.String(m.ID),
+		Number:       github.Int(m.Number),
+		Title:        github.String(m.Title),
+		Description:  github.String(m.Description),
+		State:        github.String(strings.ToLower(m.State)),
+		HTMLURL:      github.String(m.URL),
+		OpenIssues:   &open,
+		ClosedIssues: &closed,
+		DueOn:        m.DueOn,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+		ClosedAt:     m.ClosedAt,
+	}
+	if m.Creator != nil {
+		ms.Creator = &github.User{Login: github.String(m.Creator.Login)}
+	}
+	return ms
+}
+
+// graphQLListMilestones fetches the milestones in the given state of every repo, batching many repos into
+// each GraphQL request, and filling in results by index as takeSnapshot does. Any repo with more milestones
+// than fit in a single page, or in a batch that fails (say, because one of its repos needs SAML SSO
+// authorization), is fetched over REST instead, so that it is handled just as it otherwise would be.
+//
+// If every repo was fetched over GraphQL, it also returns the split issue and PR counts, sparing a later
+// FetchWorkCounts; otherwise the counts are nil.
+func graphQLListMilestones(gh *github.Client, repos []repo, state string,
+	results []*repoSnapshot) (map[string]*workCounts, error) {
+	states := "[OPEN, CLOSED]"
+	switch state {
+	case "open":
+		states = "[OPEN]"
+	case "closed":
+		states = "[CLOSED]"
+	}
+
+	var batches [][2]int
+	for start := 0; start < len(repos); start += milestonesBatch {
+		end := start + milestonesBatch
+		if end > len(repos) {
+			end = len(repos)
+		}
+		batches = append(batches, [2]int{start, end})
+	}
+
+	fallback := make([]bool, len(repos))
+	splits := make([]map[string]*workCounts, len(repos))
+	err := parallel(len(batches), func(b int) error {
+		start, end := batches[b][0], batches[b][1]
+
+		var params []string
+		var query bytes.Buffer
+		vars := make(map[string]interface{})
+		for i := start; i < end; i++ {
+			j := i - start
+			params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!", j, j))
+			vars[fmt.Sprintf("o%d", j)] = repos[i].Owner()
+			vars[fmt.Sprintf("n%d", j)] = repos[i].Name()
+			fmt.Fprintf(&query, "  r%d: repository(owner: $o%d, name: $n%d) {\n"+
+				"    milestones(first: 100, states: %s) {\n      %s\n    }\n  }\n", j, j, j, states, milestonesFields)
+		}
+		q := fmt.Sprintf("query(%s) {\n%s}", strings.Join(params, ", "), query.String())
+
+		var res map[string]*struct {
+			Milestones struct {
+				PageInfo struct {
+					HasNextPage bool `json:"hasNextPage"`
+				} `json:"pageInfo"`
+				Nodes []*graphQLMilestone `json:"nodes"`
+			} `json:"milestones"`
+		}
+		if err := graphQL(context.Background(), gh, q, vars, &res); err != nil {
+			for i := start; i < end; i++ {
+				fallback[i] = true
+			}
+			return nil
+		}
+		for i := start; i < end; i++ {
+			r := res[fmt.Sprintf("r%d", i-start)]
+			if r == nil || r.Milestones.PageInfo.HasNextPage {
+				fallback[i] = true
+				continue
+			}
+			rs := &repoSnapshot{Repo: repos[i]}
+			splits[i] = make(map[string]*workCounts)
+			for _, n := range r.Milestones.Nodes {
+				rs.Milestones = append(rs.Milestones, n.toREST())
+				splits[i][slipKey(repos[i], n.Number)] = &workCounts{
+					OpenIssues:   n.OpenIssues.TotalCount,
+					ClosedIssues: n.ClosedIssues.TotalCount,
+					OpenPRs:      n.OpenPRs.TotalCount,
+					ClosedPRs:    n.ClosedPRs.TotalCount,
+				}
+			}
+			results[i] = rs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rest []int
+	for i, fb := range fallback {
+		if fb {
+			rest = append(rest, i)
+		}
+	}
+	if len(rest) == 0 {
+		counts := make(map[string]*workCounts)
+		for _, split := range splits {
+			for k, c := range split {
+				counts[k] = c
+			}
+		}
+		return counts, nil
+	}
+	return nil, parallel(len(rest), func(k int) error {
+		i := rest[k]
+		ms, err := ghmmClient(gh).ListMilestones(context.Background(), repos[i], state)
+		if err != nil {
+			if skipUnauthorized(err, repos[i]) {
+				return nil
+			}
+			return err
+		}
+		results[i] = &repoSnapshot{Repo: repos[i], Milestones: ms}
+		return nil
+	})
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// snapshotKeyPrefix is the store key prefix under which saved snapshots are kept.
+const snapshotKeyPrefix = "snapshots/"
+
+func snapshotKey(name string) string {
+	return snapshotKeyPrefix + name + ".json"
+}
+
+// checkSnapshotName rejects names that would escape the snapshot namespace in the store.
+func checkSnapshotName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return errors.Errorf("malformed snapshot name %q", name)
+	}
+	return nil
+}
+
+func doSaveSnapshot(orgOrRepo, name string) error {
+	if err := checkSnapshotName(name); err != nil {
+		return err
+	}
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	st, err := cacheStore()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err = st.Put(snapshotKey(name), b); err != nil {
+		return errors.Wrapf(err, "saving snapshot %s", name)
+	}
+
+	var n int
+	for _, rs := range snap.Repos {
+		n += len(rs.Milestones)
+	}
+	successf("saved snapshot %s of %d milestones across %d repos in %s", name, n, len(snap.Repos), orgOrRepo)
+	return nil
+}
+
+// loadSnapshot reads a saved snapshot from the store.
+func loadSnapshot(name string) (*orgSnapshot, error) {
+	if err := checkSnapshotName(name); err != nil {
+		return nil, err
+	}
+	st, err := cacheStore()
+	if err != nil {
+		return nil, err
+	}
+	b, err := st.Get(snapshotKey(name))
+	if err == errNotFound {
+		return nil, errors.Errorf("no snapshot named %s has been saved", name)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "reading snapshot %s", name)
+	}
+	var snap orgSnapshot
+	if err = json.Unmarshal(b, &snap); err != nil {
+		return nil, errors.Wrapf(err, "parsing snapshot %s", name)
+	}
+	return &snap, nil
+}
+
+func doListSnapshots() error {
+	st, err := cacheStore()
+	if err != nil {
+		return err
+	}
+	keys, err := st.List(snapshotKeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(column{Name: "NAME"}, column{Name: "TARGET"}, column{Name: "TAKEN"})
+	for _, key := range keys {
+		name := strings.TrimSuffix(strings.TrimPrefix(key, snapshotKeyPrefix), ".json")
+		snap, err := loadSnapshot(name)
+		if err != nil {
+			return err
+		}
+		tab.AddRow(cell{Text: name}, cell{Text: snap.Target}, cell{Text: snap.Taken.Format(time.RFC1123)})
+	}
+	tab.Print()
+	return nil
+}
+
+// milestoneDiff is a single difference in one repo's milestone between two snapshots.
+type milestoneDiff struct {
+	Title  string
+	Repo   repo
+	Change string
+}
+
+// diffSnapshots compares two snapshots milestone by milestone, identifying each by its repo and number,
+// so that renames are reported as such rather than as a removal and an addition.
+func diffSnapshots(a, b *orgSnapshot) []milestoneDiff {
+	index := func(snap *orgSnapshot) map[string]*github.Milestone {
+		ms := make(map[string]*github.Milestone)
+		for _, rs := range snap.Repos {
+			for _, m := range rs.Milestones {
+				ms[slipKey(rs.Repo, m.GetNumber())] = m
+			}
+		}
+		return ms
+	}
+	before, after := index(a), index(b)
+
+	var diffs []milestoneDiff
+	for _, rs := range b.Repos {
+		for _, m := range rs.Milestones {
+			old := before[slipKey(rs.Repo, m.GetNumber())]
+			if old == nil {
+				diffs = append(diffs, milestoneDiff{m.GetTitle(), rs.Repo,
+					fmt.Sprintf("new milestone (%s, due %s)", m.GetState(), formatDueOn(m.GetDueOn()))})
+				continue
+			}
+			for _, c := range describeMilestoneChanges(old, m) {
+				diffs = append(diffs, milestoneDiff{m.GetTitle(), rs.Repo, c})
+			}
+		}
+	}
+	for _, rs := range a.Repos {
+		for _, m := range rs.Milestones {
+			if after[slipKey(rs.Repo, m.GetNumber())] == nil {
+				diffs = append(diffs, milestoneDiff{m.GetTitle(), rs.Repo, "deleted"})
+			}
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].Title != diffs[j].Title {
+			return diffs[i].Title < diffs[j].Title
+		}
+		return diffs[i].Repo < diffs[j].Repo
+	})
+	return diffs
+}
+
+// describeMilestoneChanges lists the differences between two versions of the same milestone.
+func describeMilestoneChanges(old, m *github.Milestone) []string {
+	var changes []string
+	if old.GetTitle() != m.GetTitle() {
+		changes = append(changes, fmt.Sprintf("renamed from %s", old.GetTitle()))
+	}
+	if old.GetState() != m.GetState() {
+		if m.GetState() == "closed" {
+			changes = append(changes, "closed")
+		} else {
+			changes = append(changes, "reopened")
+		}
+	}
+	if d, od := m.GetDueOn(), old.GetDueOn(); !d.Equal(od) {
+		if od.IsZero() || d.IsZero() {
+			changes = append(changes, fmt.Sprintf("due date changed from %s to %s", formatDueOn(od), formatDueOn(d)))
+		} else {
+			days := int(math.Round(d.Sub(od).Hours() / 24))
+			changes = append(changes, fmt.Sprintf("due date moved from %s to %s (%+d days)",
+				formatDueOn(od), formatDueOn(d), days))
+		}
+	}
+	if do, dc := m.GetOpenIssues()-old.GetOpenIssues(), m.GetClosedIssues()-old.GetClosedIssues(); do != 0 || dc != 0 {
+		changes = append(changes, fmt.Sprintf("%+d open, %+d closed issues", do, dc))
+	}
+	return changes
+}
+
+// formatDueOn renders a due date for display, or "none" if there isn't one.
+func formatDueOn(d time.Time) string {
+	if d.IsZero() {
+		return "none"
+	}
+	return inDueZone(d).Format("Mon Jan _2 2006")
+}
+
+func doDiffSnapshots(nameA, nameB string) error {
+	a, err := loadSnapshot(nameA)
+	if err != nil {
+		return err
+	}
+	b, err := loadSnapshot(nameB)
+	if err != nil {
+		return err
+	}
+	if a.Target != b.Target {
+		warnf("snapshots %s and %s are of different targets (%s and %s)", nameA, nameB, a.Target, b.Target)
+	}
+
+	diffs := diffSnapshots(a, b)
+	if len(diffs) == 0 {
+		fmt.Fprintf(stdout, "no milestones changed between %s and %s\n", nameA, nameB)
+		return nil
+	}
+	tab := newTable(column{Name: "MILESTONE"}, column{Name: "REPO"}, column{Name: "CHANGE"})
+	th := currentTheme()
+	for _, d := range diffs {
+		tab.AddRow(cell{Text: d.Title}, cell{Text: string(d.Repo), Color: th.Repo}, cell{Text: d.Change})
+	}
+	tab.Print()
+	fmt.Fprintf(stdout, "%d changes between %s (%s) and %s (%s)\n", len(diffs),
+		nameA, a.Taken.Format(time.RFC1123), nameB, b.Taken.Format(time.RFC1123))
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// orgSpecRepo is the org-wide repository from which the milestone spec is read by default.
+	orgSpecRepo = ".github"
+	// orgSpecPath is the location of the milestone spec within the org-wide repository.
+	orgSpecPath = "milestones.yaml"
+)
+
+// spec is a declarative description of the milestones an org should have. It is the source of truth that
+// the org's repos are checked and converged against.
+type spec struct {
+	Milestones []*milestoneSpec `yaml:"milestones"`
+	// Train lists the repos (or path.Match-style globs over owner/name) taking part in the release train.
+	// When given, milestone coverage is judged against it rather than against every repo.
+	Train []string `yaml:"train,omitempty"`
+}
+
+// milestoneSpec describes a single desired milestone.
+type milestoneSpec struct {
+	Title       string        `yaml:"title"`
+	Due         string        `yaml:"due,omitempty"`
+	Description string        `yaml:"description,omitempty"`
+	State       string        `yaml:"state,omitempty"` // "open" (the default) or "closed".
+	Repos       repoSelection `yaml:"repos,omitempty"`
+	Freeze      *freezeSpec   `yaml:"freeze,omitempty"` // locks the milestone's scope.
+
+	dueOn time.Time // the parsed due date.
+}
+
+// repoSelection narrows the repos a milestone applies to, using path.Match-style globs over owner/name.
+// With no include patterns, every repo is included.
+type repoSelection struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Matches returns true if the selection includes the given repo.
+func (sel repoSelection) Matches(r repo) bool {
+	included := len(sel.Include) == 0
+	for _, pat := range sel.Include {
+		if ok, _ := path.Match(pat, string(r)); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pat := range sel.Exclude {
+		if ok, _ := path.Match(pat, string(r)); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSpec parses and validates a milestone spec.
+func parseSpec(b []byte, source string) (*spec, error) {
+	var s spec
+	if err := yaml.UnmarshalStrict(b, &s); err != nil {
+		return nil, errors.Wrapf(err, "parsing milestone spec %s", source)
+	}
+
+	for _, pat := range s.Train {
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, errors.Errorf("milestone spec %s: malformed release train repo pattern %q", source, pat)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, ms := range s.Milestones {
+		if ms.Title == "" {
+			return nil, errors.Errorf("milestone spec %s: entry %d is missing a title", source, i+1)
+		} else if seen[ms.Title] {
+			return nil, errors.Errorf("milestone spec %s: milestone %s is listed more than once", source, ms.Title)
+		}
+		seen[ms.Title] = true
+
+		switch ms.State {
+		case "":
+			ms.State = "open"
+		case "open", "closed":
+		default:
+			return nil, errors.Errorf("milestone spec %s: milestone %s has unrecognized state %q",
+				source, ms.Title, ms.State)
+		}
+		for _, pat := range append(ms.Repos.Include, ms.Repos.Exclude...) {
+			if _, err := path.Match(pat, ""); err != nil {
+				return nil, errors.Errorf("milestone spec %s: milestone %s has malformed repo pattern %q",
+					source, ms.Title, pat)
+			}
+		}
+		if ms.Due != "" {
+			d, err := parseMilestoneDueOn(ms.Due)
+			if err != nil {
+				return nil, errors.Wrapf(err, "milestone spec %s: milestone %s", source, ms.Title)
+			}
+			ms.dueOn = d
+		}
+		if ms.Freeze != nil {
+			if err := ms.Freeze.check(); err != nil {
+				return nil, errors.Wrapf(err, "milestone spec %s: milestone %s", source, ms.Title)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// loadSpec reads the milestone spec from the given file or, if file is empty, from the org's .github repo,
+// so that the spec travels with the org rather than with whoever happens to run ghmm.
+func loadSpec(gh *github.Client, org, file string) (*spec, error) {
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading milestone spec %s", file)
+		}
+		return parseSpec(b, file)
+	}
+
+	source := fmt.Sprintf("%s/%s/%s", org, orgSpecRepo, orgSpecPath)
+	fc, _, resp, err := gh.Repositories.GetContents(context.Background(), org, orgSpecRepo, orgSpecPath, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, errors.Errorf("org %s has no milestone spec; expected one at %s", org, source)
+		}
+		return nil, errors.Wrapf(err, "fetching milestone spec %s", source)
+	} else if fc == nil {
+		return nil, errors.Errorf("milestone spec %s is a directory, not a file", source)
+	}
+	content, err := fc.GetContent()
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding milestone spec %s", source)
+	}
+	return parseSpec([]byte(content), source)
+}
+
+func doShowSpec(org, file string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	s, err := loadSpec(gh, org, file)
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(
+		column{Name: "TITLE"},
+		column{Name: "DUE"},
+		column{Name: "STATE"},
+		column{Name: "INCLUDE", Wide: true},
+		column{Name: "EXCLUDE", Wide: true},
+		column{Name: "DESCRIPTION", Wide: true},
+	)
+	for _, ms := range s.Milestones {
+		var due string
+		if !ms.dueOn.IsZero() {
+			due = inDueZone(ms.dueOn).Format("Mon Jan _2 2006")
+		}
+		tab.AddRow(
+			cell{Text: ms.Title},
+			cell{Text: due},
+			cell{Text: ms.State},
+			cell{Text: fmt.Sprint(ms.Repos.Include)},
+			cell{Text: fmt.Sprint(ms.Repos.Exclude)},
+			cell{Text: ms.Description},
+		)
+	}
+	tab.Print()
+	return nil
+}
+
+// exportSpec describes a snapshot's milestones as a milestone spec, in due date order. Each milestone takes
+// its state, due date, and description from the first repo that has it, and names the repos that have it
+// unless every repo does.
+func exportSpec(snap *orgSnapshot) *spec {
+	agg := snap.Aggregate()
+	var milestones []*milestone
+	for _, ms := range agg {
+		milestones = append(milestones, ms)
+	}
+	sortMilestones(milestones, "due")
+
+	s := &spec{}
+	for _, ms := range milestones {
+		e := &milestoneSpec{Title: ms.Title, Description: ms.Description}
+		if ms.State != "open" {
+			e.State = ms.State
+		}
+		if !ms.DueOn.IsZero() {
+			e.Due = inDueZone(ms.DueOn).Format(isoDateFormat)
+		}
+		if len(ms.Repos) < len(snap.Repos) {
+			for _, rs := range snap.Repos {
+				if ms.Repos[rs.Repo] {
+					e.Repos.Include = append(e.Repos.Include, string(rs.Repo))
+				}
+			}
+		}
+		s.Milestones = append(s.Milestones, e)
+	}
+	return s
+}
+
+// doExportSpec prints the current milestones of an org or repo as a milestone spec, as a starting point
+// for converging on it with `ghmm apply`.
+func doExportSpec(orgOrRepo, state string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, state)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(exportSpec(snap))
+	if err != nil {
+		return errors.Wrap(err, "writing milestone spec")
+	}
+	_, err = stdout.Write(b)
+	return err
+}
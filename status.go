@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// completion formats the share of issues closed as a percentage, or "-" if there are no issues at all.
+func completion(open, closed int) string {
+	if open+closed == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", closed*100/(open+closed))
+}
+
+// doStatus reports how close a milestone is to completion, in each repo and overall. With CSV output, just
+// the per-repo rows are printed.
+func doStatus(orgOrRepo, milestone, output string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	tab := newTable(
+		column{Name: "REPO"},
+		column{Name: "STATE", Wide: true},
+		column{Name: "OPEN"},
+		column{Name: "CLOSED"},
+		column{Name: "COMPLETE"},
+		column{Name: "URL", Wide: true},
+	)
+	th := currentTheme()
+	var rows [][]string
+	var repos, open, closed int
+	for _, rs := range snap.Repos {
+		m := rs.Milestone(milestone)
+		if m == nil {
+			continue
+		}
+		o, c := m.GetOpenIssues(), m.GetClosedIssues()
+		var color string
+		if o == 0 {
+			color = th.Success
+		}
+		tab.AddRow(
+			cell{Text: string(rs.Repo), Color: th.Repo},
+			cell{Text: m.GetState()},
+			cell{Text: strconv.Itoa(o)},
+			cell{Text: strconv.Itoa(c)},
+			cell{Text: completion(o, c), Color: color},
+			cell{Text: m.GetHTMLURL()},
+		)
+		rows = append(rows, []string{
+			m.GetTitle(),
+			csvDate(m.GetDueOn()),
+			m.GetState(),
+			string(rs.Repo),
+			strconv.Itoa(o),
+			strconv.Itoa(c),
+			completion(o, c),
+			m.GetHTMLURL(),
+		})
+		repos++
+		open += o
+		closed += c
+	}
+	if repos == 0 {
+		return errors.Errorf("milestone %s does not exist in %s", milestone, orgOrRepo)
+	}
+	if output == "csv" {
+		return printCSV([]string{"title", "due_on", "state", "repo", "open_issues", "closed_issues", "complete", "url"},
+			rows)
+	}
+	tab.Print()
+
+	fmt.Fprintf(stdout, "milestone %s is %s complete across %d repos: %d open and %d closed issues and PRs\n",
+		milestone, completion(open, closed), repos, open, closed)
+	return nil
+}
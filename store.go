@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// errNotFound is returned by a store when no value exists for a key.
+var errNotFound = errors.New("not found")
+
+// store persists ghmm's cached state (due date history, snapshots, and the like) as opaque blobs under
+// slash-separated keys. Backends other than the local filesystem let several ghmm processes, such as
+// replicas of the webhook server running in containers, share state without any of them holding it.
+type store interface {
+	// Get returns the value stored under key, or errNotFound.
+	Get(key string) ([]byte, error)
+	// Put stores a value under key, replacing any existing one.
+	Put(key string, data []byte) error
+	// Delete removes the value stored under key, if any.
+	Delete(key string) error
+	// List returns the keys that begin with prefix, sorted.
+	List(prefix string) ([]string, error)
+}
+
+// storeConfig selects the storage backend.
+type storeConfig struct {
+	// URL locates the store: file:///path/to/dir, redis://[:password@]host:port[/db], or
+	// s3://bucket[/prefix][?region=...&endpoint=...] for S3 or S3-compatible services such as GCS.
+	URL string `yaml:"url"`
+}
+
+var (
+	storeOnce   sync.Once
+	sharedStore store
+	storeErr    error
+)
+
+// cacheStore returns the store in effect for this invocation, opening it on first use.
+func cacheStore() (store, error) {
+	storeOnce.Do(func() {
+		sharedStore, storeErr = openStore()
+	})
+	return sharedStore, storeErr
+}
+
+// openStore opens the configured store, defaulting to a directory in the user's cache directory.
+func openStore() (store, error) {
+	raw := cfg.Store.URL
+	if raw == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, errors.Wrap(err, "locating cache directory")
+		}
+		return &fileStore{dir: filepath.Join(dir, "ghmm")}, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing store URL %q", raw)
+	}
+	switch u.Scheme {
+	case "file":
+		return &fileStore{dir: u.Path}, nil
+	case "redis":
+		return newRedisStore(u)
+	case "s3":
+		return newS3Store(u)
+	default:
+		return nil, errors.Errorf("unsupported store URL %q; expected a file, redis, or s3 URL", raw)
+	}
+}
+
+// fileStore keeps each value in a file beneath a local directory.
+type fileStore struct {
+	dir string
+}
+
+func (fs *fileStore) path(key string) string {
+	return filepath.Join(fs.dir, filepath.FromSlash(key))
+}
+
+func (fs *fileStore) Get(key string) ([]byte, error) {
+	b, err := ioutil.ReadFile(fs.path(key))
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return b, err
+}
+
+func (fs *fileStore) Put(key string, data []byte) error {
+	p := fs.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return errors.Wrapf(err, "creating directory %s", filepath.Dir(p))
+	}
+	// Write to a temporary file and rename it into place, so readers never see a partial value.
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (fs *fileStore) Delete(key string) error {
+	if err := os.Remove(fs.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (fs *fileStore) List(prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(fs.dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(fs.dir, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
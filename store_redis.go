@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// redisKeyPrefix namespaces ghmm's keys within a shared Redis database.
+const redisKeyPrefix = "ghmm:"
+
+// redisStore keeps values in Redis, speaking just enough of the RESP protocol to do so.
+type redisStore struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func newRedisStore(u *url.URL) (*redisStore, error) {
+	rs := &redisStore{addr: u.Host}
+	if u.Port() == "" {
+		rs.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		rs.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, errors.Errorf("malformed Redis database number %q", db)
+		}
+		rs.db = n
+	}
+	return rs, nil
+}
+
+// connect dials Redis if not already connected, authenticating and selecting the database.
+func (rs *redisStore) connect() error {
+	if rs.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", rs.addr, 10*time.Second)
+	if err != nil {
+		return errors.Wrapf(err, "connecting to Redis at %s", rs.addr)
+	}
+	rs.conn, rs.rd = conn, bufio.NewReader(conn)
+	if rs.password != "" {
+		registerSecret(rs.password)
+		if _, err = rs.roundTrip("AUTH", rs.password); err != nil {
+			rs.close()
+			return errors.Wrap(err, "authenticating to Redis")
+		}
+	}
+	if rs.db != 0 {
+		if _, err = rs.roundTrip("SELECT", strconv.Itoa(rs.db)); err != nil {
+			rs.close()
+			return errors.Wrapf(err, "selecting Redis database %d", rs.db)
+		}
+	}
+	return nil
+}
+
+func (rs *redisStore) close() {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn, rs.rd = nil, nil
+	}
+}
+
+// do sends a command and returns its reply, reconnecting first if need be.
+func (rs *redisStore) do(args ...string) (interface{}, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err := rs.connect(); err != nil {
+		return nil, err
+	}
+	reply, err := rs.roundTrip(args...)
+	if _, isRedisErr := err.(redisError); err != nil && !isRedisErr {
+		// The connection is in an unknown state; start afresh next time.
+		rs.close()
+	}
+	return reply, err
+}
+
+func (rs *redisStore) roundTrip(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(rs.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(rs.rd)
+}
+
+// redisError is an error reply from the Redis server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readRESP reads a single reply. Bulk strings are returned as []byte (nil for a null reply), integers as
+// int64, simple strings as string, and arrays as []interface{}.
+func readRESP(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = readRESP(rd); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	default:
+		return nil, errors.Errorf("redis: malformed reply %q", line)
+	}
+}
+
+func (rs *redisStore) Get(key string) ([]byte, error) {
+	reply, err := rs.do("GET", redisKeyPrefix+key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s from Redis", key)
+	}
+	b, _ := reply.([]byte)
+	if b == nil {
+		return nil, errNotFound
+	}
+	return b, nil
+}
+
+func (rs *redisStore) Put(key string, data []byte) error {
+	_, err := rs.do("SET", redisKeyPrefix+key, string(data))
+	return errors.Wrapf(err, "writing %s to Redis", key)
+}
+
+func (rs *redisStore) Delete(key string) error {
+	_, err := rs.do("DEL", redisKeyPrefix+key)
+	return errors.Wrapf(err, "deleting %s from Redis", key)
+}
+
+func (rs *redisStore) List(prefix string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := rs.do("SCAN", cursor, "MATCH", redisKeyPrefix+redisGlobEscape(prefix)+"*", "COUNT", "100")
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing %s* in Redis", prefix)
+		}
+		arr, ok := reply.([]interface{})
+		if !ok || len(arr) != 2 {
+			return nil, errors.New("redis: malformed SCAN reply")
+		}
+		next, _ := arr[0].([]byte)
+		batch, _ := arr[1].([]interface{})
+		for _, k := range batch {
+			if kb, ok := k.([]byte); ok {
+				keys = append(keys, strings.TrimPrefix(string(kb), redisKeyPrefix))
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// redisGlobEscape escapes the characters that are special in a Redis MATCH pattern.
+func redisGlobEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadRESP(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+		err  string
+	}{
+		{in: "+OK\r\n", want: "OK"},
+		{in: ":42\r\n", want: int64(42)},
+		{in: "$5\r\nhello\r\n", want: []byte("hello")},
+		{in: "$0\r\n\r\n", want: []byte{}},
+		{in: "$12\r\nline\r\nbreaks\r\n", want: []byte("line\r\nbreaks")},
+		{in: "$-1\r\n", want: nil},
+		{in: "*2\r\n$1\r\n0\r\n*1\r\n$3\r\nkey\r\n", want: []interface{}{[]byte("0"), []interface{}{[]byte("key")}}},
+		{in: "*0\r\n", want: []interface{}{}},
+		{in: "-ERR wrong number of arguments\r\n", err: "redis: ERR wrong number of arguments"},
+		{in: "?what\r\n", err: `redis: malformed reply "?what"`},
+		{in: "\r\n", err: "redis: empty reply"},
+		{in: "$5\r\nhel", err: "unexpected EOF"},
+	}
+	for _, test := range tests {
+		got, err := readRESP(bufio.NewReader(strings.NewReader(test.in)))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("readRESP(%q): got error %v, want %s", test.in, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readRESP(%q): %v", test.in, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("readRESP(%q) = %#v, want %#v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRedisErrorReplyKeepsConnection(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	defer srv.Close()
+	rs := srv.store(t, "")
+
+	if _, err := rs.do("BOGUS"); err == nil {
+		t.Fatal("got no error for an unknown command")
+	} else if _, ok := err.(redisError); !ok {
+		t.Fatalf("got %T, want a redisError", err)
+	}
+	conn := rs.conn
+	if conn == nil {
+		t.Fatal("an error reply closed the connection")
+	}
+	if _, err := rs.do("SET", "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if rs.conn != conn {
+		t.Error("the connection was replaced after an error reply")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	defer srv.Close()
+
+	if _, err := srv.store(t, ":wrong@").Get("x"); err == nil || !strings.Contains(err.Error(), "authenticating") {
+		t.Fatalf("with the wrong password: got %v, want an authentication error", err)
+	}
+
+	rs := srv.store(t, ":s3cret@")
+	if _, err := rs.Get("journal/1"); err != errNotFound {
+		t.Fatalf("Get of a missing key: got %v, want errNotFound", err)
+	}
+	for _, k := range []string{"journal/2", "journal/1", "journal*/odd", "slips/acme"} {
+		if err := rs.Put(k, []byte("value\r\nof "+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := srv.values["ghmm:journal/1"]; !ok {
+		t.Fatalf("Put did not namespace the key; have %v", srv.values)
+	}
+	if srv.db != "3" {
+		t.Errorf("selected database %q, want 3", srv.db)
+	}
+	b, err := rs.Get("journal/1")
+	if err != nil || string(b) != "value\r\nof journal/1" {
+		t.Fatalf("Get: got %q, %v", b, err)
+	}
+	keys, err := rs.List("journal/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(keys, ","); got != "journal/1,journal/2" {
+		t.Fatalf("List: got %s", got)
+	}
+	if keys, err = rs.List("journal*"); err != nil || strings.Join(keys, ",") != "journal*/odd" {
+		t.Fatalf("List of a prefix with a glob character: got %v, %v", keys, err)
+	}
+	if err = rs.Delete("journal/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rs.Get("journal/1"); err != errNotFound {
+		t.Fatalf("Get after Delete: got %v, want errNotFound", err)
+	}
+}
+
+// fakeRedis is just enough of a Redis server to test redisStore against: it supports AUTH, SELECT, GET, SET,
+// DEL, and SCAN, returning SCAN results a key at a time to exercise the cursor.
+type fakeRedis struct {
+	net.Listener
+	password string
+
+	mu     sync.Mutex
+	db     string
+	values map[string]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &fakeRedis{Listener: l, password: password, values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+// store returns a redisStore connected to the fake server, with the given userinfo in its URL.
+func (srv *fakeRedis) store(t *testing.T, userinfo string) *redisStore {
+	u, err := url.Parse("redis://" + userinfo + srv.Addr().String() + "/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := newRedisStore(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func (srv *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := srv.password == ""
+	for {
+		req, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range req.([]interface{}) {
+			args = append(args, string(a.([]byte)))
+		}
+		if !authed && args[0] != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		fmt.Fprint(conn, srv.reply(args, &authed))
+	}
+}
+
+func (srv *fakeRedis) reply(args []string, authed *bool) string {
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	switch args[0] {
+	case "AUTH":
+		if args[1] != srv.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "SELECT":
+		srv.db = args[1]
+		return "+OK\r\n"
+	case "SET":
+		srv.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		if v, ok := srv.values[args[1]]; ok {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case "DEL":
+		delete(srv.values, args[1])
+		return ":1\r\n"
+	case "SCAN":
+		var keys []string
+		for k := range srv.values {
+			if redisGlob(args[3]).MatchString(k) && k > args[1] {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return "*2\r\n" + bulk("0") + "*0\r\n"
+		}
+		next := "0"
+		if len(keys) > 1 {
+			next = keys[0]
+		}
+		return "*2\r\n" + bulk(next) + "*1\r\n" + bulk(keys[0])
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// redisGlob compiles the subset of Redis MATCH patterns that redisStore uses: * and ?, along with
+// backslash escapes.
+func redisGlob(pat string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pat); i++ {
+		switch c := pat[i]; {
+		case c == '\\' && i+1 < len(pat):
+			i++
+			b.WriteString(regexp.QuoteMeta(pat[i : i+1]))
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// s3Store keeps values as objects in an S3 bucket, or in any service exposing an S3-compatible API, such
+// as Google Cloud Storage with HMAC keys (endpoint=https://storage.googleapis.com). Credentials come from
+// the standard AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+type s3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func newS3Store(u *url.URL) (*s3Store, error) {
+	q := u.Query()
+	ss := &s3Store{
+		bucket:    u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		region:    q.Get("region"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		client:    &http.Client{Timeout: time.Minute},
+	}
+	if ss.bucket == "" {
+		return nil, errors.Errorf("store URL %q names no bucket", u)
+	}
+	if ss.region == "" {
+		ss.region = os.Getenv("AWS_REGION")
+	}
+	if ss.region == "" {
+		ss.region = "us-east-1"
+	}
+	if ss.accessKey == "" || ss.secretKey == "" {
+		return nil, errors.New("the s3 store requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	registerSecret(ss.secretKey)
+
+	endpoint := q.Get("endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", ss.region)
+	}
+	var err error
+	if ss.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, errors.Wrapf(err, "parsing store endpoint %q", endpoint)
+	}
+	if ss.prefix != "" {
+		ss.prefix += "/"
+	}
+	return ss, nil
+}
+
+// objectURL returns the path-style URL of an object (or of the bucket itself, for an empty key).
+func (ss *s3Store) objectURL(key string, query url.Values) *url.URL {
+	u := *ss.endpoint
+	u.Path = "/" + ss.bucket
+	if key != "" {
+		u.Path += "/" + ss.prefix + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = strings.Replace(query.Encode(), "+", "%20", -1)
+	return &u
+}
+
+// send signs and sends a request, returning the response body for a 2xx status.
+func (ss *s3Store) send(method string, u *url.URL, body []byte) ([]byte, int, error) {
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	ss.sign(req, body, time.Now().UTC())
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, resp.StatusCode, errors.Errorf("%s %s: %s", method, u.Path, resp.Status)
+	}
+	return b, resp.StatusCode, nil
+}
+
+// sign adds an AWS Signature Version 4 authorization header to a request.
+func (ss *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + ss.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := []byte("AWS4" + ss.secretKey)
+	for _, part := range []string{day, ss.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		ss.accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func (ss *s3Store) Get(key string) ([]byte, error) {
+	b, status, err := ss.send("GET", ss.objectURL(key, nil), nil)
+	if status == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	return b, errors.Wrapf(err, "reading %s from object storage", key)
+}
+
+func (ss *s3Store) Put(key string, data []byte) error {
+	_, _, err := ss.send("PUT", ss.objectURL(key, nil), data)
+	return errors.Wrapf(err, "writing %s to object storage", key)
+}
+
+func (ss *s3Store) Delete(key string) error {
+	_, status, err := ss.send("DELETE", ss.objectURL(key, nil), nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return errors.Wrapf(err, "deleting %s from object storage", key)
+}
+
+func (ss *s3Store) List(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {ss.prefix + prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		b, _, err := ss.send("GET", ss.objectURL("", q), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing %s* in object storage", prefix)
+		}
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err = xml.Unmarshal(b, &result); err != nil {
+			return nil, errors.Wrap(err, "parsing object listing")
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, ss.prefix))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// s3EscapePath percent-encodes everything in a path except unreserved characters and slashes, as
+// Signature Version 4 requires.
+func s3EscapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/pkg/errors"
+)
+
+// syncOptions controls how sync chooses the values that every repo's milestone should converge on.
+type syncOptions struct {
+	DueOn string // the canonical due date, in 1/2/2006 format; defaults to the majority's.
+	State string // the canonical state; defaults to the majority's.
+}
+
+// majority returns the most common of the given values, and an error if there is a tie for first.
+func majority(what string, values []string) (string, error) {
+	counts := make(map[string]int)
+	for _, v := range values {
+		counts[v]++
+	}
+	var distinct []string
+	for v := range counts {
+		distinct = append(distinct, v)
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if counts[distinct[i]] != counts[distinct[j]] {
+			return counts[distinct[i]] > counts[distinct[j]]
+		}
+		return distinct[i] < distinct[j]
+	})
+	if len(distinct) > 1 && counts[distinct[0]] == counts[distinct[1]] {
+		var tied []string
+		for _, v := range distinct {
+			if counts[v] == counts[distinct[0]] {
+				tied = append(tied, fmt.Sprintf("%s in %d repos", v, counts[v]))
+			}
+		}
+		return "", errors.Errorf("no single %s is most common (%s)", what, strings.Join(tied, ", "))
+	}
+	return distinct[0], nil
+}
+
+// planSyncMilestone plans to edit the milestone in every repo whose due date or state differs from the
+// canonical values, returning the plan and the number of repos that have the milestone.
+func planSyncMilestone(snap *orgSnapshot, milestone string, opts syncOptions) (*plan, int, error) {
+	var err error
+	var repos []repo
+	var ms []*github.Milestone
+	var states, dueOns []string
+	for _, rs := range snap.Repos {
+		if m := rs.Milestone(milestone); m != nil {
+			repos, ms = append(repos, rs.Repo), append(ms, m)
+			states = append(states, m.GetState())
+			dueOns = append(dueOns, m.GetDueOn().UTC().Format(time.RFC3339))
+		}
+	}
+	if len(repos) == 0 {
+		return nil, 0, errors.Errorf("milestone %s does not exist in %s", milestone, snap.Target)
+	}
+
+	// Decide on the canonical values: those given explicitly, or else the ones most repos already have.
+	var want milestoneFields
+	if opts.State != "" {
+		if opts.State != "open" && opts.State != "closed" {
+			return nil, 0, errors.Errorf("unrecognized --state %q; expected open or closed", opts.State)
+		}
+		want.State = opts.State
+	} else if want.State, err = majority("state", states); err != nil {
+		return nil, 0, errors.Wrap(err, "pass --state to choose one")
+	}
+	if opts.DueOn != "" {
+		due, err := parseDueDate(opts.DueOn)
+		if err != nil {
+			return nil, 0, err
+		}
+		want.DueOn = due.From(time.Time{})
+	} else {
+		d, err := majority("due date", dueOns)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "pass --due-on to choose one")
+		}
+		want.DueOn, _ = time.Parse(time.RFC3339, d)
+	}
+
+	// Plan to edit the outliers so that every repo agrees.
+	var p plan
+	for i, m := range ms {
+		f := fieldsOf(m)
+		f.State, f.DueOn = want.State, want.DueOn
+		p.Edit(repos[i], m, f)
+	}
+	return &p, len(repos), nil
+}
+
+func doSyncMilestone(orgOrRepo, milestone string, opts syncOptions) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+
+	snap, err := takeSnapshot(gh, orgOrRepo, "all")
+	if err != nil {
+		return err
+	}
+
+	p, total, err := planSyncMilestone(snap, milestone, opts)
+	if err != nil {
+		return err
+	}
+	if err = p.Apply(gh); err != nil {
+		return err
+	}
+
+	if c := len(p.Changes); c == 0 {
+		fmt.Fprintf(stdout, "milestone %s already agrees across all %d repos\n", milestone, total)
+	} else if applying() {
+		successf("synced %d of %d milestones", c, total)
+	} else {
+		fmt.Fprintf(stdout, "would sync %d of %d milestones; re-run with --yes to edit them\n", c, total)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v19/github"
+)
+
+func TestMajority(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+		err    string
+	}{
+		{values: []string{"open"}, want: "open"},
+		{values: []string{"open", "closed", "open"}, want: "open"},
+		{values: []string{"b", "a", "c", "a", "c", "c"}, want: "c"},
+		{values: []string{"open", "closed"}, err: "no single state is most common (closed in 1 repos, open in 1 repos)"},
+		{
+			values: []string{"b", "a", "b", "a", "c"},
+			err:    "no single state is most common (a in 2 repos, b in 2 repos)",
+		},
+	}
+	for _, test := range tests {
+		got, err := majority("state", test.values)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("majority(%v): got error %v, want %q", test.values, err, test.err)
+			}
+		} else if err != nil || got != test.want {
+			t.Errorf("majority(%v) = %q, %v; want %q", test.values, got, err, test.want)
+		}
+	}
+}
+
+func TestPlanSyncMilestone(t *testing.T) {
+	jul, aug := dueOnDate(2019, 7, 1), dueOnDate(2019, 8, 1)
+	snap := testSnapshot(
+		&repoSnapshot{Repo: "acme/a", Milestones: []*github.Milestone{testMilestone(1, "M1", "open", jul, 0)}},
+		&repoSnapshot{Repo: "acme/b", Milestones: []*github.Milestone{testMilestone(2, "M1", "open", jul, 0)}},
+		&repoSnapshot{Repo: "acme/c", Milestones: []*github.Milestone{testMilestone(3, "M1", "closed", aug, 0)}},
+		&repoSnapshot{Repo: "acme/d", Milestones: []*github.Milestone{
+			testMilestone(4, "M2", "open", jul, 0),
+			testMilestone(5, "M3", "open", jul, 0),
+		}},
+		&repoSnapshot{Repo: "acme/e", Milestones: []*github.Milestone{
+			testMilestone(6, "M2", "closed", aug, 0),
+			testMilestone(7, "M3", "open", time.Time{}, 0),
+		}},
+	)
+
+	tests := []struct {
+		name      string
+		milestone string
+		opts      syncOptions
+		want      []string
+		err       string
+	}{
+		{
+			name:      "majority",
+			milestone: "M1",
+			want:      []string{`edit acme/c#3 M1 open 2019-07-01 ""`},
+		},
+		{
+			name:      "explicit",
+			milestone: "M1",
+			opts:      syncOptions{DueOn: "8/1/2019", State: "closed"},
+			want: []string{
+				`edit acme/a#1 M1 closed 2019-08-01 ""`,
+				`edit acme/b#2 M1 closed 2019-08-01 ""`,
+			},
+		},
+		{
+			name:      "state tie",
+			milestone: "M2",
+			err:       "pass --state to choose one: no single state is most common (closed in 1 repos, open in 1 repos)",
+		},
+		{
+			name:      "due date tie",
+			milestone: "M2",
+			opts:      syncOptions{State: "open"},
+			err:       "pass --due-on to choose one: no single due date is most common",
+		},
+		{
+			name:      "due date tie broken",
+			milestone: "M2",
+			opts:      syncOptions{State: "open", DueOn: "2019-07-01"},
+			want:      []string{`edit acme/e#6 M2 open 2019-07-01 ""`},
+		},
+		{
+			name:      "no due date tie",
+			milestone: "M3",
+			opts:      syncOptions{DueOn: "7/1/2019"},
+			want:      []string{`edit acme/e#7 M3 open 2019-07-01 ""`},
+		},
+		{
+			name:      "bad state",
+			milestone: "M1",
+			opts:      syncOptions{State: "all"},
+			err:       `unrecognized --state "all"; expected open or closed`,
+		},
+		{
+			name:      "bad due date",
+			milestone: "M1",
+			opts:      syncOptions{DueOn: "someday"},
+			err:       "malformed date",
+		},
+		{
+			name:      "missing",
+			milestone: "M4",
+			err:       "milestone M4 does not exist in acme",
+		},
+	}
+	for _, test := range tests {
+		p, total, err := planSyncMilestone(snap, test.milestone, test.opts)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		checkPlan(t, test.name, p, test.want)
+		if total == 0 {
+			t.Errorf("%s: found no repos with milestone %s", test.name, test.milestone)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAbuseRetries is how many times a request rejected by abuse detection is retried before giving up.
+	maxAbuseRetries = 5
+	// defaultAbuseWait is how long to pause after an abuse response that doesn't say how long to wait.
+	defaultAbuseWait = time.Minute
+)
+
+// maxWait caps how long any single pause for rate limiting may last. A request that would need to wait
+// longer fails instead.
+var maxWait = time.Hour
+
+// pauseGate holds back every request while GitHub has asked us to slow down. It is shared by all
+// requests, so that one abuse response pauses all work, rather than just whichever request received it.
+type pauseGate struct {
+	mu       sync.Mutex
+	resumeAt time.Time
+	reason   string // why requests are paused, for the countdown.
+	counting bool   // true while a countdown is being displayed.
+}
+
+var gate = &pauseGate{}
+
+// wait blocks until the gate is open.
+func (g *pauseGate) wait(req *http.Request) error {
+	for {
+		g.mu.Lock()
+		d := time.Until(g.resumeAt)
+		g.mu.Unlock()
+		if d <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(d):
+		case <-req.Context().Done():
+			return req.Context().Err()
+		}
+	}
+}
+
+// pause closes the gate for at least d, showing the user a countdown until it reopens.
+func (g *pauseGate) pause(d time.Duration, reason string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(d); until.After(g.resumeAt) {
+		g.resumeAt = until
+		g.reason = reason
+	}
+	if !g.counting {
+		g.counting = true
+		go g.countdown()
+	}
+}
+
+// countdown reports how long remains until the gate reopens, updating in place on a terminal.
+func (g *pauseGate) countdown() {
+	tty := isTerminal(os.Stderr) && level >= normalLevel
+	if !tty {
+		g.mu.Lock()
+		d, reason := time.Until(g.resumeAt), g.reason
+		g.mu.Unlock()
+		warnf("%s; pausing all requests for %v", reason, d.Round(time.Second))
+	}
+	for {
+		g.mu.Lock()
+		d, reason := time.Until(g.resumeAt), g.reason
+		if d <= 0 {
+			g.counting = false
+			g.mu.Unlock()
+			if tty {
+				fmt.Fprint(stderr, "\r\x1b[K")
+			}
+			return
+		}
+		g.mu.Unlock()
+
+		if tty {
+			fmt.Fprintf(stderr, "\r%s; resuming in %v ", reason, d.Round(time.Second))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// abuseRetryAfter returns how long GitHub asked us to wait, if resp is an abuse-detection (secondary rate
+// limit) rejection. It consumes and restores the response body in order to check.
+func abuseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	ra := resp.Header.Get("Retry-After")
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, false
+	}
+	msg := strings.ToLower(string(body))
+	if ra == "" && !strings.Contains(msg, "abuse") && !strings.Contains(msg, "secondary rate limit") {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	return defaultAbuseWait, true
+}
+
+// rateLimitReset returns how long until the rate limit resets, if resp shows that the limit has been
+// exhausted. GitHub reports this on every response, so a successful response can also report it, warning
+// that the next request will be rejected.
+func rateLimitReset(resp *http.Response) (time.Duration, bool) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	// Allow a moment of slack for clock skew between us and GitHub.
+	d := time.Until(time.Unix(reset, 0)) + time.Second
+	if d < time.Second {
+		d = time.Second
+	}
+	return d, true
+}
+
+// throttleTransport pauses all requests and retries when GitHub's abuse detection rejects a request, and
+// waits for the rate limit to reset when it is exhausted, rather than failing mid-run. No single pause may
+// exceed --max-wait; a request that would need a longer one returns its rate limit error instead.
+type throttleTransport struct {
+	base http.RoundTripper
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := gate.wait(req); err != nil {
+			return nil, err
+		}
+
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return resp, err
+		}
+
+		reset, exhausted := rateLimitReset(resp)
+		if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusNotModified {
+			// Hold back subsequent requests until the limit resets, rather than letting them fail.
+			if exhausted && reset <= maxWait {
+				gate.pause(reset, "GitHub rate limit exhausted")
+			}
+			return resp, nil
+		}
+
+		var d time.Duration
+		var reason string
+		if exhausted && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+			d, reason = reset, "GitHub rate limit exceeded"
+		} else if wait, abuse := abuseRetryAfter(resp); abuse {
+			// Back off exponentially if GitHub keeps rejecting us after we have waited as asked.
+			d, reason = wait<<uint(attempt), "GitHub abuse detection triggered"
+		} else {
+			return resp, nil
+		}
+		if attempt == maxAbuseRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		if d > maxWait {
+			warnf("%s, but waiting %v would exceed --max-wait of %v; giving up",
+				reason, d.Round(time.Second), maxWait)
+			return resp, nil
+		}
+		resp.Body.Close()
+		gate.pause(d, reason)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// tokenKeyEnv names an environment variable holding a base64-encoded 32-byte key for the token file.
+	tokenKeyEnv = "GHMM_TOKEN_KEY"
+	// tokenPassphraseEnv names an environment variable holding the passphrase for the token file.
+	tokenPassphraseEnv = "GHMM_TOKEN_PASSPHRASE"
+)
+
+// tokenFile is the on-disk format of an encrypted token. The token is sealed with NaCl secretbox, using
+// either a key derived from a passphrase with scrypt, or a raw key supplied through the environment.
+type tokenFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`  // "scrypt" or "none" (a raw key).
+	Salt    []byte `json:"salt"` // the scrypt salt, if KDF is "scrypt".
+	Nonce   []byte `json:"nonce"`
+	Box     []byte `json:"box"`
+}
+
+// scrypt parameters recommended for interactive logins.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// defaultTokenFilePath returns where the encrypted token is stored unless told otherwise.
+func defaultTokenFilePath() string {
+	if p := configPath(); p != "" {
+		return filepath.Join(filepath.Dir(p), "token.enc")
+	}
+	return ""
+}
+
+// tokenFileKey returns the secretbox key for a token file, reading a raw key or passphrase from the
+// environment if either is set and prompting for a passphrase otherwise.
+func tokenFileKey(kdf string, salt []byte, confirm bool) (*[32]byte, error) {
+	var key [32]byte
+	switch kdf {
+	case "none":
+		registerSecret(os.Getenv(tokenKeyEnv))
+		raw, err := base64.StdEncoding.DecodeString(os.Getenv(tokenKeyEnv))
+		if err != nil || len(raw) != len(key) {
+			return nil, errors.Errorf("%s must be a base64-encoded %d-byte key", tokenKeyEnv, len(key))
+		}
+		copy(key[:], raw)
+	case "scrypt":
+		pass := os.Getenv(tokenPassphraseEnv)
+		registerSecret(pass)
+		if pass == "" {
+			var err error
+			if pass, err = promptSecret("Token file passphrase: "); err != nil {
+				return nil, err
+			}
+			if confirm {
+				again, err := promptSecret("Confirm passphrase: ")
+				if err != nil {
+					return nil, err
+				}
+				if again != pass {
+					return nil, errors.New("passphrases do not match")
+				}
+			}
+		}
+		dk, err := scrypt.Key([]byte(pass), salt, scryptN, scryptR, scryptP, len(key))
+		if err != nil {
+			return nil, errors.Wrap(err, "deriving token file key")
+		}
+		copy(key[:], dk)
+	default:
+		return nil, errors.Errorf("unsupported token file key derivation %q", kdf)
+	}
+	return &key, nil
+}
+
+// promptSecret reads a line from the terminal without echoing it.
+func promptSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.Errorf("cannot prompt for a secret: stdin is not a terminal")
+	}
+	notifyInput(prompt)
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.Wrap(err, "reading secret")
+	}
+	return string(b), nil
+}
+
+// saveEncryptedToken seals the token and writes it to path, readable only by the current user.
+func saveEncryptedToken(path, tok string) error {
+	tf := tokenFile{Version: 1, KDF: "scrypt"}
+	if os.Getenv(tokenKeyEnv) != "" {
+		tf.KDF = "none"
+	} else {
+		tf.Salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, tf.Salt); err != nil {
+			return errors.Wrap(err, "generating salt")
+		}
+	}
+	key, err := tokenFileKey(tf.KDF, tf.Salt, true)
+	if err != nil {
+		return err
+	}
+
+	var nonce [24]byte
+	if _, err = io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return errors.Wrap(err, "generating nonce")
+	}
+	tf.Nonce = nonce[:]
+	tf.Box = secretbox.Seal(nil, []byte(tok), &nonce, key)
+
+	b, err := json.MarshalIndent(&tf, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "creating directory for token file %s", path)
+	}
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		return errors.Wrapf(err, "writing token file %s", path)
+	}
+	return nil
+}
+
+// loadEncryptedToken reads and decrypts the token stored at path.
+func loadEncryptedToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading token file %s", path)
+	}
+	var tf tokenFile
+	if err = json.Unmarshal(b, &tf); err != nil {
+		return "", errors.Wrapf(err, "parsing token file %s", path)
+	}
+	if tf.Version != 1 || len(tf.Nonce) != 24 {
+		return "", errors.Errorf("token file %s has an unsupported format", path)
+	}
+	key, err := tokenFileKey(tf.KDF, tf.Salt, false)
+	if err != nil {
+		return "", err
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], tf.Nonce)
+	tok, ok := secretbox.Open(nil, tf.Box, &nonce, key)
+	if !ok {
+		return "", errors.Errorf("decrypting token file %s: wrong passphrase or key", path)
+	}
+	return string(tok), nil
+}
+
+// readTokenInput reads a token to be stored, prompting for it if stdin is a terminal.
+func readTokenInput() (string, error) {
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return promptSecret("GitHub token: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "reading token from stdin")
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v19/github"
+	"github.com/joeduffy/ghmm/pkg/ghmm"
+	"github.com/pkg/errors"
+)
+
+// transferIssueMutation moves an issue to another repository. The REST API has no equivalent.
+const transferIssueMutation = `mutation($issue: ID!, $repo: ID!) {
+  transferIssue(input: {issueId: $issue, repositoryId: $repo}) {
+    issue { number }
+  }
+}`
+
+// findMilestone looks up a milestone by title in the given repo, in any state.
+func findMilestone(gh *github.Client, r repo, title string) (*github.Milestone, error) {
+	ms, err := ghmmClient(gh).ListMilestones(context.Background(), r, "all")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range ms {
+		if m.GetTitle() == title {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func doTransferIssues(org, from, to, milestone, state string) error {
+	gh, err := ghClient()
+	if err != nil {
+		return err
+	}
+	src, dst := ghmm.QualifyRepo(org, from), ghmm.QualifyRepo(org, to)
+
+	// Find the milestone in the source repo, and the issues within it.
+	sm, err := findMilestone(gh, src, milestone)
+	if err != nil {
+		return err
+	} else if sm == nil {
+		return errors.Errorf("milestone %s does not exist in repo %s", milestone, src)
+	}
+	issues, err := ghmmClient(gh).ListMilestoneIssues(context.Background(), src, sm.GetNumber(), state)
+	if err != nil {
+		return err
+	}
+
+	// Ensure the equivalently named milestone exists in the destination repo, creating it if necessary.
+	dm, err := findMilestone(gh, dst, milestone)
+	if err != nil {
+		return err
+	}
+	dstNumber := dm.GetNumber()
+	if dm == nil {
+		var p plan
+		p.Create(dst, milestone, milestoneFields{State: "open", DueOn: sm.GetDueOn()})
+		if err = p.Apply(gh); err != nil {
+			return err
+		}
+		dstNumber = p.Changes[0].Number
+	}
+
+	dr, _, err := gh.Repositories.Get(context.Background(), dst.Owner(), dst.Name())
+	if err != nil {
+		return errors.Wrapf(err, "looking up repo %s", dst)
+	}
+
+	// Now transfer each issue, and then put it back into the milestone, which the transfer drops.
+	var c int
+	for _, iss := range issues {
+		n := iss.GetNumber()
+		if iss.IsPullRequest() {
+			warnf("skipping #%d in repo %s: pull requests cannot be transferred", n, src)
+			continue
+		}
+		if !applying() {
+			fmt.Fprintf(stdout, "would transfer issue #%d from repo %s to repo %s and assign it to milestone %s\n",
+				n, src, dst, milestone)
+			c++
+			continue
+		}
+
+		var res struct {
+			TransferIssue struct {
+				Issue struct {
+					Number int `json:"number"`
+				} `json:"issue"`
+			} `json:"transferIssue"`
+		}
+		vars := map[string]interface{}{"issue": iss.GetNodeID(), "repo": dr.GetNodeID()}
+		if err = graphQL(context.Background(), gh, transferIssueMutation, vars, &res); err != nil {
+			return errors.Wrapf(err, "transferring issue #%d from repo %s to repo %s", n, src, dst)
+		}
+		nn := res.TransferIssue.Issue.Number
+		req := &github.IssueRequest{Milestone: &dstNumber}
+		if _, _, err = gh.Issues.Edit(context.Background(), dst.Owner(), dst.Name(), nn, req); err != nil {
+			return errors.Wrapf(err, "assigning issue #%d in repo %s to milestone %s", nn, dst, milestone)
+		}
+		fmt.Fprintf(stdout, "transferred issue #%d from repo %s to repo %s as #%d in milestone %s\n",
+			n, src, dst, nn, milestone)
+		c++
+	}
+
+	if c > 0 {
+		if applying() {
+			successf("transferred %d issues", c)
+		} else {
+			fmt.Fprintf(stdout, "would transfer %d issues; re-run with --yes to transfer them\n", c)
+		}
+	}
+
+	return nil
+}
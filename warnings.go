@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// verboseWarnings prints every aggregated warning individually, rather than one line per group.
+	verboseWarnings bool
+	// strict makes any warning at all fail the command with exitWarnings, not just drift.
+	strict bool
+	// warned counts the warnings printed (or, with --quiet, suppressed) so far.
+	warned int32
+	// checkingDrift is set by the commands that check milestones for drift (list and check), so that any drift
+	// they find is reported, and fails them with exitWarnings. Other commands warn about drift in passing.
+	checkingDrift bool
+
+	warningsMu sync.Mutex
+	// warningGroups holds the warnings recorded so far, grouped by summary, in the order first seen.
+	warningGroups []*warningGroup
+)
+
+// maxWarningRepos is the number of repos named in an aggregated warning before the rest are elided.
+const maxWarningRepos = 5
+
+// warningGroup is a set of warnings that share a summary, and differ only in the repo they concern.
+type warningGroup struct {
+	key     string
+	summary string
+	args    []interface{}
+	repos   []repo
+	details []string
+	drift   bool // true if the warnings are about drift between repos, rather than, say, skipped repos.
+}
+
+func (g *warningGroup) Len() int           { return len(g.repos) }
+func (g *warningGroup) Less(i, j int) bool { return g.repos[i] < g.repos[j] }
+func (g *warningGroup) Swap(i, j int) {
+	g.repos[i], g.repos[j] = g.repos[j], g.repos[i]
+	g.details[i], g.details[j] = g.details[j], g.details[i]
+}
+
+// warnRepo records a warning about repo r, to be printed when warnings are flushed. The detail describes
+// this specific instance. Warnings that share the same summary are aggregated into a single line listing
+// every affected repo: summary is a format string whose final verb receives that list. If
+// --verbose-warnings was passed, or only one repo is affected, the details are printed instead.
+func warnRepo(r repo, detail string, summary string, args ...interface{}) {
+	recordWarning(r, false, detail, summary, args...)
+}
+
+// warnDrift records a warning about drift between repo r's milestones and the others', as warnRepo does.
+// Drift is what commands that check for it report, and exit with exitWarnings for.
+func warnDrift(r repo, detail string, summary string, args ...interface{}) {
+	recordWarning(r, true, detail, summary, args...)
+}
+
+func recordWarning(r repo, drift bool, detail string, summary string, args ...interface{}) {
+	key := fmt.Sprintf("%s\x00%v", summary, args)
+
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	var g *warningGroup
+	for _, e := range warningGroups {
+		if e.key == key {
+			g = e
+			break
+		}
+	}
+	if g == nil {
+		g = &warningGroup{key: key, summary: summary, args: args, drift: drift}
+		warningGroups = append(warningGroups, g)
+	}
+	g.repos = append(g.repos, r)
+	g.details = append(g.details, detail)
+}
+
+// flushWarnings prints all recorded warnings, and returns the lines printed about drift.
+func flushWarnings() []string {
+	warningsMu.Lock()
+	groups := warningGroups
+	warningGroups = nil
+	warningsMu.Unlock()
+
+	var lines []string
+	for _, g := range groups {
+		// Warnings may be recorded concurrently, so sort each group to keep the output stable.
+		sort.Sort(g)
+		if verboseWarnings || len(g.repos) == 1 {
+			for _, d := range g.details {
+				warnf("%s", d)
+				if g.drift {
+					lines = append(lines, d)
+				}
+			}
+			continue
+		}
+
+		var names []string
+		for i, r := range g.repos {
+			if i == maxWarningRepos {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, string(r))
+		}
+		list := fmt.Sprintf("%d repos: %s", len(g.repos), strings.Join(names, ", "))
+		line := fmt.Sprintf(g.summary, append(g.args, list)...)
+		warnf("%s", line)
+		if g.drift {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// countWarning records that a warning was raised, for the exit code.
+func countWarning() {
+	atomic.AddInt32(&warned, 1)
+}
+
+// exitCode returns the code to exit a command that didn't fail with: exitWarnings if it checked for drift
+// and found some (the drift warnings flushed), or if it raised any warning at all with --strict, and
+// otherwise 0.
+func exitCode(drift []string) int {
+	if (checkingDrift && len(drift) > 0) || (strict && atomic.LoadInt32(&warned) > 0) {
+		return exitWarnings
+	}
+	return 0
+}
+
+// takeWarnings returns the details of every warning recorded so far, clearing them, for callers that
+// report warnings themselves rather than printing them.
+func takeWarnings() []string {
+	warningsMu.Lock()
+	groups := warningGroups
+	warningGroups = nil
+	warningsMu.Unlock()
+
+	details := []string{}
+	for _, g := range groups {
+		sort.Sort(g)
+		details = append(details, g.details...)
+	}
+	return details
+}
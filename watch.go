@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// watchOptions controls what watch checks for, and how often.
+type watchOptions struct {
+	Interval  time.Duration // how long to wait between checks.
+	DueWithin time.Duration // report open milestones coming due within this long.
+}
+
+// checkDrift takes a fresh snapshot and returns every drift warning about it, along with a notice for each
+// open milestone coming due within the given window.
+func checkDrift(orgOrRepo string, dueWithin time.Duration) ([]string, error) {
+	gh, err := ghClient()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := takeSnapshot(gh, orgOrRepo, "open")
+	if err != nil {
+		return nil, err
+	}
+	milestones := snap.Aggregate()
+	problems := takeWarnings()
+
+	var shown []*milestone
+	for _, ms := range milestones {
+		shown = append(shown, ms)
+	}
+	sortMilestones(shown, "due")
+	now := time.Now()
+	for _, ms := range shown {
+		if d := ms.DueOn; !d.IsZero() && d.After(now) && d.Sub(now) <= dueWithin {
+			problems = append(problems, fmt.Sprintf("milestone %s is due %s, with %d open issues and PRs",
+				ms.Title, inDueZone(d).Format("Mon Jan _2 2006"), ms.OpenIssues))
+		}
+	}
+	return problems, nil
+}
+
+// doWatch re-runs list's consistency checks periodically, reporting only what is newly found each time:
+// drift between repos, missing milestones, and milestones coming due. New findings are also sent as desktop
+// notifications (with --notify) and to Slack (with --notify-slack). It runs until interrupted.
+func doWatch(orgOrRepo string, opts watchOptions) error {
+	seen := make(map[string]bool)
+	for first := true; ; first = false {
+		if !first {
+			time.Sleep(opts.Interval)
+		}
+		problems, err := checkDrift(orgOrRepo, opts.DueWithin)
+		if err != nil {
+			// A transient failure shouldn't end the watch; the next check will likely succeed.
+			warnf("checking %s: %v", orgOrRepo, err)
+			continue
+		}
+
+		current := make(map[string]bool)
+		var fresh []string
+		for _, p := range problems {
+			current[p] = true
+			if !seen[p] {
+				fresh = append(fresh, p)
+			}
+		}
+		stamp := time.Now().Format(time.RFC3339)
+		for _, p := range fresh {
+			fmt.Fprintf(stdout, "%s %s\n", stamp, p)
+		}
+		for p := range seen {
+			if !current[p] {
+				fmt.Fprintf(stdout, "%s resolved: %s\n", stamp, p)
+			}
+		}
+		if len(fresh) > 0 {
+			notify(fmt.Sprintf("ghmm watch found %d new problems in %s", len(fresh), orgOrRepo), fresh[0])
+			notifySlack("found new milestone problems", fresh)
+		}
+		seen = current
+	}
+}